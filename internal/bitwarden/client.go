@@ -0,0 +1,336 @@
+// Package bitwarden runs the Bitwarden CLI, bw, decodes its JSON output and
+// keeps the session key of an unlocked vault for the processes started by
+// chezmoi-tui.
+package bitwarden
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+)
+
+// Client runs bw commands. A client with a Session passes its key to every
+// command, so that the vault unlocked by one chezmoi-tui command can be read
+// by the next.
+type Client struct {
+	binaryPath string
+	session    *Session
+	executor   Executor
+}
+
+// Option configures a Client
+type Option func(*Client)
+
+// WithBinaryPath sets the bw binary to run, either a path or a name to look
+// up in PATH. The default is "bw".
+func WithBinaryPath(path string) Option {
+	return func(c *Client) {
+		if path != "" {
+			c.binaryPath = path
+		}
+	}
+}
+
+// WithSession passes the key of session to every command and saves the key
+// of a vault unlocked by the client in it
+func WithSession(session *Session) Option {
+	return func(c *Client) {
+		c.session = session
+	}
+}
+
+// WithExecutor replaces the executor used to start bw processes. The binary
+// is not looked up in PATH when a custom executor is used.
+func WithExecutor(executor Executor) Option {
+	return func(c *Client) {
+		c.executor = executor
+	}
+}
+
+// New creates a client. It returns an error matching ErrNotInstalled if the
+// bw binary cannot be found.
+func New(opts ...Option) (*Client, error) {
+	c := &Client{binaryPath: "bw"}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	if c.executor == nil {
+		binaryPath, err := exec.LookPath(c.binaryPath)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrNotInstalled, err)
+		}
+		c.binaryPath = binaryPath
+		c.executor = ProcessExecutor{}
+	}
+	return c, nil
+}
+
+// Session returns the session of the client, or nil if it has none
+func (c *Client) Session() *Session {
+	return c.session
+}
+
+// Run executes bw with args, passing the session key, and returns its
+// standard output. Failures are reported as *ExecError.
+func (c *Client) Run(ctx context.Context, args ...string) ([]byte, error) {
+	var env []string
+	if c.session != nil {
+		key, err := c.session.Key()
+		if err != nil {
+			return nil, err
+		}
+		if key != "" {
+			env = []string{SessionEnv + "=" + key}
+		}
+	}
+	return c.execute(ctx, Command{Args: args, Env: env})
+}
+
+// execute runs cmd with the client's binary. Stderr is always captured so
+// that failures can be reported as *ExecError.
+func (c *Client) execute(ctx context.Context, cmd Command) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	cmd.Path = c.binaryPath
+	cmd.Stdout = &stdout
+	if cmd.Stderr != nil {
+		cmd.Stderr = io.MultiWriter(&stderr, cmd.Stderr)
+	} else {
+		cmd.Stderr = &stderr
+	}
+
+	if err := c.executor.Execute(ctx, cmd); err != nil {
+		execErr := &ExecError{Args: cmd.Args, ExitCode: -1, Stderr: stderr.String(), Err: err}
+		var exitErr exitCoder
+		if errors.As(err, &exitErr) {
+			execErr.ExitCode = exitErr.ExitCode()
+		}
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			execErr.Err = ctxErr
+		}
+		return nil, execErr
+	}
+	return stdout.Bytes(), nil
+}
+
+// runJSON runs bw and decodes its output into v
+func (c *Client) runJSON(ctx context.Context, v any, args ...string) error {
+	output, err := c.Run(ctx, args...)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(output, v); err != nil {
+		return fmt.Errorf("failed to decode the output of bw %s: %w", strings.Join(args[:min(2, len(args))], " "), err)
+	}
+	return nil
+}
+
+// Status returns the state of the vault
+func (c *Client) Status(ctx context.Context) (*Status, error) {
+	var status Status
+	if err := c.runJSON(ctx, &status, "status"); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// CheckUnlocked returns ErrUnauthenticated or ErrLocked unless the vault can
+// be read with the client's session
+func (c *Client) CheckUnlocked(ctx context.Context) error {
+	status, err := c.Status(ctx)
+	if err != nil {
+		return err
+	}
+	return status.Err()
+}
+
+// Unlock runs bw unlock --raw, which prompts for the master password on
+// prompt and reads it from in, and returns the session key. The key is saved
+// in the client's session.
+func (c *Client) Unlock(ctx context.Context, in io.Reader, prompt io.Writer) (string, error) {
+	output, err := c.execute(ctx, Command{Args: []string{"unlock", "--raw"}, Stdin: in, Stderr: prompt})
+	if err != nil {
+		return "", err
+	}
+	return c.saveKey(output)
+}
+
+// passwordEnv is the environment variable UnlockWithPassword passes the
+// master password in, so that it never shows up in the arguments of bw
+const passwordEnv = "CHEZMOI_TUI_BW_PASSWORD"
+
+// UnlockWithPassword unlocks the vault with a master password that has
+// already been read, e.g. by the TUI, and returns the session key. The key is
+// saved in the client's session.
+func (c *Client) UnlockWithPassword(ctx context.Context, password string) (string, error) {
+	output, err := c.execute(ctx, Command{
+		Args: []string{"unlock", "--raw", "--passwordenv", passwordEnv},
+		Env:  []string{passwordEnv + "=" + password},
+	})
+	if err != nil {
+		return "", err
+	}
+	return c.saveKey(output)
+}
+
+// saveKey saves the session key printed by bw unlock --raw
+func (c *Client) saveKey(output []byte) (string, error) {
+	key := strings.TrimSpace(string(output))
+	if key == "" {
+		return "", errors.New("bw unlock printed no session key")
+	}
+	if c.session != nil {
+		if err := c.session.Save(key); err != nil {
+			return "", err
+		}
+	}
+	return key, nil
+}
+
+// Lock locks the vault and forgets the key of the client's session
+func (c *Client) Lock(ctx context.Context) error {
+	if _, err := c.Run(ctx, "lock"); err != nil {
+		return err
+	}
+	if c.session != nil {
+		return c.session.Clear()
+	}
+	return nil
+}
+
+// LockSession locks the vault unlocked with key, without reading the
+// client's session. It is the lock function of an expiring Session.
+func (c *Client) LockSession(ctx context.Context, key string) error {
+	_, err := c.execute(ctx, Command{Args: []string{"lock"}, Env: []string{SessionEnv + "=" + key}})
+	return err
+}
+
+// Sync pulls the latest vault data from the server
+func (c *Client) Sync(ctx context.Context) error {
+	_, err := c.Run(ctx, "sync")
+	return err
+}
+
+// ItemFilter selects the items listed by Items. Empty fields do not filter.
+type ItemFilter struct {
+	// Search matches the names and other fields of items, as bw list
+	// --search does
+	Search       string
+	FolderID     string
+	CollectionID string
+}
+
+// Items lists the items matching filter
+func (c *Client) Items(ctx context.Context, filter ItemFilter) ([]Item, error) {
+	args := []string{"list", "items"}
+	if filter.Search != "" {
+		args = append(args, "--search", filter.Search)
+	}
+	if filter.FolderID != "" {
+		args = append(args, "--folderid", filter.FolderID)
+	}
+	if filter.CollectionID != "" {
+		args = append(args, "--collectionid", filter.CollectionID)
+	}
+
+	var items []Item
+	if err := c.runJSON(ctx, &items, args...); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// Item fetches an item by ID or by a search term that matches a single item.
+// An unknown item is reported as an error matching ErrNotFound.
+func (c *Client) Item(ctx context.Context, idOrName string) (*Item, error) {
+	var item Item
+	if err := c.runJSON(ctx, &item, "get", "item", idOrName); err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+// Folders lists the folders whose names match search, or all folders if
+// search is empty
+func (c *Client) Folders(ctx context.Context, search string) ([]Folder, error) {
+	var folders []Folder
+	if err := c.runJSON(ctx, &folders, listArgs("folders", search)...); err != nil {
+		return nil, err
+	}
+	return folders, nil
+}
+
+// Collections lists the collections whose names match search, or all
+// collections if search is empty
+func (c *Client) Collections(ctx context.Context, search string) ([]Collection, error) {
+	var collections []Collection
+	if err := c.runJSON(ctx, &collections, listArgs("collections", search)...); err != nil {
+		return nil, err
+	}
+	return collections, nil
+}
+
+// FindFolder returns the folder whose ID is nameOrID or whose name is
+// nameOrID, ignoring case
+func (c *Client) FindFolder(ctx context.Context, nameOrID string) (*Folder, error) {
+	folders, err := c.Folders(ctx, nameOrID)
+	if err != nil {
+		return nil, err
+	}
+	i, err := findNamed("folder", nameOrID, len(folders), func(i int) (string, string) {
+		return folders[i].ID, folders[i].Name
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &folders[i], nil
+}
+
+// FindCollection returns the collection whose ID is nameOrID or whose name
+// is nameOrID, ignoring case
+func (c *Client) FindCollection(ctx context.Context, nameOrID string) (*Collection, error) {
+	collections, err := c.Collections(ctx, nameOrID)
+	if err != nil {
+		return nil, err
+	}
+	i, err := findNamed("collection", nameOrID, len(collections), func(i int) (string, string) {
+		return collections[i].ID, collections[i].Name
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &collections[i], nil
+}
+
+// listArgs returns the arguments of bw list for kind
+func listArgs(kind, search string) []string {
+	args := []string{"list", kind}
+	if search != "" {
+		args = append(args, "--search", search)
+	}
+	return args
+}
+
+// findNamed returns the index of the single one of n objects, described by
+// ref, whose ID or name is nameOrID. bw list --search also returns partial
+// matches, so the results must be filtered.
+func findNamed(kind, nameOrID string, n int, ref func(i int) (id, name string)) (int, error) {
+	var matches []int
+	for i := 0; i < n; i++ {
+		if id, name := ref(i); id == nameOrID || strings.EqualFold(name, nameOrID) {
+			matches = append(matches, i)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return 0, fmt.Errorf("%w: no %s named %q", ErrNotFound, kind, nameOrID)
+	case 1:
+		return matches[0], nil
+	}
+	return 0, fmt.Errorf("%d %ss are named %q, use the ID instead", len(matches), kind, nameOrID)
+}
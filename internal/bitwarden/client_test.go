@@ -0,0 +1,200 @@
+package bitwarden
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeVault is a fake bw that logs its arguments and session key to
+// $BW_LOG and answers from a vault with two items. The vault is unlocked
+// when BW_SESSION is "key".
+const fakeVault = `echo "$* [$BW_SESSION]" >> "$BW_LOG"
+if [ "$1" = "unlock" ]; then
+	if [ "$3" = "--passwordenv" ]; then
+		eval password=\"\$$4\"
+	else
+		read password
+		echo "? Master password: [hidden]" >&2
+	fi
+	[ "$password" = "hunter2" ] || { echo "Invalid master password." >&2; exit 1; }
+	echo "key"; exit 0
+fi
+if [ "$BW_SESSION" != "key" ]; then
+	[ "$1" = "status" ] && { echo '{"serverUrl":null,"lastSync":null,"status":"locked"}'; exit 0; }
+	echo "Vault is locked." >&2; exit 1
+fi
+case "$1 $2" in
+"status ") echo '{"serverUrl":"https://vault.example.com","lastSync":"2024-03-01T12:30:00.000Z","userEmail":"jane@example.com","status":"unlocked"}' ;;
+"get item") [ "$3" = "GitHub" ] || { echo "Not found." >&2; exit 1; }
+	echo '{"id":"i1","type":1,"name":"GitHub","notes":null,"login":{"username":"octocat","password":"p","totp":null,"uris":[{"match":null,"uri":"https://github.com"}]},"fields":[{"name":"token","value":"ghp_123","type":1}]}' ;;
+"list items") echo '[{"id":"i1","type":1,"name":"GitHub"},{"id":"i2","type":2,"name":"Notes","login":null}]' ;;
+"list folders") echo '[{"id":"f1","name":"Work"},{"id":"f2","name":"Workshop"},{"id":"f3","name":"work"}]' ;;
+"lock ") echo "Your vault is locked." ;;
+*) echo "unexpected" >&2; exit 1 ;;
+esac
+`
+
+// newFakeClient returns a client of a fake bw running script, with a session
+// in a temporary directory, and the file the fake logs its calls to
+func newFakeClient(t *testing.T, script string) (*Client, string) {
+	t.Helper()
+	t.Setenv(SessionEnv, "")
+
+	dir := t.TempDir()
+	bw := filepath.Join(dir, "bw")
+	if err := os.WriteFile(bw, []byte("#!/bin/sh\n"+script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	log := filepath.Join(dir, "calls")
+	t.Setenv("BW_LOG", log)
+
+	session := NewSession(filepath.Join(dir, "cache", "bw-session"), time.Hour, nil)
+	client, err := New(WithBinaryPath(bw), WithSession(session))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client, log
+}
+
+func TestNewWithMissingBinary(t *testing.T) {
+	_, err := New(WithBinaryPath("/nonexistent/bw"))
+	if !errors.Is(err, ErrNotInstalled) {
+		t.Errorf("Expected ErrNotInstalled, got: %v", err)
+	}
+}
+
+func TestClientLocked(t *testing.T) {
+	client, _ := newFakeClient(t, fakeVault)
+	ctx := context.Background()
+
+	status, err := client.Status(ctx)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if status.Status != StatusLocked || !status.LastSync.IsZero() {
+		t.Errorf("Unexpected status %+v", status)
+	}
+	if err := client.CheckUnlocked(ctx); !errors.Is(err, ErrLocked) {
+		t.Errorf("Expected ErrLocked, got: %v", err)
+	}
+
+	_, err = client.Items(ctx, ItemFilter{})
+	if !errors.Is(err, ErrLocked) {
+		t.Errorf("Expected the failure to match ErrLocked, got: %v", err)
+	}
+	var execErr *ExecError
+	if !errors.As(err, &execErr) || execErr.ExitCode != 1 || err.Error() != "bw list items failed (exit code 1): Vault is locked." {
+		t.Errorf("Unexpected error %v", err)
+	}
+	if hint := Remediation(err); !strings.Contains(hint, "bitwarden unlock") {
+		t.Errorf("Expected a hint to unlock the vault, got %q", hint)
+	}
+}
+
+func TestClientUnlocked(t *testing.T) {
+	client, log := newFakeClient(t, fakeVault)
+	ctx := context.Background()
+
+	var prompt strings.Builder
+	if _, err := client.Unlock(ctx, strings.NewReader("wrong\n"), &prompt); err == nil {
+		t.Fatal("Expected a wrong password to be refused")
+	}
+	key, err := client.Unlock(ctx, strings.NewReader("hunter2\n"), &prompt)
+	if err != nil || key != "key" {
+		t.Fatalf("Expected the session key, got %q, %v", key, err)
+	}
+	if !strings.Contains(prompt.String(), "Master password") {
+		t.Errorf("Expected the prompt to be shown, got %q", prompt.String())
+	}
+
+	status, err := client.Status(ctx)
+	if err != nil || status.Err() != nil {
+		t.Fatalf("Expected an unlocked vault, got %+v, %v", status, err)
+	}
+	if status.UserEmail != "jane@example.com" || status.LastSync.Format(time.RFC3339) != "2024-03-01T12:30:00Z" {
+		t.Errorf("Unexpected status %+v", status)
+	}
+
+	item, err := client.Item(ctx, "GitHub")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if item.Type != ItemLogin || item.Notes != "" || item.Login.Username != "octocat" || item.Login.URIs[0].URI != "https://github.com" {
+		t.Errorf("Unexpected item %+v", item)
+	}
+	if !reflect.DeepEqual(item.Fields, []Field{{Name: "token", Value: "ghp_123", Type: FieldHidden}}) {
+		t.Errorf("Unexpected fields %+v", item.Fields)
+	}
+	if _, err := client.Item(ctx, "Gitlab"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got: %v", err)
+	}
+
+	items, err := client.Items(ctx, ItemFilter{Search: "git", FolderID: "f1"})
+	if err != nil || len(items) != 2 || items[1].Type.String() != "note" || items[1].Login != nil {
+		t.Errorf("Unexpected items %+v, %v", items, err)
+	}
+
+	if err := client.Lock(ctx); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if key, _ := client.Session().Peek(); key != "" {
+		t.Errorf("Expected the session to be forgotten, got %q", key)
+	}
+
+	data, _ := os.ReadFile(log)
+	expected := "unlock --raw []\nunlock --raw []\nstatus [key]\nget item GitHub [key]\nget item Gitlab [key]\n" +
+		"list items --search git --folderid f1 [key]\nlock [key]\n"
+	if string(data) != expected {
+		t.Errorf("Unexpected calls:\n%s", data)
+	}
+}
+
+func TestUnlockWithPassword(t *testing.T) {
+	client, log := newFakeClient(t, fakeVault)
+	ctx := context.Background()
+
+	if _, err := client.UnlockWithPassword(ctx, "wrong"); err == nil || !strings.Contains(err.Error(), "Invalid master password") {
+		t.Errorf("Expected a wrong password to be refused, got: %v", err)
+	}
+	if key, _ := client.Session().Peek(); key != "" {
+		t.Errorf("Expected no session after a failed unlock, got %q", key)
+	}
+	if key, err := client.UnlockWithPassword(ctx, "hunter2"); err != nil || key != "key" {
+		t.Fatalf("Expected the session key, got %q, %v", key, err)
+	}
+	if key, _ := client.Session().Peek(); key != "key" {
+		t.Errorf("Expected the session key to be saved, got %q", key)
+	}
+
+	data, _ := os.ReadFile(log)
+	if strings.Contains(string(data), "hunter2") {
+		t.Errorf("Expected the password to be kept out of the arguments, got:\n%s", data)
+	}
+}
+
+func TestFindFolder(t *testing.T) {
+	client, _ := newFakeClient(t, fakeVault)
+	ctx := context.Background()
+	if err := client.Session().Save("key"); err != nil {
+		t.Fatal(err)
+	}
+
+	if folder, err := client.FindFolder(ctx, "workshop"); err != nil || folder.ID != "f2" {
+		t.Errorf("Expected the folder named workshop, got %+v, %v", folder, err)
+	}
+	if folder, err := client.FindFolder(ctx, "f1"); err != nil || folder.Name != "Work" {
+		t.Errorf("Expected the folder with the ID, got %+v, %v", folder, err)
+	}
+	if _, err := client.FindFolder(ctx, "work"); err == nil || !strings.Contains(err.Error(), "use the ID") {
+		t.Errorf("Expected folders with the same name to be refused, got: %v", err)
+	}
+	if _, err := client.FindFolder(ctx, "home"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got: %v", err)
+	}
+}
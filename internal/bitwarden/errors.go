@@ -0,0 +1,88 @@
+package bitwarden
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os/exec"
+	"strings"
+)
+
+// Sentinel errors matched through errors.Is by the errors of the client
+var (
+	ErrNotInstalled    = errors.New("Bitwarden CLI (bw) not found")
+	ErrUnauthenticated = errors.New("not logged in to Bitwarden")
+	ErrLocked          = errors.New("Bitwarden vault is locked")
+	ErrNotFound        = errors.New("not found in the Bitwarden vault")
+)
+
+// ExecError describes a failed bw invocation
+type ExecError struct {
+	// Args are the arguments bw was invoked with
+	Args []string
+	// ExitCode is the process exit code, or -1 if it did not exit normally
+	ExitCode int
+	// Stderr holds everything the process wrote to standard error
+	Stderr string
+	// Err is the underlying error from os/exec or the context
+	Err error
+}
+
+// Error implements the error interface
+func (e *ExecError) Error() string {
+	command := "bw " + strings.Join(e.Args, " ")
+	if msg := firstLine(e.Stderr); msg != "" {
+		return fmt.Sprintf("%s failed (exit code %d): %s", command, e.ExitCode, msg)
+	}
+	return fmt.Sprintf("%s failed: %v", command, e.Err)
+}
+
+// Unwrap returns the underlying error
+func (e *ExecError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether the error matches one of the package sentinel errors,
+// judging by what bw printed
+func (e *ExecError) Is(target error) bool {
+	stderr := strings.ToLower(e.Stderr)
+	switch target {
+	case ErrNotInstalled:
+		return errors.Is(e.Err, exec.ErrNotFound) || errors.Is(e.Err, fs.ErrNotExist)
+	case ErrUnauthenticated:
+		return strings.Contains(stderr, "you are not logged in")
+	case ErrLocked:
+		return strings.Contains(stderr, "vault is locked") ||
+			strings.Contains(stderr, "session key is invalid")
+	case ErrNotFound:
+		return strings.HasPrefix(strings.TrimSpace(stderr), "not found")
+	}
+	return false
+}
+
+// Remediation returns a suggestion for resolving a Bitwarden failure
+// anywhere in err's chain, or an empty string when there is nothing specific
+// to suggest
+func Remediation(err error) string {
+	switch {
+	case errors.Is(err, ErrNotInstalled):
+		return "Install the Bitwarden CLI, or set integration.bitwarden_binary_path in your config."
+	case errors.Is(err, ErrUnauthenticated):
+		return "Log in with 'bw login' first."
+	case errors.Is(err, ErrLocked):
+		return "Unlock the vault with 'chezmoi-tui bitwarden unlock'."
+	case errors.Is(err, ErrNotFound):
+		return "Check the name or ID with 'chezmoi-tui bitwarden list'."
+	}
+	return ""
+}
+
+// firstLine returns the first non-empty line of s
+func firstLine(s string) string {
+	for _, line := range strings.Split(s, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			return line
+		}
+	}
+	return ""
+}
@@ -0,0 +1,61 @@
+package bitwarden
+
+import (
+	"context"
+	"io"
+	"os"
+	"os/exec"
+	"time"
+)
+
+// waitDelay bounds how long Run waits for output pipes to close after bw has
+// been killed
+const waitDelay = 2 * time.Second
+
+// Executor starts bw processes. It separates the client from the operating
+// system so that tests can substitute a fake.
+type Executor interface {
+	// Execute runs the command and returns once it has exited. A non-zero
+	// exit status must be reported as an error with an ExitCode() int method,
+	// as *exec.ExitError does.
+	Execute(ctx context.Context, cmd Command) error
+}
+
+// Command describes a single invocation of the bw binary
+type Command struct {
+	// Path is the bw binary to run
+	Path string
+	// Args are the arguments passed to bw
+	Args []string
+	// Env holds variables, in the form key=value, added to the environment
+	// inherited from the current process
+	Env []string
+	// Stdin, Stdout and Stderr connect the process to the caller. Nil
+	// streams are connected to the null device.
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+// ProcessExecutor runs commands as operating system processes. It is the
+// default Executor.
+type ProcessExecutor struct{}
+
+// Execute implements Executor. The process is killed when ctx is done.
+func (ProcessExecutor) Execute(ctx context.Context, command Command) error {
+	cmd := exec.CommandContext(ctx, command.Path, command.Args...)
+	cmd.Stdin = command.Stdin
+	cmd.Stdout = command.Stdout
+	cmd.Stderr = command.Stderr
+	if len(command.Env) > 0 {
+		cmd.Env = append(os.Environ(), command.Env...)
+	}
+	cmd.WaitDelay = waitDelay
+
+	return cmd.Run()
+}
+
+// exitCoder is implemented by errors that carry a process exit code
+type exitCoder interface {
+	ExitCode() int
+}
@@ -0,0 +1,224 @@
+package bitwarden
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"chezmoi-tui/internal/fsutil"
+)
+
+// SessionEnv is the environment variable through which bw, and chezmoi's
+// bitwarden template functions, receive the session key of an unlocked vault
+const SessionEnv = "BW_SESSION"
+
+// Session stores the key printed by bw unlock --raw in a cache file that only
+// the user can read, so that it outlives the command that unlocked the vault.
+// A key that has not been used for longer than the idle timeout is locked
+// and forgotten the next time it is asked for. It is safe for concurrent
+// use.
+type Session struct {
+	path    string
+	timeout time.Duration
+	lock    func(key string) error
+	now     func() time.Time
+	mu      sync.Mutex
+}
+
+// sessionFile is the contents of the cache file
+type sessionFile struct {
+	Key      string    `json:"key"`
+	LastUsed time.Time `json:"last_used"`
+}
+
+// NewSession returns a session cached at path that expires after timeout of
+// idle time, or never if timeout is zero. lock is called with the key of an
+// expired session to lock the vault; if it is nil the key is only
+// forgotten.
+func NewSession(path string, timeout time.Duration, lock func(key string) error) *Session {
+	return &Session{path: path, timeout: timeout, lock: lock, now: time.Now}
+}
+
+// DefaultSessionPath returns the cache file in the user's cache directory,
+// e.g. ~/.cache/chezmoi-tui/bw-session
+func DefaultSessionPath() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = fsutil.ExpandHome("~/.cache")
+	}
+	return filepath.Join(dir, "chezmoi-tui", "bw-session")
+}
+
+// Path returns the cache file
+func (s *Session) Path() string {
+	return s.path
+}
+
+// Timeout returns the idle time after which the session expires, zero if it
+// never does
+func (s *Session) Timeout() time.Duration {
+	return s.timeout
+}
+
+// Key returns the session key and marks it used, which restarts the idle
+// timeout. A BW_SESSION variable in the environment takes precedence over
+// the cache and never expires. The key is empty if there is no session,
+// e.g. because it expired.
+func (s *Session) Key() (string, error) {
+	return s.key(true)
+}
+
+// Peek returns the session key like Key without restarting the idle
+// timeout, for commands run in the background such as the periodic status
+// refresh, which must not keep the vault unlocked forever
+func (s *Session) Peek() (string, error) {
+	return s.key(false)
+}
+
+// Remaining returns the idle time left until the cached key expires. ok is
+// false if there is no cached key or it never expires, e.g. because the
+// timeout is zero or the key comes from BW_SESSION. The key is only locked
+// when it is next asked for, so a process that keeps running calls Peek once
+// the time is up.
+func (s *Session) Remaining() (remaining time.Duration, ok bool) {
+	if s.timeout <= 0 || os.Getenv(SessionEnv) != "" {
+		return 0, false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cached, err := s.read()
+	if err != nil || cached == nil {
+		return 0, false
+	}
+	return max(s.timeout-s.now().Sub(cached.LastUsed), 0), true
+}
+
+// Env returns the environment passing the session key to a child process,
+// or nil if there is no session. It does not restart the idle timeout.
+// Errors reading the cache are treated as if there were no session.
+func (s *Session) Env() []string {
+	key, _ := s.Peek()
+	if key == "" {
+		return nil
+	}
+	return []string{SessionEnv + "=" + key}
+}
+
+// key implements Key and Peek
+func (s *Session) key(touch bool) (string, error) {
+	if key := os.Getenv(SessionEnv); key != "" {
+		return key, nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cached, err := s.read()
+	if err != nil || cached == nil {
+		return "", err
+	}
+	now := s.now()
+	if s.timeout > 0 && now.Sub(cached.LastUsed) > s.timeout {
+		return "", s.expire(cached.Key)
+	}
+	if touch {
+		cached.LastUsed = now
+		if err := s.write(cached); err != nil {
+			return "", err
+		}
+	}
+	return cached.Key, nil
+}
+
+// expire locks the vault of an expired key and forgets it
+func (s *Session) expire(key string) error {
+	var errs []error
+	if s.lock != nil {
+		if err := s.lock(key); err != nil {
+			errs = append(errs, fmt.Errorf("failed to lock the expired Bitwarden session: %w", err))
+		}
+	}
+	if err := s.remove(); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// Save caches the key of a vault that has just been unlocked
+func (s *Session) Save(key string) error {
+	if key == "" {
+		return errors.New("empty session key")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.write(&sessionFile{Key: key, LastUsed: s.now()})
+}
+
+// Clear forgets the cached key, e.g. after the vault has been locked. It is
+// not an error if there is none.
+func (s *Session) Clear() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.remove()
+}
+
+// read decodes the cache file, returning nil if it does not exist. A file
+// that others can read is removed rather than trusted.
+func (s *Session) read() (*sessionFile, error) {
+	info, err := os.Stat(s.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the Bitwarden session: %w", err)
+	}
+	if info.Mode().Perm()&0o077 != 0 {
+		s.remove()
+		return nil, fmt.Errorf("removed the Bitwarden session cache %s: permissions %04o allow access by others", s.path, info.Mode().Perm())
+	}
+
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the Bitwarden session: %w", err)
+	}
+	var cached sessionFile
+	if err := json.Unmarshal(data, &cached); err != nil || cached.Key == "" {
+		s.remove()
+		return nil, nil
+	}
+	return &cached, nil
+}
+
+// write replaces the cache file atomically, creating it with 0600
+// permissions in a directory only the user can access
+func (s *Session) write(cached *sessionFile) error {
+	data, err := json.Marshal(cached)
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Dir(s.path)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return fmt.Errorf("failed to save the Bitwarden session: %w", err)
+	}
+	if err := fsutil.WriteFileAtomic(s.path, data, 0o600); err != nil {
+		return fmt.Errorf("failed to save the Bitwarden session: %w", err)
+	}
+	return nil
+}
+
+// remove deletes the cache file
+func (s *Session) remove() error {
+	if err := os.Remove(s.path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to remove the Bitwarden session: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,143 @@
+package bitwarden
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// newTestSession returns a session in a temporary directory with a clock
+// that tests advance by hand
+func newTestSession(t *testing.T, timeout time.Duration, lock func(string) error) (*Session, *time.Time) {
+	t.Helper()
+	t.Setenv(SessionEnv, "")
+
+	clock := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	s := NewSession(filepath.Join(t.TempDir(), "chezmoi-tui", "bw-session"), timeout, lock)
+	s.now = func() time.Time { return clock }
+	return s, &clock
+}
+
+func TestSessionSaveAndKey(t *testing.T) {
+	s, _ := newTestSession(t, time.Hour, nil)
+
+	if key, err := s.Key(); key != "" || err != nil {
+		t.Fatalf("Expected no session before unlocking, got %q, %v", key, err)
+	}
+	if err := s.Save("secret-key"); err != nil {
+		t.Fatal(err)
+	}
+	if key, err := s.Key(); key != "secret-key" || err != nil {
+		t.Errorf("Expected the saved key, got %q, %v", key, err)
+	}
+	if env := s.Env(); len(env) != 1 || env[0] != "BW_SESSION=secret-key" {
+		t.Errorf("Unexpected environment %v", env)
+	}
+
+	info, err := os.Stat(s.Path())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0o600 {
+		t.Errorf("Expected 0600 permissions, got %v", info.Mode())
+	}
+	if dir, _ := os.Stat(filepath.Dir(s.Path())); dir.Mode().Perm() != 0o700 {
+		t.Errorf("Expected a 0700 directory, got %v", dir.Mode())
+	}
+
+	if err := s.Clear(); err != nil {
+		t.Fatal(err)
+	}
+	if s.Env() != nil {
+		t.Error("Expected no session after clearing")
+	}
+}
+
+func TestSessionIdleTimeout(t *testing.T) {
+	var locked []string
+	s, clock := newTestSession(t, 30*time.Minute, func(key string) error {
+		locked = append(locked, key)
+		return nil
+	})
+	if err := s.Save("secret-key"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Using the key restarts the timeout, peeking at it does not
+	*clock = clock.Add(20 * time.Minute)
+	if key, _ := s.Key(); key != "secret-key" {
+		t.Fatalf("Expected the key within the timeout, got %q", key)
+	}
+	*clock = clock.Add(20 * time.Minute)
+	if key, _ := s.Peek(); key != "secret-key" {
+		t.Fatalf("Expected the key to have been kept alive, got %q", key)
+	}
+	if len(locked) != 0 {
+		t.Fatalf("Expected the vault to stay unlocked, got %v", locked)
+	}
+
+	*clock = clock.Add(15 * time.Minute)
+	if env := s.Env(); env != nil {
+		t.Errorf("Expected the idle session to expire, got %v", env)
+	}
+	if len(locked) != 1 || locked[0] != "secret-key" {
+		t.Errorf("Expected the expired session to be locked, got %v", locked)
+	}
+	if _, err := os.Stat(s.Path()); !os.IsNotExist(err) {
+		t.Errorf("Expected the cache to be removed, got %v", err)
+	}
+}
+
+func TestSessionRemaining(t *testing.T) {
+	s, clock := newTestSession(t, 30*time.Minute, nil)
+	if _, ok := s.Remaining(); ok {
+		t.Error("Expected no remaining time without a session")
+	}
+	if err := s.Save("secret-key"); err != nil {
+		t.Fatal(err)
+	}
+
+	*clock = clock.Add(20 * time.Minute)
+	if remaining, ok := s.Remaining(); !ok || remaining != 10*time.Minute {
+		t.Errorf("Expected 10m remaining, got %v, %v", remaining, ok)
+	}
+	*clock = clock.Add(time.Hour)
+	if remaining, ok := s.Remaining(); !ok || remaining != 0 {
+		t.Errorf("Expected the session to be due, got %v, %v", remaining, ok)
+	}
+
+	t.Setenv(SessionEnv, "from-env")
+	if _, ok := s.Remaining(); ok {
+		t.Error("Expected BW_SESSION never to expire")
+	}
+}
+
+func TestSessionEnvironmentWins(t *testing.T) {
+	s, _ := newTestSession(t, time.Minute, nil)
+	if err := s.Save("cached"); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(SessionEnv, "from-env")
+
+	if key, _ := s.Key(); key != "from-env" {
+		t.Errorf("Expected BW_SESSION to take precedence, got %q", key)
+	}
+}
+
+func TestSessionRejectsReadableCache(t *testing.T) {
+	s, _ := newTestSession(t, 0, nil)
+	if err := s.Save("secret-key"); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(s.Path(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if key, err := s.Key(); key != "" || err == nil {
+		t.Errorf("Expected a cache readable by others to be refused, got %q, %v", key, err)
+	}
+	if _, err := os.Stat(s.Path()); !os.IsNotExist(err) {
+		t.Errorf("Expected the cache to be removed, got %v", err)
+	}
+}
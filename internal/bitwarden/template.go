@@ -0,0 +1,194 @@
+package bitwarden
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"chezmoi-tui/internal/chezmoi"
+	"chezmoi-tui/internal/fsutil"
+)
+
+// ItemField is a field of an item that templates and exports read
+type ItemField struct {
+	// Selector names the field, e.g. login.password or fields.api_key
+	Selector string
+	// Value is the value of the field in the vault
+	Value string
+	// Expr is the chezmoi template expression that reads the field
+	Expr string
+}
+
+// ItemFields lists the fields of an item that are set, login first, then the
+// notes and the custom fields
+func ItemFields(item *Item) []ItemField {
+	itemArgs := fmt.Sprintf(`"item" %s`, strconv.Quote(item.ID))
+	var fields []ItemField
+	if login := item.Login; login != nil {
+		for _, f := range []struct{ name, value string }{
+			{"username", login.Username},
+			{"password", login.Password},
+			{"totp", login.Totp},
+		} {
+			if f.value != "" {
+				fields = append(fields, ItemField{
+					Selector: "login." + f.name,
+					Value:    f.value,
+					Expr:     fmt.Sprintf("(bitwarden %s).login.%s", itemArgs, f.name),
+				})
+			}
+		}
+	}
+	if item.Notes != "" {
+		fields = append(fields, ItemField{Selector: "notes", Value: item.Notes, Expr: fmt.Sprintf("(bitwarden %s).notes", itemArgs)})
+	}
+	for _, f := range item.Fields {
+		expr := fmt.Sprintf("(bitwardenFields %s).%s.value", itemArgs, f.Name)
+		if !templateIdentifier.MatchString(f.Name) {
+			expr = fmt.Sprintf("(index (bitwardenFields %s) %s).value", itemArgs, strconv.Quote(f.Name))
+		}
+		fields = append(fields, ItemField{Selector: "fields." + f.Name, Value: f.Value, Expr: expr})
+	}
+	return fields
+}
+
+var (
+	// templateIdentifier matches field names that templates can read with
+	// a dot
+	templateIdentifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	// variableName matches valid shell variable names
+	variableName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	// nonVariableChars are the runs of characters replaced by an underscore
+	// when deriving a variable name
+	nonVariableChars = regexp.MustCompile(`[^A-Z0-9]+`)
+)
+
+// ValidVariable reports whether name is a valid shell variable name
+func ValidVariable(name string) bool {
+	return variableName.MatchString(name)
+}
+
+// DefaultVariable derives a variable name from the name of an item and the
+// selector of a field, e.g. GITHUB_API_KEY for fields.api key of the item
+// "GitHub" or GITHUB_PASSWORD for login.password
+func DefaultVariable(itemName, selector string) string {
+	field := strings.TrimPrefix(strings.TrimPrefix(selector, "login."), "fields.")
+	name := nonVariableChars.ReplaceAllString(strings.ToUpper(itemName+"_"+field), "_")
+	name = strings.Trim(name, "_")
+	if name == "" || (name[0] >= '0' && name[0] <= '9') {
+		name = "_" + name
+	}
+	return name
+}
+
+// TemplateVariable maps a field to the variable it is assigned to
+type TemplateVariable struct {
+	Name  string
+	Field ItemField
+}
+
+// Template formats
+const (
+	// FormatShell assigns variables with export VAR='...'
+	FormatShell = "shell"
+	// FormatEnv assigns variables with VAR="..."
+	FormatEnv = "env"
+)
+
+// RenderTemplate renders the template lines assigning the variables in the
+// given format, FormatShell or FormatEnv
+func RenderTemplate(item *Item, vars []TemplateVariable, format string) string {
+	var b strings.Builder
+	// The item name must not open an action in the comment
+	name := strings.NewReplacer("{{", "{ {", "}}", "} }").Replace(item.Name)
+	fmt.Fprintf(&b, "# Bitwarden item %q (%s), generated by chezmoi-tui\n", name, item.ID)
+	for _, v := range vars {
+		switch format {
+		case FormatEnv:
+			fmt.Fprintf(&b, "%s={{ %s | quote }}\n", v.Name, v.Field.Expr)
+		default:
+			// Single quotes keep the shell from expanding the value
+			fmt.Fprintf(&b, "export %s={{ %s | replace \"'\" \"'\\\\''\" | squote }}\n", v.Name, v.Field.Expr)
+		}
+	}
+	return b.String()
+}
+
+// CheckTemplateVariables returns an error if a variable name is invalid,
+// repeated, or already assigned by the existing template
+func CheckTemplateVariables(vars []TemplateVariable, existing string) error {
+	if len(vars) == 0 {
+		return errors.New("no fields were selected")
+	}
+	seen := make(map[string]bool, len(vars))
+	for _, v := range vars {
+		if !variableName.MatchString(v.Name) {
+			return fmt.Errorf("%q is not a valid variable name", v.Name)
+		}
+		if seen[v.Name] {
+			return fmt.Errorf("%s is assigned more than once", v.Name)
+		}
+		seen[v.Name] = true
+		if regexp.MustCompile(`(?m)^(export\s+)?` + v.Name + `=`).MatchString(existing) {
+			return fmt.Errorf("the template already sets %s", v.Name)
+		}
+	}
+	return nil
+}
+
+// TemplateMode decides what WriteTemplate does with an existing template
+type TemplateMode int
+
+const (
+	// TemplateCreate refuses to touch an existing template
+	TemplateCreate TemplateMode = iota
+	// TemplateAppend adds the variables to an existing template
+	TemplateAppend
+	// TemplateReplace overwrites an existing template
+	TemplateReplace
+)
+
+// WriteTemplate writes the template assigning vars from item to the source
+// file path, which must be a template by its name. An existing file keeps
+// its permissions; how its contents are treated depends on mode.
+func WriteTemplate(path string, item *Item, vars []TemplateVariable, format string, mode TemplateMode) error {
+	if !chezmoi.ParseSourceName(filepath.Base(path), false).Template {
+		return fmt.Errorf("%s does not end in .tmpl, so chezmoi would not execute it as a template", path)
+	}
+
+	var existing []byte
+	perm := os.FileMode(0o644)
+	if info, err := os.Stat(path); err == nil {
+		switch mode {
+		case TemplateCreate:
+			return fmt.Errorf("%s already exists", path)
+		case TemplateAppend:
+			if existing, err = os.ReadFile(path); err != nil {
+				return fmt.Errorf("failed to read template: %w", err)
+			}
+		}
+		perm = info.Mode().Perm()
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to check template: %w", err)
+	}
+	if err := CheckTemplateVariables(vars, string(existing)); err != nil {
+		return err
+	}
+
+	content := RenderTemplate(item, vars, format)
+	if len(existing) > 0 {
+		content = strings.TrimRight(string(existing), "\n") + "\n\n" + content
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	if err := fsutil.WriteFileAtomic(path, []byte(content), perm); err != nil {
+		return fmt.Errorf("failed to write template: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package bitwarden
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCheckTemplateVariables(t *testing.T) {
+	vars := []TemplateVariable{{Name: "GH_TOKEN"}}
+	if err := CheckTemplateVariables(vars, "export GH_TOKEN='x'\n"); err == nil {
+		t.Error("Expected a variable set by the existing template to be refused")
+	}
+	if err := CheckTemplateVariables([]TemplateVariable{{Name: "1X"}}, ""); err == nil {
+		t.Error("Expected an invalid variable name to be refused")
+	}
+	if err := CheckTemplateVariables(nil, ""); err == nil {
+		t.Error("Expected an error when no fields are selected")
+	}
+	if name := DefaultVariable("My Server (prod)", "fields.api-key"); name != "MY_SERVER_PROD_API_KEY" {
+		t.Errorf("Unexpected variable name %q", name)
+	}
+}
+
+func TestWriteTemplate(t *testing.T) {
+	item := &Item{ID: "i1", Name: "GitHub", Fields: []Field{{Name: "token", Value: "ghp_123"}}}
+	fields := ItemFields(item)
+	vars := []TemplateVariable{{Name: "GH_TOKEN", Field: fields[0]}}
+	path := filepath.Join(t.TempDir(), "private_dot_secrets.tmpl")
+
+	if err := WriteTemplate(filepath.Join(filepath.Dir(path), "dot_secrets"), item, vars, FormatShell, TemplateCreate); err == nil {
+		t.Error("Expected a source file that is not a template to be refused")
+	}
+	if err := WriteTemplate(path, item, vars, FormatEnv, TemplateCreate); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if err := WriteTemplate(path, item, vars, FormatEnv, TemplateCreate); err == nil {
+		t.Error("Expected an existing template to be kept")
+	}
+	if err := WriteTemplate(path, item, vars, FormatEnv, TemplateAppend); err == nil || !strings.Contains(err.Error(), "already sets GH_TOKEN") {
+		t.Errorf("Expected a variable to be set only once, got: %v", err)
+	}
+
+	vars[0].Name = "GITHUB_TOKEN"
+	if err := WriteTemplate(path, item, vars, FormatEnv, TemplateAppend); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	data, _ := os.ReadFile(path)
+	expected := `GH_TOKEN={{ (bitwardenFields "item" "i1").token.value | quote }}` + "\n\n"
+	if !strings.Contains(string(data), expected) || !strings.HasSuffix(string(data), "GITHUB_TOKEN={{ (bitwardenFields \"item\" \"i1\").token.value | quote }}\n") {
+		t.Errorf("Expected the variables to be appended, got:\n%s", data)
+	}
+}
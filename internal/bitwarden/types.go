@@ -0,0 +1,123 @@
+package bitwarden
+
+import "time"
+
+// Vault states reported by bw status
+const (
+	StatusUnauthenticated = "unauthenticated"
+	StatusLocked          = "locked"
+	StatusUnlocked        = "unlocked"
+)
+
+// Status is the result of bw status
+type Status struct {
+	ServerURL string `json:"serverUrl"`
+	// LastSync is zero if the vault has never been synced
+	LastSync  time.Time `json:"lastSync"`
+	UserEmail string    `json:"userEmail"`
+	UserID    string    `json:"userId"`
+	// Status is one of StatusUnauthenticated, StatusLocked and
+	// StatusUnlocked
+	Status string `json:"status"`
+}
+
+// Err returns ErrUnauthenticated or ErrLocked if the vault cannot be read,
+// or nil if it is unlocked
+func (s *Status) Err() error {
+	switch s.Status {
+	case StatusUnlocked:
+		return nil
+	case StatusUnauthenticated:
+		return ErrUnauthenticated
+	}
+	return ErrLocked
+}
+
+// ItemType is the kind of a vault item
+type ItemType int
+
+// Item types, numbered as by bw
+const (
+	ItemLogin      ItemType = 1
+	ItemSecureNote ItemType = 2
+	ItemCard       ItemType = 3
+	ItemIdentity   ItemType = 4
+)
+
+// String returns the name of the item type
+func (t ItemType) String() string {
+	switch t {
+	case ItemLogin:
+		return "login"
+	case ItemSecureNote:
+		return "note"
+	case ItemCard:
+		return "card"
+	case ItemIdentity:
+		return "identity"
+	}
+	return "unknown"
+}
+
+// Item is a vault item as printed by bw get item and bw list items. Only the
+// parts chezmoi-tui reads are decoded.
+type Item struct {
+	ID             string   `json:"id"`
+	OrganizationID string   `json:"organizationId"`
+	FolderID       string   `json:"folderId"`
+	CollectionIDs  []string `json:"collectionIds"`
+	Type           ItemType `json:"type"`
+	Name           string   `json:"name"`
+	Notes          string   `json:"notes"`
+	Favorite       bool     `json:"favorite"`
+	// Login is nil for items that are not logins
+	Login *Login `json:"login"`
+	// Fields are the custom fields of the item
+	Fields       []Field   `json:"fields"`
+	RevisionDate time.Time `json:"revisionDate"`
+}
+
+// Login holds the credentials of a login item
+type Login struct {
+	Username string     `json:"username"`
+	Password string     `json:"password"`
+	Totp     string     `json:"totp"`
+	URIs     []LoginURI `json:"uris"`
+}
+
+// LoginURI is a website or application a login is used for
+type LoginURI struct {
+	URI string `json:"uri"`
+}
+
+// FieldType is the kind of a custom field
+type FieldType int
+
+// Field types, numbered as by bw
+const (
+	FieldText    FieldType = 0
+	FieldHidden  FieldType = 1
+	FieldBoolean FieldType = 2
+	FieldLinked  FieldType = 3
+)
+
+// Field is a custom field of an item
+type Field struct {
+	Name  string    `json:"name"`
+	Value string    `json:"value"`
+	Type  FieldType `json:"type"`
+}
+
+// Folder is a folder of the vault
+type Folder struct {
+	// ID is empty for the pseudo-folder of items without a folder
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// Collection is a collection of an organization
+type Collection struct {
+	ID             string `json:"id"`
+	OrganizationID string `json:"organizationId"`
+	Name           string `json:"name"`
+}
@@ -1,71 +1,427 @@
 package chezmoi
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"os/exec"
-	"strings"
+	"time"
+
+	"chezmoi-tui/internal/fsutil"
 )
 
+// DefaultTimeout is the timeout applied to chezmoi commands when none is configured
+const DefaultTimeout = 30 * time.Second
+
+// waitDelay bounds how long Run waits for output pipes to close after the
+// process has been killed, in case a grandchild keeps them open
+const waitDelay = 2 * time.Second
+
 // Chezmoi wraps the chezmoi command-line tool
 type Chezmoi struct {
 	binaryPath string
+	sourceDir  string
+	destDir    string
+	configFile string
+	cacheDir   string
+	globalArgs []string
+	timeout    time.Duration
+	executor   Executor
+	env        func() []string
+	version    Version
 }
 
-// New creates a new Chezmoi wrapper
-func New() (*Chezmoi, error) {
-	binaryPath, err := exec.LookPath("chezmoi")
+// Option configures a Chezmoi wrapper
+type Option func(*Chezmoi)
+
+// WithTimeout sets the maximum duration of a single chezmoi command.
+// A zero or negative timeout disables the limit.
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *Chezmoi) {
+		c.timeout = timeout
+	}
+}
+
+// WithBinaryPath sets the chezmoi binary to run, either a path or a name to
+// look up in PATH. The default is "chezmoi".
+func WithBinaryPath(path string) Option {
+	return func(c *Chezmoi) {
+		c.binaryPath = fsutil.ExpandHome(path)
+	}
+}
+
+// WithSourceDir runs chezmoi against the given source directory
+func WithSourceDir(dir string) Option {
+	return func(c *Chezmoi) {
+		c.sourceDir = fsutil.ExpandHome(dir)
+	}
+}
+
+// WithDestDir runs chezmoi against the given destination directory
+func WithDestDir(dir string) Option {
+	return func(c *Chezmoi) {
+		c.destDir = fsutil.ExpandHome(dir)
+	}
+}
+
+// WithConfigFile makes chezmoi read the given config file
+func WithConfigFile(path string) Option {
+	return func(c *Chezmoi) {
+		c.configFile = fsutil.ExpandHome(path)
+	}
+}
+
+// WithCacheDir makes chezmoi use the given cache directory
+func WithCacheDir(dir string) Option {
+	return func(c *Chezmoi) {
+		c.cacheDir = fsutil.ExpandHome(dir)
+	}
+}
+
+// WithGlobalArgs passes extra global flags, such as --verbose or
+// --mode=symlink, to every chezmoi command
+func WithGlobalArgs(args ...string) Option {
+	return func(c *Chezmoi) {
+		c.globalArgs = append(c.globalArgs, args...)
+	}
+}
+
+// WithEnv adds the variables returned by env, in the form key=value, to the
+// environment of every chezmoi command. env is called for each command so
+// that values that change during the wrapper's lifetime, such as a Bitwarden
+// session key, are passed on.
+func WithEnv(env func() []string) Option {
+	return func(c *Chezmoi) {
+		c.env = env
+	}
+}
+
+// WithExecutor replaces the executor used to start chezmoi processes. The
+// binary is not looked up in PATH when a custom executor is used.
+func WithExecutor(executor Executor) Option {
+	return func(c *Chezmoi) {
+		c.executor = executor
+	}
+}
+
+// New creates a new Chezmoi wrapper. It detects the installed chezmoi
+// version and returns a *VersionError if it is older than MinimumVersion.
+func New(opts ...Option) (*Chezmoi, error) {
+	c := &Chezmoi{
+		timeout: DefaultTimeout,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	if c.binaryPath == "" {
+		c.binaryPath = "chezmoi"
+	}
+
+	if c.executor == nil {
+		binaryPath, err := exec.LookPath(c.binaryPath)
+		if err != nil {
+			if c.binaryPath == "chezmoi" {
+				return nil, fmt.Errorf("chezmoi binary not found in PATH: %w", err)
+			}
+			return nil, fmt.Errorf("chezmoi binary %s not found: %w", c.binaryPath, err)
+		}
+		c.binaryPath = binaryPath
+		c.executor = ProcessExecutor{}
+	}
+
+	version, err := c.Version(context.Background())
 	if err != nil {
-		return nil, fmt.Errorf("chezmoi binary not found in PATH: %w", err)
+		return nil, fmt.Errorf("failed to detect chezmoi version: %w", err)
+	}
+	if err := checkMinimumVersion(version); err != nil {
+		return nil, err
 	}
 
-	return &Chezmoi{
-		binaryPath: binaryPath,
-	}, nil
+	return c, nil
 }
 
-// Run executes a chezmoi command with the given arguments
-func (c *Chezmoi) Run(args ...string) (string, error) {
-	cmd := exec.Command(c.binaryPath, args...)
+// globalFlags returns the flags passed to chezmoi before every command
+func (c *Chezmoi) globalFlags() []string {
+	var flags []string
+	if c.sourceDir != "" {
+		flags = append(flags, "--source", c.sourceDir)
+	}
+	if c.destDir != "" {
+		flags = append(flags, "--destination", c.destDir)
+	}
+	if c.configFile != "" {
+		flags = append(flags, "--config", c.configFile)
+	}
+	if c.cacheDir != "" {
+		flags = append(flags, "--cache", c.cacheDir)
+	}
+	return append(flags, c.globalArgs...)
+}
+
+// Run executes a chezmoi command with the given arguments and returns its
+// standard output. The command is killed, together with any processes it
+// started, when ctx is cancelled or the configured timeout expires. Failures
+// are reported as *ExecError.
+func (c *Chezmoi) Run(ctx context.Context, args ...string) (string, error) {
+	var stdout bytes.Buffer
+	err := c.execute(ctx, IOStreams{Out: &stdout}, args)
+	if err != nil {
+		var execErr *ExecError
+		if errors.As(err, &execErr) {
+			execErr.Stdout = stdout.String()
+		}
+		return "", err
+	}
+
+	return stdout.String(), nil
+}
+
+// execute runs chezmoi with the global flags followed by args, connected to
+// the given streams
+func (c *Chezmoi) execute(ctx context.Context, streams IOStreams, args []string) error {
+	return c.invoke(ctx, streams, append(c.globalFlags(), args...), c.timeout)
+}
+
+// invoke runs chezmoi with exactly args, killing it after timeout unless
+// timeout is zero. Stderr is always captured so that failures can be
+// reported as *ExecError.
+func (c *Chezmoi) invoke(ctx context.Context, streams IOStreams, args []string, timeout time.Duration) error {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	var stderr bytes.Buffer
+	command := Command{
+		Path:    c.binaryPath,
+		Args:    args,
+		Streams: IOStreams{In: streams.In, Out: streams.Out, Err: &stderr},
+	}
+	if c.env != nil {
+		command.Env = c.env()
+	}
+	if streams.Err != nil {
+		command.Streams.Err = io.MultiWriter(&stderr, streams.Err)
+	}
+
+	executor := c.executor
+	if executor == nil {
+		executor = ProcessExecutor{}
+	}
 
-	output, err := cmd.CombinedOutput()
+	start := time.Now()
+	err := executor.Execute(ctx, command)
 	if err != nil {
-		return "", fmt.Errorf("chezmoi %v failed: %w (output: %s)", args, err, string(output))
+		execErr := &ExecError{
+			Args:     args,
+			ExitCode: -1,
+			Stderr:   stderr.String(),
+			Duration: time.Since(start),
+			Err:      err,
+		}
+
+		var exitErr exitCoder
+		if errors.As(err, &exitErr) {
+			execErr.ExitCode = exitErr.ExitCode()
+		}
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			execErr.Err = ctxErr
+		}
+		return execErr
 	}
 
-	return string(output), nil
+	return nil
 }
 
-// Status runs the chezmoi status command
-func (c *Chezmoi) Status() (string, error) {
-	return c.Run("status")
+// Stream executes a chezmoi command, copying its output to streams as it is
+// produced instead of buffering it. Cancellation and failures are handled as
+// in Run, except that ExecError.Stdout is left empty.
+func (c *Chezmoi) Stream(ctx context.Context, streams IOStreams, args ...string) error {
+	return c.execute(ctx, streams, args)
+}
+
+// Interactive executes a chezmoi command that interacts with the user, such
+// as one starting an editor. It behaves like Stream except that the
+// configured timeout does not apply.
+func (c *Chezmoi) Interactive(ctx context.Context, streams IOStreams, args ...string) error {
+	return c.invoke(ctx, streams, append(c.globalFlags(), args...), 0)
+}
+
+// Status runs the chezmoi status command for the given targets, or for all
+// targets if none are given
+func (c *Chezmoi) Status(ctx context.Context, targets ...string) (string, error) {
+	return c.Run(ctx, append([]string{"status"}, targets...)...)
 }
 
 // Apply runs the chezmoi apply command
-func (c *Chezmoi) Apply(targets ...string) (string, error) {
+func (c *Chezmoi) Apply(ctx context.Context, targets ...string) (string, error) {
 	args := []string{"apply"}
 	args = append(args, targets...)
-	return c.Run(args...)
+	return c.Run(ctx, args...)
 }
 
 // Add runs the chezmoi add command
-func (c *Chezmoi) Add(targets ...string) (string, error) {
+func (c *Chezmoi) Add(ctx context.Context, targets ...string) (string, error) {
 	args := []string{"add"}
 	args = append(args, targets...)
-	return c.Run(args...)
+	return c.Run(ctx, args...)
+}
+
+// AddOptions holds the flags of chezmoi add
+type AddOptions struct {
+	// Template adds the files as templates
+	Template bool
+	// Encrypt encrypts the files in the source state
+	Encrypt bool
+	// Exact makes added directories exact, so that chezmoi removes
+	// unmanaged entries from them
+	Exact bool
+	// Follow adds the targets of symlinks instead of the symlinks
+	Follow bool
+}
+
+// args converts the options to chezmoi command line arguments
+func (o AddOptions) args() []string {
+	var args []string
+	if o.Template {
+		args = append(args, "--template")
+	}
+	if o.Encrypt {
+		args = append(args, "--encrypt")
+	}
+	if o.Exact {
+		args = append(args, "--exact")
+	}
+	if o.Follow {
+		args = append(args, "--follow")
+	}
+	return args
+}
+
+// AddStream runs the chezmoi add command with opts, streaming its output
+func (c *Chezmoi) AddStream(ctx context.Context, streams IOStreams, opts AddOptions, targets ...string) error {
+	args := append([]string{"add"}, opts.args()...)
+	args = append(args, targets...)
+	return c.Stream(ctx, streams, args...)
 }
 
 // Diff runs the chezmoi diff command
-func (c *Chezmoi) Diff(targets ...string) (string, error) {
+func (c *Chezmoi) Diff(ctx context.Context, targets ...string) (string, error) {
 	args := []string{"diff"}
 	args = append(args, targets...)
-	return c.Run(args...)
+	return c.Run(ctx, args...)
+}
+
+// UnifiedDiff runs the chezmoi diff command for output that is parsed rather
+// than shown as is: chezmoi's builtin unified diff is used instead of the
+// configured diff.command, and neither the pager nor colours are applied.
+// The builtin diff cannot be forced on releases without --use-builtin-diff.
+func (c *Chezmoi) UnifiedDiff(ctx context.Context, targets ...string) (string, error) {
+	args := []string{"diff"}
+	if c.Supports(CapabilityBuiltinDiff) {
+		args = append(args, "--use-builtin-diff")
+	}
+	args = append(args, "--no-pager", "--color=false")
+	return c.Run(ctx, append(args, targets...)...)
+}
+
+// ApplyOptions holds the flags of chezmoi apply
+type ApplyOptions struct {
+	// Force overwrites destination files changed since chezmoi last wrote
+	// them without prompting
+	Force bool
+	// NoTTY makes chezmoi read prompts from stdin instead of the terminal,
+	// so that a prompt fails rather than waits when there is no stdin
+	NoTTY bool
+}
+
+// args converts the options to chezmoi command line arguments
+func (o ApplyOptions) args() []string {
+	var args []string
+	if o.Force {
+		args = append(args, "--force")
+	}
+	if o.NoTTY {
+		args = append(args, "--no-tty")
+	}
+	return args
+}
+
+// ApplyStream runs the chezmoi apply command with opts, streaming its output
+func (c *Chezmoi) ApplyStream(ctx context.Context, streams IOStreams, opts ApplyOptions, targets ...string) error {
+	args := append([]string{"apply"}, opts.args()...)
+	args = append(args, targets...)
+	return c.Stream(ctx, streams, args...)
+}
+
+// DiffStream runs the chezmoi diff command, streaming its output
+func (c *Chezmoi) DiffStream(ctx context.Context, streams IOStreams, targets ...string) error {
+	args := []string{"diff"}
+	args = append(args, targets...)
+	return c.Stream(ctx, streams, args...)
+}
+
+// ReAddStream runs the chezmoi re-add command, streaming its output
+func (c *Chezmoi) ReAddStream(ctx context.Context, streams IOStreams, targets ...string) error {
+	args := []string{"re-add"}
+	args = append(args, targets...)
+	return c.Stream(ctx, streams, args...)
+}
+
+// ForgetStream runs the chezmoi forget command, streaming its output. The
+// command is run with --force since it cannot prompt for confirmation.
+func (c *Chezmoi) ForgetStream(ctx context.Context, streams IOStreams, targets ...string) error {
+	args := []string{"forget", "--force"}
+	args = append(args, targets...)
+	return c.Stream(ctx, streams, args...)
+}
+
+// Edit runs the chezmoi edit command, which opens the source files of
+// targets in the user's editor. It must be connected to a terminal.
+func (c *Chezmoi) Edit(ctx context.Context, streams IOStreams, targets ...string) error {
+	args := []string{"edit"}
+	args = append(args, targets...)
+	return c.Interactive(ctx, streams, args...)
+}
+
+// SourcePath runs the chezmoi source-path command and returns the absolute
+// source path of each target, in order
+func (c *Chezmoi) SourcePath(ctx context.Context, targets ...string) ([]string, error) {
+	output, err := c.Run(ctx, append([]string{"source-path"}, targets...)...)
+	if err != nil {
+		return nil, err
+	}
+	return splitPaths(output, "\n"), nil
+}
+
+// LastCommit runs git log in the source directory with the chezmoi git
+// command and returns the last commit that touched path, or nil if it has
+// never been committed
+func (c *Chezmoi) LastCommit(ctx context.Context, path string) (*Commit, error) {
+	output, err := c.Run(ctx, "git", "--", "log", "-1", "--format="+commitFormat, "--", path)
+	if err != nil {
+		return nil, err
+	}
+	return parseCommit(output)
 }
 
 // Init runs the chezmoi init command
-func (c *Chezmoi) Init(args ...string) (string, error) {
+func (c *Chezmoi) Init(ctx context.Context, args ...string) (string, error) {
+	initWithArgs := []string{"init"}
+	initWithArgs = append(initWithArgs, args...)
+	return c.Run(ctx, initWithArgs...)
+}
+
+// InitStream runs the chezmoi init command, streaming its output
+func (c *Chezmoi) InitStream(ctx context.Context, streams IOStreams, args ...string) error {
 	initWithArgs := []string{"init"}
 	initWithArgs = append(initWithArgs, args...)
-	return c.Run(initWithArgs...)
+	return c.Stream(ctx, streams, initWithArgs...)
 }
 
 // GetBinaryPath returns the path to the chezmoi binary
@@ -73,74 +429,153 @@ func (c *Chezmoi) GetBinaryPath() string {
 	return c.binaryPath
 }
 
-// Managed runs the chezmoi managed command to list managed entries
-func (c *Chezmoi) Managed() (string, error) {
-	return c.Run("managed")
+// GetSourceDir returns the source directory passed to chezmoi, or an empty
+// string if chezmoi's default is used
+func (c *Chezmoi) GetSourceDir() string {
+	return c.sourceDir
 }
 
-// Unmanaged runs the chezmoi unmanaged command to list unmanaged files
-func (c *Chezmoi) Unmanaged() (string, error) {
-	return c.Run("unmanaged")
+// GetDestDir returns the destination directory passed to chezmoi, or an
+// empty string if chezmoi's default is used
+func (c *Chezmoi) GetDestDir() string {
+	return c.destDir
 }
 
-// Ignored runs the chezmoi ignored command to list ignored targets
-func (c *Chezmoi) Ignored() (string, error) {
-	return c.Run("ignored")
+// GetConfigFile returns the config file passed to chezmoi, or an empty
+// string if chezmoi's default is used
+func (c *Chezmoi) GetConfigFile() string {
+	return c.configFile
 }
 
-// Doctor runs the chezmoi doctor command to check for potential problems
-func (c *Chezmoi) Doctor() (string, error) {
-	return c.Run("doctor")
+// GetCacheDir returns the cache directory passed to chezmoi, or an empty
+// string if chezmoi's default is used
+func (c *Chezmoi) GetCacheDir() string {
+	return c.cacheDir
 }
 
-// Data runs the chezmoi data command to print template data
-func (c *Chezmoi) Data() (string, error) {
-	return c.Run("data")
+// GetTimeout returns the maximum duration of a single chezmoi command
+func (c *Chezmoi) GetTimeout() time.Duration {
+	return c.timeout
 }
 
-// ParseStatusOutput parses the output of the status command into structured data
-func (c *Chezmoi) ParseStatusOutput(output string) []map[string]string {
-	var result []map[string]string
+// Managed runs the chezmoi managed command to list managed entries. Paths
+// are requested NUL-separated, when chezmoi supports it, so that any file
+// name is returned intact.
+func (c *Chezmoi) Managed(ctx context.Context, opts ListOptions) ([]string, error) {
+	if len(opts.Exclude) > 0 {
+		if err := c.require(CapabilityManagedExclude); err != nil {
+			return nil, err
+		}
+	}
+	return c.listPaths(ctx, "managed", opts.args()...)
+}
 
-	lines := strings.Split(output, "\n")
-	for _, line := range lines {
-		if strings.TrimSpace(line) == "" {
-			continue
+// ManagedEntries runs the chezmoi managed command and returns every managed
+// entry with both its target and source paths
+func (c *Chezmoi) ManagedEntries(ctx context.Context, opts ListOptions) ([]ManagedEntry, error) {
+	if err := c.require(CapabilityJSONFormat); err != nil {
+		return nil, err
+	}
+	if len(opts.Exclude) > 0 {
+		if err := c.require(CapabilityManagedExclude); err != nil {
+			return nil, err
 		}
+	}
 
-		// Split by whitespace but preserve file paths with spaces
-		parts := strings.Fields(line)
-		if len(parts) >= 1 { // At least 2 parts: status and filename
-			// Extract the status column and filename
-			// In chezmoi status, there could be 1 column (status only) or 2+ columns
-			var destStatus, targetStatus, filename string
-
-			if len(parts) == 2 {
-				// Format: status filename
-				destStatus = " "
-				targetStatus = parts[0]
-				filename = parts[1]
-			} else if len(parts) >= 3 {
-				// Format: dest_status target_status filename(s)
-				destStatus = parts[0]
-				targetStatus = parts[1]
-				// Join remaining parts to reconstruct full filename (which might have spaces)
-				filename = strings.Join(parts[2:], " ")
-			} else {
-				// If only one part, it's likely just the filename with no status changes
-				destStatus = " "
-				targetStatus = " "
-				filename = parts[0]
-			}
+	opts.PathStyle = PathStyleAll
+	args := append([]string{"managed", "--format", "json"}, opts.args()...)
+	output, err := c.Run(ctx, args...)
+	if err != nil {
+		return nil, err
+	}
+	return parseManagedEntries(output)
+}
+
+// Unmanaged runs the chezmoi unmanaged command to list unmanaged files
+func (c *Chezmoi) Unmanaged(ctx context.Context, opts ListOptions) ([]string, error) {
+	var args []string
+	if opts.PathStyle != "" {
+		args = append(args, "--path-style", string(opts.PathStyle))
+	}
+	args = append(args, opts.Targets...)
+	return c.listPaths(ctx, "unmanaged", args...)
+}
+
+// Ignored runs the chezmoi ignored command to list ignored targets
+func (c *Chezmoi) Ignored(ctx context.Context) ([]string, error) {
+	return c.listPaths(ctx, "ignored")
+}
+
+// listPaths runs a chezmoi command that prints one path per entry. Older
+// releases without --nul-path-separator print newline-separated paths.
+func (c *Chezmoi) listPaths(ctx context.Context, command string, args ...string) ([]string, error) {
+	nul := c.Supports(CapabilityNULPathSeparator)
+	commandArgs := []string{command}
+	if nul {
+		commandArgs = append(commandArgs, "--nul-path-separator")
+	}
+
+	output, err := c.Run(ctx, append(commandArgs, args...)...)
+	if err != nil {
+		return nil, err
+	}
+	if nul {
+		return splitNULPaths(output), nil
+	}
+	return splitPaths(output, "\n"), nil
+}
 
-			entry := map[string]string{
-				"dest_status":   destStatus,
-				"target_status": targetStatus,
-				"filename":      filename,
+// Doctor runs the chezmoi doctor command to check for potential problems.
+// chezmoi exits with an error when a check fails, in which case the checks
+// are still returned together with the error.
+func (c *Chezmoi) Doctor(ctx context.Context) ([]DoctorCheck, error) {
+	output, err := c.Run(ctx, "doctor")
+	if err != nil {
+		var execErr *ExecError
+		if errors.As(err, &execErr) && execErr.Kind() != ErrorInterrupted {
+			if checks := parseDoctorOutput(execErr.Stdout); len(checks) > 0 {
+				return checks, err
 			}
-			result = append(result, entry)
 		}
+		return nil, err
+	}
+	return parseDoctorOutput(output), nil
+}
+
+// DestDir returns the destination directory chezmoi writes to: the one
+// passed to chezmoi, or else the destDir of chezmoi dump-config, which
+// honours the destDir set in chezmoi's config file
+func (c *Chezmoi) DestDir(ctx context.Context) (string, error) {
+	if c.destDir != "" {
+		return c.destDir, nil
+	}
+
+	output, err := c.Run(ctx, "dump-config", "--format", "json")
+	if err != nil {
+		return "", err
+	}
+	var config struct {
+		DestDir string `json:"destDir"`
+	}
+	if err := json.Unmarshal([]byte(output), &config); err != nil {
+		return "", fmt.Errorf("failed to decode chezmoi config: %w", err)
+	}
+	if config.DestDir == "" {
+		return "", errors.New("chezmoi config has no destDir")
+	}
+	return config.DestDir, nil
+}
+
+// Data runs the chezmoi data command to print template data
+func (c *Chezmoi) Data(ctx context.Context) (TemplateData, error) {
+	output, err := c.Run(ctx, "data", "--format", "json")
+	if err != nil {
+		return nil, err
 	}
 
-	return result
+	var data TemplateData
+	if err := json.Unmarshal([]byte(output), &data); err != nil {
+		return nil, fmt.Errorf("failed to decode chezmoi data: %w", err)
+	}
+	return data, nil
 }
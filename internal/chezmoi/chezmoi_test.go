@@ -1,7 +1,11 @@
 package chezmoi
 
 import (
+	"context"
+	"errors"
+	"runtime"
 	"testing"
+	"time"
 )
 
 func TestNewChezmoi(t *testing.T) {
@@ -35,18 +39,51 @@ A  .gitconfig
 	}
 
 	// First line: ' M .bashrc' -> ["M", ".bashrc"]
-	// This should result in dest_status=" ", target_status="M", filename=".bashrc"
-	if results[0]["filename"] != ".bashrc" {
-		t.Errorf("Expected .bashrc, got %s", results[0]["filename"])
+	// This should result in DestStatus=' ', TargetStatus='M', Path=".bashrc"
+	if results[0].Path != ".bashrc" {
+		t.Errorf("Expected .bashrc, got %s", results[0].Path)
 	}
 
-	// According to my logic: for " M .bashrc", it becomes [M, .bashrc]
-	// Since len=2, destStatus=" ", targetStatus="M", filename=".bashrc"
-	if results[0]["target_status"] != "M" {
-		t.Errorf("Expected M as target_status, got %s", results[0]["target_status"])
+	if results[0].TargetStatus != StatusModified {
+		t.Errorf("Expected M as TargetStatus, got %s", results[0].TargetStatus.Symbol())
 	}
 
-	if results[0]["dest_status"] != " " {
-		t.Errorf("Expected space as dest_status, got '%s'", results[0]["dest_status"])
+	if results[0].DestStatus != StatusUnchanged {
+		t.Errorf("Expected space as DestStatus, got '%s'", results[0].DestStatus.Symbol())
+	}
+}
+
+func TestRunCancellation(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Skipping test: requires a POSIX shell")
+	}
+
+	// Use the shell as a stand-in binary whose child outlives it unless the
+	// whole process group is killed
+	client := &Chezmoi{binaryPath: "/bin/sh"}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := client.Run(ctx, "-c", "sleep 30 & wait")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected deadline exceeded error, got: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Expected Run to return promptly after cancellation, took %v", elapsed)
+	}
+}
+
+func TestRunTimeout(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Skipping test: requires a POSIX shell")
+	}
+
+	client := &Chezmoi{binaryPath: "/bin/sh", timeout: 100 * time.Millisecond}
+
+	_, err := client.Run(context.Background(), "-c", "sleep 30")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected deadline exceeded error, got: %v", err)
 	}
 }
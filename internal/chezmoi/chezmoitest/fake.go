@@ -0,0 +1,229 @@
+// Package chezmoitest provides a scriptable in-memory chezmoi executor for
+// testing code built on the chezmoi wrapper without a chezmoi binary.
+package chezmoitest
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"chezmoi-tui/internal/chezmoi"
+)
+
+// ExitError is returned by the fake for responses with a non-zero exit code
+type ExitError struct {
+	Code int
+}
+
+// Error implements the error interface
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("exit status %d", e.Code)
+}
+
+// ExitCode returns the simulated exit code
+func (e *ExitError) ExitCode() int {
+	return e.Code
+}
+
+// Call records a single invocation of the fake
+type Call struct {
+	Args  []string
+	Stdin string
+	Env   []string
+}
+
+// Response is the canned result for invocations matching an argument prefix.
+// Its methods configure it and return it to allow chaining.
+type Response struct {
+	prefix   []string
+	stdout   string
+	stderr   string
+	exitCode int
+	err      error
+	delay    time.Duration
+	limit    int
+	used     int
+}
+
+// Stdout sets the output written to standard output
+func (r *Response) Stdout(stdout string) *Response {
+	r.stdout = stdout
+	return r
+}
+
+// Stderr sets the output written to standard error
+func (r *Response) Stderr(stderr string) *Response {
+	r.stderr = stderr
+	return r
+}
+
+// Fail makes the invocation exit with the given code and standard error
+func (r *Response) Fail(exitCode int, stderr string) *Response {
+	r.exitCode = exitCode
+	r.stderr = stderr
+	return r
+}
+
+// Error makes the invocation fail to start with err
+func (r *Response) Error(err error) *Response {
+	r.err = err
+	return r
+}
+
+// Delay makes the invocation take at least d, or until it is cancelled
+func (r *Response) Delay(d time.Duration) *Response {
+	r.delay = d
+	return r
+}
+
+// Times limits how many invocations the response answers, after which
+// earlier registered responses apply again
+func (r *Response) Times(n int) *Response {
+	r.limit = n
+	return r
+}
+
+// Once limits the response to a single invocation
+func (r *Response) Once() *Response {
+	return r.Times(1)
+}
+
+// Fake is a chezmoi.Executor that answers invocations from canned responses
+// and records every call. It is safe for concurrent use.
+type Fake struct {
+	mu        sync.Mutex
+	responses []*Response
+	calls     []Call
+}
+
+// DefaultVersion is the chezmoi --version output of a new fake
+const DefaultVersion = "chezmoi version v2.52.1, commit 0123456789abcdef, built at 2024-08-18T12:00:00Z, built by goreleaser\n"
+
+// NewFake returns a fake that only answers chezmoi --version, with
+// DefaultVersion. Other invocations without a matching response fail like an
+// unknown chezmoi command.
+func NewFake() *Fake {
+	f := &Fake{}
+	f.On("--version").Stdout(DefaultVersion)
+	return f
+}
+
+// On registers a response for invocations whose arguments start with prefix.
+// When several responses match, the most recently registered one wins.
+func (f *Fake) On(prefix ...string) *Response {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	r := &Response{prefix: prefix}
+	f.responses = append(f.responses, r)
+	return r
+}
+
+// Calls returns the recorded invocations in order
+func (f *Fake) Calls() []Call {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return append([]Call(nil), f.calls...)
+}
+
+// CallCount returns how many invocations started with prefix
+func (f *Fake) CallCount(prefix ...string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	count := 0
+	for _, call := range f.calls {
+		if hasPrefix(call.Args, prefix) {
+			count++
+		}
+	}
+	return count
+}
+
+// Execute implements chezmoi.Executor
+func (f *Fake) Execute(ctx context.Context, cmd chezmoi.Command) error {
+	call := Call{Args: append([]string(nil), cmd.Args...), Env: append([]string(nil), cmd.Env...)}
+	if cmd.Streams.In != nil {
+		stdin, _ := io.ReadAll(cmd.Streams.In)
+		call.Stdin = string(stdin)
+	}
+
+	f.mu.Lock()
+	f.calls = append(f.calls, call)
+	r := f.match(cmd.Args)
+	f.mu.Unlock()
+
+	if r == nil {
+		r = &Response{exitCode: 1, stderr: fmt.Sprintf("chezmoi: unknown command %q\n", strings.Join(cmd.Args, " "))}
+	}
+
+	if r.delay > 0 {
+		timer := time.NewTimer(r.delay)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	if r.err != nil {
+		return r.err
+	}
+	if cmd.Streams.Out != nil {
+		io.WriteString(cmd.Streams.Out, r.stdout)
+	}
+	if cmd.Streams.Err != nil {
+		io.WriteString(cmd.Streams.Err, r.stderr)
+	}
+	if r.exitCode != 0 {
+		return &ExitError{Code: r.exitCode}
+	}
+	return nil
+}
+
+// match finds the response for args and consumes one of its uses. It must
+// be called with f.mu held.
+func (f *Fake) match(args []string) *Response {
+	for i := len(f.responses) - 1; i >= 0; i-- {
+		r := f.responses[i]
+		if r.limit > 0 && r.used >= r.limit {
+			continue
+		}
+		if !hasPrefix(args, r.prefix) {
+			continue
+		}
+		r.used++
+		return r
+	}
+	return nil
+}
+
+// hasPrefix reports whether args starts with prefix
+func hasPrefix(args, prefix []string) bool {
+	if len(prefix) > len(args) {
+		return false
+	}
+	for i := range prefix {
+		if args[i] != prefix[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// New returns a chezmoi wrapper backed by fake
+func New(t testing.TB, fake *Fake, opts ...chezmoi.Option) *chezmoi.Chezmoi {
+	t.Helper()
+
+	client, err := chezmoi.New(append([]chezmoi.Option{chezmoi.WithExecutor(fake)}, opts...)...)
+	if err != nil {
+		t.Fatalf("Failed to create chezmoi client: %v", err)
+	}
+	return client
+}
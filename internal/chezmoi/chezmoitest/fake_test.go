@@ -0,0 +1,49 @@
+package chezmoitest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"chezmoi-tui/internal/chezmoi"
+)
+
+func TestFakeResponses(t *testing.T) {
+	fake := NewFake()
+	fake.On("status").Stdout("first\n")
+	fake.On("status").Stdout("override\n").Once()
+
+	run := func(args ...string) (string, error) {
+		var stdout bytes.Buffer
+		err := fake.Execute(context.Background(), chezmoi.Command{
+			Args:    args,
+			Streams: chezmoi.IOStreams{Out: &stdout, In: strings.NewReader("input")},
+		})
+		return stdout.String(), err
+	}
+
+	// The most recent response wins until it is used up
+	if out, _ := run("status"); out != "override\n" {
+		t.Errorf("Expected override response, got %q", out)
+	}
+	if out, _ := run("status", "--verbose"); out != "first\n" {
+		t.Errorf("Expected first response once override is used up, got %q", out)
+	}
+
+	// Unmatched invocations fail like an unknown command
+	_, err := run("bogus")
+	var exitErr *ExitError
+	if !errors.As(err, &exitErr) || exitErr.ExitCode() != 1 {
+		t.Errorf("Expected exit error for unmatched invocation, got: %v", err)
+	}
+
+	calls := fake.Calls()
+	if len(calls) != 3 || calls[0].Stdin != "input" {
+		t.Errorf("Expected calls with recorded stdin, got %+v", calls)
+	}
+	if fake.CallCount("status") != 2 {
+		t.Errorf("Expected 2 status calls, got %d", fake.CallCount("status"))
+	}
+}
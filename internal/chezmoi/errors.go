@@ -0,0 +1,183 @@
+package chezmoi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ErrorKind classifies why a chezmoi command failed
+type ErrorKind int
+
+const (
+	// ErrorUnknown is a failure that could not be classified
+	ErrorUnknown ErrorKind = iota
+	// ErrorInterrupted means the command was cancelled or timed out
+	ErrorInterrupted
+	// ErrorNotInitialized means the chezmoi source directory does not exist
+	ErrorNotInitialized
+	// ErrorTemplate means a source template failed to execute
+	ErrorTemplate
+	// ErrorPermissionDenied means chezmoi could not read or write a file
+	ErrorPermissionDenied
+	// ErrorVersionTooOld means the installed chezmoi does not understand the command
+	ErrorVersionTooOld
+)
+
+// Sentinel errors matched by ExecError through errors.Is
+var (
+	ErrNotInitialized   = errors.New("chezmoi is not initialized")
+	ErrTemplate         = errors.New("chezmoi template error")
+	ErrPermissionDenied = errors.New("chezmoi permission denied")
+	ErrVersionTooOld    = errors.New("chezmoi version too old")
+)
+
+// String returns a short human readable name for the kind
+func (k ErrorKind) String() string {
+	switch k {
+	case ErrorInterrupted:
+		return "interrupted"
+	case ErrorNotInitialized:
+		return "not initialized"
+	case ErrorTemplate:
+		return "template error"
+	case ErrorPermissionDenied:
+		return "permission denied"
+	case ErrorVersionTooOld:
+		return "chezmoi too old"
+	default:
+		return "unknown"
+	}
+}
+
+// ExecError describes a failed chezmoi invocation
+type ExecError struct {
+	// Args are the arguments chezmoi was invoked with
+	Args []string
+	// ExitCode is the process exit code, or -1 if it did not exit normally
+	ExitCode int
+	// Stdout and Stderr hold everything the process wrote before it stopped
+	Stdout string
+	Stderr string
+	// Duration is how long the process ran
+	Duration time.Duration
+	// Err is the underlying error from os/exec or the context
+	Err error
+}
+
+// Error implements the error interface
+func (e *ExecError) Error() string {
+	command := "chezmoi " + strings.Join(e.Args, " ")
+
+	if e.Kind() == ErrorInterrupted {
+		return fmt.Sprintf("%s interrupted after %s: %v", command, e.Duration.Round(time.Millisecond), e.Err)
+	}
+
+	if msg := firstLine(e.Stderr); msg != "" {
+		return fmt.Sprintf("%s failed (exit code %d): %s", command, e.ExitCode, msg)
+	}
+	return fmt.Sprintf("%s failed: %v", command, e.Err)
+}
+
+// Unwrap returns the underlying error
+func (e *ExecError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether the error matches one of the package sentinel errors
+func (e *ExecError) Is(target error) bool {
+	switch target {
+	case ErrNotInitialized:
+		return e.Kind() == ErrorNotInitialized
+	case ErrTemplate:
+		return e.Kind() == ErrorTemplate
+	case ErrPermissionDenied:
+		return e.Kind() == ErrorPermissionDenied
+	case ErrVersionTooOld:
+		return e.Kind() == ErrorVersionTooOld
+	}
+	return false
+}
+
+// Kind classifies the failure based on the context state and chezmoi's stderr
+func (e *ExecError) Kind() ErrorKind {
+	if errors.Is(e.Err, context.Canceled) || errors.Is(e.Err, context.DeadlineExceeded) {
+		return ErrorInterrupted
+	}
+
+	stderr := strings.ToLower(e.Stderr)
+	switch {
+	case strings.Contains(stderr, "unknown flag"),
+		strings.Contains(stderr, "unknown shorthand flag"),
+		strings.Contains(stderr, "unknown command"):
+		return ErrorVersionTooOld
+	case strings.Contains(stderr, "template:"),
+		strings.Contains(stderr, "map has no entry for key"):
+		return ErrorTemplate
+	case strings.Contains(stderr, "permission denied"),
+		strings.Contains(stderr, "operation not permitted"):
+		return ErrorPermissionDenied
+	case strings.Contains(stderr, "share/chezmoi: no such file or directory"),
+		strings.Contains(stderr, "source directory does not exist"),
+		strings.Contains(stderr, "not a git repository"):
+		return ErrorNotInitialized
+	}
+	return ErrorUnknown
+}
+
+// Remediation returns a suggestion for resolving the failure, or an empty
+// string when there is nothing specific to suggest
+func (e *ExecError) Remediation() string {
+	switch e.Kind() {
+	case ErrorInterrupted:
+		return "The command took too long or was cancelled. Increase integration.timeout in your config if it needs more time."
+	case ErrorNotInitialized:
+		return "Run 'chezmoi-tui init [repo]' to set up your source directory."
+	case ErrorTemplate:
+		return "Fix the template reported above; 'chezmoi execute-template' helps to debug it."
+	case ErrorPermissionDenied:
+		return "Check the ownership and permissions of the file reported above."
+	case ErrorVersionTooOld:
+		return "Upgrade chezmoi to the latest release, e.g. with 'chezmoi upgrade'."
+	}
+	return ""
+}
+
+// KindOf returns the kind of a chezmoi failure anywhere in err's chain
+func KindOf(err error) ErrorKind {
+	var versionErr *VersionError
+	if errors.As(err, &versionErr) {
+		return ErrorVersionTooOld
+	}
+	var execErr *ExecError
+	if errors.As(err, &execErr) {
+		return execErr.Kind()
+	}
+	return ErrorUnknown
+}
+
+// Remediation returns the remediation for a chezmoi failure anywhere in
+// err's chain
+func Remediation(err error) string {
+	var versionErr *VersionError
+	if errors.As(err, &versionErr) {
+		return versionErr.Remediation()
+	}
+	var execErr *ExecError
+	if errors.As(err, &execErr) {
+		return execErr.Remediation()
+	}
+	return ""
+}
+
+// firstLine returns the first non-empty line of s
+func firstLine(s string) string {
+	for _, line := range strings.Split(s, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			return line
+		}
+	}
+	return ""
+}
@@ -0,0 +1,104 @@
+package chezmoi
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"testing"
+)
+
+func TestExecErrorKind(t *testing.T) {
+	testCases := []struct {
+		name     string
+		stderr   string
+		err      error
+		expected ErrorKind
+		sentinel error
+	}{
+		{
+			name:     "Not initialized",
+			stderr:   "chezmoi: stat /home/user/.local/share/chezmoi: no such file or directory\n",
+			expected: ErrorNotInitialized,
+			sentinel: ErrNotInitialized,
+		},
+		{
+			name:     "Template error",
+			stderr:   "chezmoi: dot_gitconfig.tmpl: template: dot_gitconfig.tmpl:3:18: executing \"dot_gitconfig.tmpl\" at <.email>: map has no entry for key \"email\"\n",
+			expected: ErrorTemplate,
+			sentinel: ErrTemplate,
+		},
+		{
+			name:     "Permission denied",
+			stderr:   "chezmoi: open /etc/hosts: permission denied\n",
+			expected: ErrorPermissionDenied,
+			sentinel: ErrPermissionDenied,
+		},
+		{
+			name:     "Chezmoi too old",
+			stderr:   "chezmoi: unknown flag: --format\n",
+			expected: ErrorVersionTooOld,
+			sentinel: ErrVersionTooOld,
+		},
+		{
+			name:     "Interrupted",
+			stderr:   "",
+			err:      context.DeadlineExceeded,
+			expected: ErrorInterrupted,
+		},
+		{
+			name:     "Unknown",
+			stderr:   "chezmoi: something unexpected\n",
+			expected: ErrorUnknown,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.err
+			if err == nil {
+				err = errors.New("exit status 1")
+			}
+			execErr := &ExecError{Args: []string{"status"}, ExitCode: 1, Stderr: tc.stderr, Err: err}
+
+			if kind := execErr.Kind(); kind != tc.expected {
+				t.Errorf("Expected kind %v, got %v", tc.expected, kind)
+			}
+			if tc.sentinel != nil && !errors.Is(execErr, tc.sentinel) {
+				t.Errorf("Expected error to match %v", tc.sentinel)
+			}
+			if tc.expected != ErrorUnknown && execErr.Remediation() == "" {
+				t.Error("Expected a remediation hint, got empty string")
+			}
+		})
+	}
+}
+
+func TestRunExecError(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Skipping test: requires a POSIX shell")
+	}
+
+	client := &Chezmoi{binaryPath: "/bin/sh"}
+
+	_, err := client.Run(context.Background(), "-c", "echo partial; echo 'chezmoi: open x: permission denied' >&2; exit 3")
+
+	var execErr *ExecError
+	if !errors.As(err, &execErr) {
+		t.Fatalf("Expected *ExecError, got %T: %v", err, err)
+	}
+	if execErr.ExitCode != 3 {
+		t.Errorf("Expected exit code 3, got %d", execErr.ExitCode)
+	}
+	if execErr.Stdout != "partial\n" {
+		t.Errorf("Expected stdout to be captured separately, got %q", execErr.Stdout)
+	}
+	if execErr.Stderr != "chezmoi: open x: permission denied\n" {
+		t.Errorf("Expected stderr to be captured separately, got %q", execErr.Stderr)
+	}
+	if len(execErr.Args) != 2 || execErr.Args[0] != "-c" {
+		t.Errorf("Expected args to be recorded, got %v", execErr.Args)
+	}
+	if !errors.Is(err, ErrPermissionDenied) {
+		t.Error("Expected error to be classified as permission denied")
+	}
+}
@@ -0,0 +1,58 @@
+package chezmoi
+
+import (
+	"context"
+	"os"
+	"os/exec"
+)
+
+// Executor starts chezmoi processes. It separates the wrapper from the
+// operating system so that tests can substitute a fake.
+type Executor interface {
+	// Execute runs the command and returns once it has exited. A non-zero
+	// exit status must be reported as an error with an ExitCode() int method,
+	// as *exec.ExitError does.
+	Execute(ctx context.Context, cmd Command) error
+}
+
+// Command describes a single invocation of the chezmoi binary
+type Command struct {
+	// Path is the chezmoi binary to run
+	Path string
+	// Args are the arguments passed to chezmoi
+	Args []string
+	// Streams connects the process to the caller
+	Streams IOStreams
+	// Env holds variables, in the form key=value, added to the environment
+	// inherited from the current process
+	Env []string
+}
+
+// ProcessExecutor runs commands as operating system processes. It is the
+// default Executor.
+type ProcessExecutor struct{}
+
+// Execute implements Executor. The process, and everything it started, is
+// killed when ctx is done.
+func (ProcessExecutor) Execute(ctx context.Context, command Command) error {
+	cmd := exec.CommandContext(ctx, command.Path, command.Args...)
+	cmd.Stdin = command.Streams.In
+	cmd.Stdout = command.Streams.Out
+	cmd.Stderr = command.Streams.Err
+	if len(command.Env) > 0 {
+		cmd.Env = append(os.Environ(), command.Env...)
+	}
+	// An interactive command must stay in the terminal's foreground process
+	// group to be able to prompt; the terminal delivers ctrl+c to it anyway
+	if !command.Streams.interactive() {
+		setProcessGroup(cmd)
+	}
+	cmd.WaitDelay = waitDelay
+
+	return cmd.Run()
+}
+
+// exitCoder is implemented by errors that carry a process exit code
+type exitCoder interface {
+	ExitCode() int
+}
@@ -0,0 +1,181 @@
+package chezmoi
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// PathStyle selects how chezmoi prints paths
+type PathStyle string
+
+const (
+	PathStyleAbsolute       PathStyle = "absolute"
+	PathStyleRelative       PathStyle = "relative"
+	PathStyleSourceAbsolute PathStyle = "source-absolute"
+	PathStyleSourceRelative PathStyle = "source-relative"
+	PathStyleAll            PathStyle = "all"
+)
+
+// ListOptions filters the entries listed by commands such as managed
+type ListOptions struct {
+	// Include and Exclude select entry types, e.g. "files", "dirs",
+	// "symlinks", "scripts", "templates" or "encrypted"
+	Include []string
+	Exclude []string
+	// PathStyle selects how paths are printed; chezmoi's default is relative
+	PathStyle PathStyle
+	// Targets restricts the listing to the given targets
+	Targets []string
+}
+
+// args converts the options to chezmoi command line arguments
+func (o ListOptions) args() []string {
+	var args []string
+	if len(o.Include) > 0 {
+		args = append(args, "--include", strings.Join(o.Include, ","))
+	}
+	if len(o.Exclude) > 0 {
+		args = append(args, "--exclude", strings.Join(o.Exclude, ","))
+	}
+	if o.PathStyle != "" {
+		args = append(args, "--path-style", string(o.PathStyle))
+	}
+	return append(args, o.Targets...)
+}
+
+// ManagedEntry is a managed target together with its source paths
+type ManagedEntry struct {
+	// Path is the target path relative to the destination directory
+	Path string
+	// Absolute is the absolute target path
+	Absolute string `json:"absolute"`
+	// SourceAbsolute is the absolute path of the entry in the source state
+	SourceAbsolute string `json:"sourceAbsolute"`
+	// SourceRelative is the path of the entry relative to the source directory
+	SourceRelative string `json:"sourceRelative"`
+}
+
+// TemplateData is the data available to chezmoi templates
+type TemplateData map[string]any
+
+// Lookup returns the value at a dotted key such as "chezmoi.hostname"
+func (d TemplateData) Lookup(key string) (any, bool) {
+	var value any = map[string]any(d)
+	for _, part := range strings.Split(key, ".") {
+		m, ok := value.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		if value, ok = m[part]; !ok {
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+// DoctorResult is the outcome of a single chezmoi doctor check
+type DoctorResult string
+
+const (
+	DoctorOK      DoctorResult = "ok"
+	DoctorInfo    DoctorResult = "info"
+	DoctorWarning DoctorResult = "warning"
+	DoctorFailed  DoctorResult = "failed"
+	DoctorError   DoctorResult = "error"
+	DoctorSkipped DoctorResult = "skipped"
+)
+
+// DoctorCheck is one row of chezmoi doctor output
+type DoctorCheck struct {
+	Result  DoctorResult
+	Name    string
+	Message string
+}
+
+// Commit is a git commit of the source directory
+type Commit struct {
+	Hash    string
+	Author  string
+	Date    time.Time
+	Subject string
+}
+
+// commitFormat is the git log format parsed by parseCommit
+const commitFormat = "%H%x00%an%x00%aI%x00%s"
+
+// parseCommit parses a commit printed by git log with commitFormat. It
+// returns nil if the output is empty, which means no commit matched.
+func parseCommit(output string) (*Commit, error) {
+	output = strings.TrimRight(output, "\n")
+	if output == "" {
+		return nil, nil
+	}
+
+	fields := strings.SplitN(output, "\x00", 4)
+	if len(fields) != 4 {
+		return nil, fmt.Errorf("failed to parse commit %q", output)
+	}
+	date, err := time.Parse(time.RFC3339, fields[2])
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse commit date: %w", err)
+	}
+	return &Commit{Hash: fields[0], Author: fields[1], Date: date, Subject: fields[3]}, nil
+}
+
+// splitNULPaths splits NUL-separated command output into paths
+func splitNULPaths(output string) []string {
+	return splitPaths(output, "\x00")
+}
+
+// splitPaths splits output on sep, dropping empty entries
+func splitPaths(output, sep string) []string {
+	var paths []string
+	for _, path := range strings.Split(output, sep) {
+		if path != "" {
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
+
+// parseManagedEntries decodes the output of chezmoi managed --path-style all
+// --format json, which maps target paths to their other path styles
+func parseManagedEntries(output string) ([]ManagedEntry, error) {
+	var byPath map[string]ManagedEntry
+	if err := json.Unmarshal([]byte(output), &byPath); err != nil {
+		return nil, fmt.Errorf("failed to decode managed entries: %w", err)
+	}
+
+	entries := make([]ManagedEntry, 0, len(byPath))
+	for path, entry := range byPath {
+		entry.Path = path
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Path < entries[j].Path })
+	return entries, nil
+}
+
+// parseDoctorOutput parses chezmoi doctor's table of RESULT, CHECK and
+// MESSAGE columns. The message is the rest of the line and may be empty.
+func parseDoctorOutput(output string) []DoctorCheck {
+	var checks []DoctorCheck
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 || fields[0] == "RESULT" {
+			continue
+		}
+
+		message := strings.TrimSpace(line)
+		message = strings.TrimSpace(strings.TrimPrefix(message, fields[0]))
+		message = strings.TrimSpace(strings.TrimPrefix(message, fields[1]))
+		checks = append(checks, DoctorCheck{
+			Result:  DoctorResult(fields[0]),
+			Name:    fields[1],
+			Message: message,
+		})
+	}
+	return checks
+}
@@ -0,0 +1,134 @@
+package chezmoi
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestListOptionsArgs(t *testing.T) {
+	opts := ListOptions{
+		Include:   []string{"files", "templates"},
+		Exclude:   []string{"scripts"},
+		PathStyle: PathStyleAbsolute,
+		Targets:   []string{"~/.config"},
+	}
+
+	expected := []string{"--include", "files,templates", "--exclude", "scripts", "--path-style", "absolute", "~/.config"}
+	if args := opts.args(); !reflect.DeepEqual(args, expected) {
+		t.Errorf("Expected %v, got %v", expected, args)
+	}
+
+	if args := (ListOptions{}).args(); len(args) != 0 {
+		t.Errorf("Expected no arguments for empty options, got %v", args)
+	}
+}
+
+func TestSplitNULPaths(t *testing.T) {
+	output := ".bashrc\x00dir with spaces/file\x00line\nbreak\x00"
+
+	expected := []string{".bashrc", "dir with spaces/file", "line\nbreak"}
+	if paths := splitNULPaths(output); !reflect.DeepEqual(paths, expected) {
+		t.Errorf("Expected %q, got %q", expected, paths)
+	}
+
+	if paths := splitNULPaths(""); len(paths) != 0 {
+		t.Errorf("Expected no paths for empty output, got %q", paths)
+	}
+}
+
+func TestParseManagedEntries(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "managed.json"))
+	if err != nil {
+		t.Fatalf("Failed to read fixture: %v", err)
+	}
+
+	entries, err := parseManagedEntries(string(data))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("Expected 3 entries, got %d", len(entries))
+	}
+
+	expected := ManagedEntry{
+		Path:           ".config/git/config",
+		Absolute:       "/home/user/.config/git/config",
+		SourceAbsolute: "/home/user/.local/share/chezmoi/dot_config/git/private_config.tmpl",
+		SourceRelative: "dot_config/git/private_config.tmpl",
+	}
+	if entries[1] != expected {
+		t.Errorf("Expected %+v, got %+v", expected, entries[1])
+	}
+	if entries[2].Path != "line\nbreak" {
+		t.Errorf("Expected path with a newline to survive decoding, got %q", entries[2].Path)
+	}
+
+	if _, err := parseManagedEntries("not json"); err == nil {
+		t.Error("Expected an error for invalid JSON")
+	}
+}
+
+func TestParseDoctorOutput(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "doctor.txt"))
+	if err != nil {
+		t.Fatalf("Failed to read fixture: %v", err)
+	}
+
+	checks := parseDoctorOutput(string(data))
+	if len(checks) != 9 {
+		t.Fatalf("Expected 9 checks, got %d", len(checks))
+	}
+
+	expected := DoctorCheck{Result: DoctorWarning, Name: "config-file", Message: "~/.config/chezmoi/chezmoi.toml: not found"}
+	if checks[3] != expected {
+		t.Errorf("Expected %+v, got %+v", expected, checks[3])
+	}
+	if checks[6].Result != DoctorFailed || checks[6].Name != "edit-command" {
+		t.Errorf("Expected failed edit-command check, got %+v", checks[6])
+	}
+}
+
+func TestTemplateDataLookup(t *testing.T) {
+	data := TemplateData{
+		"chezmoi": map[string]any{
+			"hostname": "laptop",
+			"os":       "linux",
+		},
+		"email": "user@example.com",
+	}
+
+	if value, ok := data.Lookup("chezmoi.hostname"); !ok || value != "laptop" {
+		t.Errorf("Expected laptop, got %v (found: %v)", value, ok)
+	}
+	if value, ok := data.Lookup("email"); !ok || value != "user@example.com" {
+		t.Errorf("Expected user@example.com, got %v (found: %v)", value, ok)
+	}
+	if _, ok := data.Lookup("email.domain"); ok {
+		t.Error("Expected lookup through a non-map value to fail")
+	}
+	if _, ok := data.Lookup("missing"); ok {
+		t.Error("Expected lookup of a missing key to fail")
+	}
+}
+
+func TestParseCommit(t *testing.T) {
+	commit, err := parseCommit("0123abcd\x00Jane Doe\x002024-03-01T12:30:00+01:00\x00Add zsh aliases: ll, la\n")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if commit.Hash != "0123abcd" || commit.Author != "Jane Doe" || commit.Subject != "Add zsh aliases: ll, la" {
+		t.Errorf("Unexpected commit %+v", commit)
+	}
+	if commit.Date.Year() != 2024 || commit.Date.Hour() != 12 {
+		t.Errorf("Unexpected date %v", commit.Date)
+	}
+
+	if commit, err := parseCommit(""); commit != nil || err != nil {
+		t.Errorf("Expected no commit for empty output, got %+v, %v", commit, err)
+	}
+	if _, err := parseCommit("not a commit"); err == nil {
+		t.Error("Expected an error for malformed output")
+	}
+}
@@ -0,0 +1,9 @@
+//go:build windows
+
+package chezmoi
+
+import "os/exec"
+
+// setProcessGroup is a no-op on Windows, where exec.CommandContext already
+// kills the process on cancellation
+func setProcessGroup(cmd *exec.Cmd) {}
@@ -0,0 +1,300 @@
+package chezmoi
+
+import (
+	"io/fs"
+	"strings"
+)
+
+// SourceType is the type of target that a source state entry produces
+type SourceType int
+
+const (
+	// SourceFile is a regular file
+	SourceFile SourceType = iota
+	// SourceDir is a directory
+	SourceDir
+	// SourceCreate is a file that is only created if it does not exist
+	SourceCreate
+	// SourceModify is a script that modifies an existing file
+	SourceModify
+	// SourceRemove is a target that is removed
+	SourceRemove
+	// SourceScript is a run_ script
+	SourceScript
+	// SourceSymlink is a symbolic link
+	SourceSymlink
+)
+
+// String returns a human readable name for the source type
+func (t SourceType) String() string {
+	switch t {
+	case SourceDir:
+		return "directory"
+	case SourceCreate:
+		return "create file"
+	case SourceModify:
+		return "modify script"
+	case SourceRemove:
+		return "remove"
+	case SourceScript:
+		return "script"
+	case SourceSymlink:
+		return "symlink"
+	default:
+		return "file"
+	}
+}
+
+// Source state name prefixes and suffixes, see
+// https://www.chezmoi.io/reference/source-state-attributes/
+const (
+	prefixAfter      = "after_"
+	prefixBefore     = "before_"
+	prefixCreate     = "create_"
+	prefixDot        = "dot_"
+	prefixEmpty      = "empty_"
+	prefixEncrypted  = "encrypted_"
+	prefixExact      = "exact_"
+	prefixExecutable = "executable_"
+	prefixExternal   = "external_"
+	prefixLiteral    = "literal_"
+	prefixModify     = "modify_"
+	prefixOnce       = "once_"
+	prefixOnChange   = "onchange_"
+	prefixPrivate    = "private_"
+	prefixReadonly   = "readonly_"
+	prefixRemove     = "remove_"
+	prefixRun        = "run_"
+	prefixSymlink    = "symlink_"
+	suffixLiteral    = ".literal"
+	suffixTemplate   = ".tmpl"
+)
+
+// encryptedSuffixes are the suffixes that age and gpg add to encrypted
+// source files
+var encryptedSuffixes = []string{".age", ".asc"}
+
+// SourceAttributes are the attributes chezmoi decodes from the name of a
+// source state entry
+type SourceAttributes struct {
+	Type SourceType
+	// TargetName is the name of the target, e.g. .bashrc for dot_bashrc.tmpl
+	TargetName string
+
+	After      bool
+	Before     bool
+	Dot        bool
+	Empty      bool
+	Encrypted  bool
+	Exact      bool
+	Executable bool
+	External   bool
+	Once       bool
+	OnChange   bool
+	Private    bool
+	Readonly   bool
+	Template   bool
+}
+
+// ParseSourceName decodes the attributes of the base name of a source state
+// entry. dir selects the attributes of directories, which differ from those
+// of files.
+func ParseSourceName(name string, dir bool) SourceAttributes {
+	if dir {
+		return parseDirName(name)
+	}
+	return parseFileName(name)
+}
+
+// parseDirName decodes the attributes of a source directory name
+func parseDirName(name string) SourceAttributes {
+	a := SourceAttributes{Type: SourceDir}
+	var remove bool
+	if name, remove = strings.CutPrefix(name, prefixRemove); remove {
+		a.Type = SourceRemove
+	}
+	name, a.External = strings.CutPrefix(name, prefixExternal)
+	name, a.Exact = strings.CutPrefix(name, prefixExact)
+	name, a.Private = strings.CutPrefix(name, prefixPrivate)
+	name, a.Readonly = strings.CutPrefix(name, prefixReadonly)
+	a.TargetName, a.Dot = cutDotPrefix(name)
+	return a
+}
+
+// parseFileName decodes the attributes of a source file name. Prefixes are
+// only recognized in the order chezmoi requires them.
+func parseFileName(name string) SourceAttributes {
+	var a SourceAttributes
+	var ok bool
+	switch {
+	case strings.HasPrefix(name, prefixCreate):
+		a.Type = SourceCreate
+		name = name[len(prefixCreate):]
+		name, a.Encrypted = strings.CutPrefix(name, prefixEncrypted)
+		name, a.Private = strings.CutPrefix(name, prefixPrivate)
+		name, a.Readonly = strings.CutPrefix(name, prefixReadonly)
+		name, a.Empty = strings.CutPrefix(name, prefixEmpty)
+		name, a.Executable = strings.CutPrefix(name, prefixExecutable)
+	case strings.HasPrefix(name, prefixRemove):
+		a.Type = SourceRemove
+		name = name[len(prefixRemove):]
+	case strings.HasPrefix(name, prefixRun):
+		a.Type = SourceScript
+		name = name[len(prefixRun):]
+		if name, a.Once = strings.CutPrefix(name, prefixOnce); !a.Once {
+			name, a.OnChange = strings.CutPrefix(name, prefixOnChange)
+		}
+		if name, a.Before = strings.CutPrefix(name, prefixBefore); !a.Before {
+			name, a.After = strings.CutPrefix(name, prefixAfter)
+		}
+	case strings.HasPrefix(name, prefixSymlink):
+		a.Type = SourceSymlink
+		name = name[len(prefixSymlink):]
+	case strings.HasPrefix(name, prefixModify):
+		a.Type = SourceModify
+		name = name[len(prefixModify):]
+		name, a.Encrypted = strings.CutPrefix(name, prefixEncrypted)
+		name, a.Private = strings.CutPrefix(name, prefixPrivate)
+		name, a.Readonly = strings.CutPrefix(name, prefixReadonly)
+		name, a.Executable = strings.CutPrefix(name, prefixExecutable)
+	default:
+		name, a.Encrypted = strings.CutPrefix(name, prefixEncrypted)
+		name, a.Private = strings.CutPrefix(name, prefixPrivate)
+		name, a.Readonly = strings.CutPrefix(name, prefixReadonly)
+		name, a.Empty = strings.CutPrefix(name, prefixEmpty)
+		name, a.Executable = strings.CutPrefix(name, prefixExecutable)
+	}
+	name, a.Dot = cutDotPrefix(name)
+
+	if a.Encrypted {
+		for _, suffix := range encryptedSuffixes {
+			if name, ok = strings.CutSuffix(name, suffix); ok {
+				break
+			}
+		}
+	}
+	if name, ok = strings.CutSuffix(name, suffixLiteral); !ok && a.Type != SourceRemove {
+		name, a.Template = strings.CutSuffix(name, suffixTemplate)
+	}
+	a.TargetName = name
+	return a
+}
+
+// cutDotPrefix replaces a dot_ prefix with a dot, or removes a literal_
+// prefix that stops the name from being decoded further
+func cutDotPrefix(name string) (string, bool) {
+	if rest, ok := strings.CutPrefix(name, prefixDot); ok {
+		return "." + rest, true
+	}
+	return strings.TrimPrefix(name, prefixLiteral), false
+}
+
+// ParseSourcePath decodes a source path relative to the source directory and
+// returns the target path relative to the destination directory together
+// with the attributes of its last element. dir reports whether the last
+// element is a directory. The names below an external_ directory are taken
+// literally.
+func ParseSourcePath(path string, dir bool) (string, SourceAttributes) {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	external := false
+	for i, part := range parts[:len(parts)-1] {
+		if external {
+			continue
+		}
+		attrs := parseDirName(part)
+		parts[i] = attrs.TargetName
+		external = attrs.External
+	}
+
+	last := len(parts) - 1
+	if external {
+		return strings.Join(parts, "/"), SourceAttributes{Type: typeOf(dir), TargetName: parts[last]}
+	}
+	attrs := ParseSourceName(parts[last], dir)
+	parts[last] = attrs.TargetName
+	return strings.Join(parts, "/"), attrs
+}
+
+// typeOf returns the source type of a plain file or directory
+func typeOf(dir bool) SourceType {
+	if dir {
+		return SourceDir
+	}
+	return SourceFile
+}
+
+// Names returns the names of the attributes that are set, in the order of
+// their prefixes in a source name, with "template" for the .tmpl suffix
+func (a SourceAttributes) Names() []string {
+	var names []string
+	switch a.Type {
+	case SourceCreate:
+		names = append(names, "create")
+	case SourceModify:
+		names = append(names, "modify")
+	case SourceRemove:
+		names = append(names, "remove")
+	case SourceScript:
+		names = append(names, "run")
+	case SourceSymlink:
+		names = append(names, "symlink")
+	}
+	for _, attr := range []struct {
+		set  bool
+		name string
+	}{
+		{a.Once, "once"},
+		{a.OnChange, "onchange"},
+		{a.Before, "before"},
+		{a.After, "after"},
+		{a.External, "external"},
+		{a.Exact, "exact"},
+		{a.Encrypted, "encrypted"},
+		{a.Private, "private"},
+		{a.Readonly, "readonly"},
+		{a.Empty, "empty"},
+		{a.Executable, "executable"},
+		{a.Dot, "dot"},
+		{a.Template, "template"},
+	} {
+		if attr.set {
+			names = append(names, attr.name)
+		}
+	}
+	return names
+}
+
+// Has reports whether the attribute with the given name, as returned by
+// Names, is set
+func (a SourceAttributes) Has(name string) bool {
+	for _, n := range a.Names() {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Perm returns the permissions that chezmoi gives the target before the
+// umask is applied. Removed targets, scripts and symlinks have none.
+func (a SourceAttributes) Perm() fs.FileMode {
+	var perm fs.FileMode
+	switch a.Type {
+	case SourceDir:
+		perm = 0o777
+	case SourceFile, SourceCreate, SourceModify:
+		perm = 0o666
+		if a.Executable {
+			perm |= 0o111
+		}
+	default:
+		return 0
+	}
+	if a.Private {
+		perm &^= 0o077
+	}
+	if a.Readonly {
+		perm &^= 0o222
+	}
+	return perm
+}
@@ -0,0 +1,74 @@
+package chezmoi
+
+import (
+	"io/fs"
+	"reflect"
+	"testing"
+)
+
+func TestParseSourceName(t *testing.T) {
+	testCases := []struct {
+		name   string
+		dir    bool
+		target string
+		typ    SourceType
+		attrs  []string
+		perm   fs.FileMode
+	}{
+		{name: "dot_bashrc", target: ".bashrc", attrs: []string{"dot"}, perm: 0o666},
+		{name: "private_executable_dot_local.tmpl", target: ".local", attrs: []string{"private", "executable", "dot", "template"}, perm: 0o700},
+		{name: "encrypted_private_dot_netrc.tmpl.age", target: ".netrc", attrs: []string{"encrypted", "private", "dot", "template"}, perm: 0o600},
+		{name: "readonly_config", target: "config", attrs: []string{"readonly"}, perm: 0o444},
+		{name: "create_empty_dot_hushlogin", target: ".hushlogin", typ: SourceCreate, attrs: []string{"create", "empty", "dot"}, perm: 0o666},
+		{name: "modify_dot_gitconfig", target: ".gitconfig", typ: SourceModify, attrs: []string{"modify", "dot"}, perm: 0o666},
+		{name: "run_once_before_install.sh.tmpl", target: "install.sh", typ: SourceScript, attrs: []string{"run", "once", "before", "template"}},
+		{name: "run_onchange_after_reload.sh", target: "reload.sh", typ: SourceScript, attrs: []string{"run", "onchange", "after"}},
+		{name: "symlink_dot_vimrc.tmpl", target: ".vimrc", typ: SourceSymlink, attrs: []string{"symlink", "dot", "template"}},
+		{name: "remove_dot_old", target: ".old", typ: SourceRemove, attrs: []string{"remove", "dot"}},
+		{name: "literal_dot_x.tmpl.literal", target: "dot_x.tmpl", perm: 0o666},
+		// Prefixes out of order are part of the name
+		{name: "dot_private_x", target: ".private_x", attrs: []string{"dot"}, perm: 0o666},
+		{name: "exact_private_dot_ssh", dir: true, target: ".ssh", typ: SourceDir, attrs: []string{"exact", "private", "dot"}, perm: 0o700},
+		{name: "external_dot_oh-my-zsh", dir: true, target: ".oh-my-zsh", typ: SourceDir, attrs: []string{"external", "dot"}, perm: 0o777},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			attrs := ParseSourceName(tc.name, tc.dir)
+			if attrs.TargetName != tc.target {
+				t.Errorf("Expected target %q, got %q", tc.target, attrs.TargetName)
+			}
+			if attrs.Type != tc.typ {
+				t.Errorf("Expected type %v, got %v", tc.typ, attrs.Type)
+			}
+			if names := attrs.Names(); !reflect.DeepEqual(names, tc.attrs) {
+				t.Errorf("Expected attributes %v, got %v", tc.attrs, names)
+			}
+			if perm := attrs.Perm(); perm != tc.perm {
+				t.Errorf("Expected permissions %v, got %v", tc.perm, perm)
+			}
+		})
+	}
+}
+
+func TestParseSourcePath(t *testing.T) {
+	testCases := []struct {
+		path   string
+		dir    bool
+		target string
+	}{
+		{path: "dot_config/private_git/config.tmpl", target: ".config/git/config"},
+		{path: "private_dot_ssh", dir: true, target: ".ssh"},
+		{path: "external_dot_vim/dot_pack/private_x", target: ".vim/dot_pack/private_x"},
+	}
+
+	for _, tc := range testCases {
+		if target, _ := ParseSourcePath(tc.path, tc.dir); target != tc.target {
+			t.Errorf("%s: expected %q, got %q", tc.path, tc.target, target)
+		}
+	}
+
+	if _, attrs := ParseSourcePath("dot_config/private_git/config.tmpl", false); !attrs.Template || attrs.Private {
+		t.Errorf("Expected the attributes of the file only, got %+v", attrs)
+	}
+}
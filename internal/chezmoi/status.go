@@ -0,0 +1,210 @@
+package chezmoi
+
+import "strings"
+
+// StatusCode is a single column of chezmoi status output
+type StatusCode byte
+
+const (
+	// StatusUnchanged means there is no difference
+	StatusUnchanged StatusCode = ' '
+	// StatusAdded means the entry will be created
+	StatusAdded StatusCode = 'A'
+	// StatusDeleted means the entry will be removed
+	StatusDeleted StatusCode = 'D'
+	// StatusModified means the entry will be changed
+	StatusModified StatusCode = 'M'
+	// StatusRunScript means the entry is a script that will be run
+	StatusRunScript StatusCode = 'R'
+)
+
+// String returns a human readable name for the status code
+func (s StatusCode) String() string {
+	switch s {
+	case StatusUnchanged:
+		return "unchanged"
+	case StatusAdded:
+		return "added"
+	case StatusDeleted:
+		return "deleted"
+	case StatusModified:
+		return "modified"
+	case StatusRunScript:
+		return "run script"
+	default:
+		return "unknown"
+	}
+}
+
+// valid reports whether s is a status code chezmoi emits
+func (s StatusCode) valid() bool {
+	switch s {
+	case StatusUnchanged, StatusAdded, StatusDeleted, StatusModified, StatusRunScript:
+		return true
+	}
+	return false
+}
+
+// Symbol returns the single character chezmoi uses for the status code
+func (s StatusCode) Symbol() string {
+	return string(rune(s))
+}
+
+// EntryKind is the type of a managed entry
+type EntryKind int
+
+const (
+	// EntryUnknown is an entry whose type chezmoi did not report
+	EntryUnknown EntryKind = iota
+	// EntryFile is a regular file
+	EntryFile
+	// EntryDir is a directory
+	EntryDir
+	// EntrySymlink is a symbolic link
+	EntrySymlink
+	// EntryScript is a run_ script
+	EntryScript
+)
+
+// String returns a human readable name for the entry kind
+func (k EntryKind) String() string {
+	switch k {
+	case EntryFile:
+		return "file"
+	case EntryDir:
+		return "dir"
+	case EntrySymlink:
+		return "symlink"
+	case EntryScript:
+		return "script"
+	default:
+		return "unknown"
+	}
+}
+
+// StatusEntry is one line of chezmoi status output
+type StatusEntry struct {
+	// DestStatus is the first column: the difference between the state
+	// chezmoi last wrote and the actual state of the destination
+	DestStatus StatusCode
+	// TargetStatus is the second column: the difference between the actual
+	// state and the target state, i.e. what chezmoi apply will do
+	TargetStatus StatusCode
+	// Path is the target path relative to the destination directory
+	Path string
+	// Kind is the type of the entry, if known
+	Kind EntryKind
+}
+
+// Code returns the most significant status of the entry, preferring what
+// chezmoi apply will do over changes made since chezmoi last wrote it
+func (e StatusEntry) Code() StatusCode {
+	if e.TargetStatus != StatusUnchanged {
+		return e.TargetStatus
+	}
+	return e.DestStatus
+}
+
+// IsModified reports whether either column is modified
+func (e StatusEntry) IsModified() bool {
+	return e.DestStatus == StatusModified || e.TargetStatus == StatusModified
+}
+
+// IsAdded reports whether either column is added
+func (e StatusEntry) IsAdded() bool {
+	return e.DestStatus == StatusAdded || e.TargetStatus == StatusAdded
+}
+
+// IsDeleted reports whether either column is deleted
+func (e StatusEntry) IsDeleted() bool {
+	return e.DestStatus == StatusDeleted || e.TargetStatus == StatusDeleted
+}
+
+// IsScript reports whether the entry is a script that will be run
+func (e StatusEntry) IsScript() bool {
+	return e.Kind == EntryScript || e.DestStatus == StatusRunScript || e.TargetStatus == StatusRunScript
+}
+
+// IsUnchanged reports whether neither column shows a difference
+func (e StatusEntry) IsUnchanged() bool {
+	return e.DestStatus == StatusUnchanged && e.TargetStatus == StatusUnchanged
+}
+
+// HasLocalChanges reports whether the destination was changed since chezmoi
+// last wrote it, so applying would overwrite those changes
+func (e StatusEntry) HasLocalChanges() bool {
+	return e.DestStatus != StatusUnchanged
+}
+
+// NeedsApply reports whether chezmoi apply would change the entry
+func (e StatusEntry) NeedsApply() bool {
+	return e.TargetStatus != StatusUnchanged
+}
+
+// StatusCounts summarizes a set of status entries
+type StatusCounts struct {
+	Modified  int
+	Added     int
+	Deleted   int
+	Scripts   int
+	Unchanged int
+}
+
+// CountStatus counts entries by their most significant status
+func CountStatus(entries []StatusEntry) StatusCounts {
+	var counts StatusCounts
+	for _, entry := range entries {
+		switch {
+		case entry.IsScript():
+			counts.Scripts++
+		case entry.IsModified():
+			counts.Modified++
+		case entry.IsAdded():
+			counts.Added++
+		case entry.IsDeleted():
+			counts.Deleted++
+		default:
+			counts.Unchanged++
+		}
+	}
+	return counts
+}
+
+// ParseStatusOutput parses the output of the status command into structured
+// data. Each line consists of two fixed status columns, a space and the
+// target path, which is taken verbatim so that paths with leading, trailing
+// or repeated spaces survive. Lines that do not match this layout are skipped.
+func (c *Chezmoi) ParseStatusOutput(output string) []StatusEntry {
+	var result []StatusEntry
+
+	for _, line := range strings.Split(output, "\n") {
+		entry, ok := parseStatusLine(strings.TrimSuffix(line, "\r"))
+		if ok {
+			result = append(result, entry)
+		}
+	}
+
+	return result
+}
+
+// parseStatusLine parses a single line of chezmoi status output
+func parseStatusLine(line string) (StatusEntry, bool) {
+	if len(line) < 4 || line[2] != ' ' {
+		return StatusEntry{}, false
+	}
+
+	destStatus, targetStatus := StatusCode(line[0]), StatusCode(line[1])
+	if !destStatus.valid() || !targetStatus.valid() {
+		return StatusEntry{}, false
+	}
+
+	entry := StatusEntry{
+		DestStatus:   destStatus,
+		TargetStatus: targetStatus,
+		Path:         line[3:],
+	}
+	if destStatus == StatusRunScript || targetStatus == StatusRunScript {
+		entry.Kind = EntryScript
+	}
+	return entry, true
+}
@@ -0,0 +1,230 @@
+package chezmoi
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestStatusEntryPredicates(t *testing.T) {
+	testCases := []struct {
+		name      string
+		entry     StatusEntry
+		modified  bool
+		added     bool
+		deleted   bool
+		script    bool
+		unchanged bool
+		code      StatusCode
+	}{
+		{
+			name:     "Modified target",
+			entry:    StatusEntry{DestStatus: StatusUnchanged, TargetStatus: StatusModified},
+			modified: true,
+			code:     StatusModified,
+		},
+		{
+			name:     "Locally modified",
+			entry:    StatusEntry{DestStatus: StatusModified, TargetStatus: StatusUnchanged},
+			modified: true,
+			code:     StatusModified,
+		},
+		{
+			name:  "Added",
+			entry: StatusEntry{DestStatus: StatusUnchanged, TargetStatus: StatusAdded},
+			added: true,
+			code:  StatusAdded,
+		},
+		{
+			name:    "Deleted locally, will be re-added",
+			entry:   StatusEntry{DestStatus: StatusDeleted, TargetStatus: StatusAdded},
+			added:   true,
+			deleted: true,
+			code:    StatusAdded,
+		},
+		{
+			name:   "Script",
+			entry:  StatusEntry{DestStatus: StatusUnchanged, TargetStatus: StatusRunScript, Kind: EntryScript},
+			script: true,
+			code:   StatusRunScript,
+		},
+		{
+			name:      "Unchanged",
+			entry:     StatusEntry{DestStatus: StatusUnchanged, TargetStatus: StatusUnchanged},
+			unchanged: true,
+			code:      StatusUnchanged,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.entry.IsModified(); got != tc.modified {
+				t.Errorf("IsModified: expected %v, got %v", tc.modified, got)
+			}
+			if got := tc.entry.IsAdded(); got != tc.added {
+				t.Errorf("IsAdded: expected %v, got %v", tc.added, got)
+			}
+			if got := tc.entry.IsDeleted(); got != tc.deleted {
+				t.Errorf("IsDeleted: expected %v, got %v", tc.deleted, got)
+			}
+			if got := tc.entry.IsScript(); got != tc.script {
+				t.Errorf("IsScript: expected %v, got %v", tc.script, got)
+			}
+			if got := tc.entry.IsUnchanged(); got != tc.unchanged {
+				t.Errorf("IsUnchanged: expected %v, got %v", tc.unchanged, got)
+			}
+			if got := tc.entry.Code(); got != tc.code {
+				t.Errorf("Code: expected %v, got %v", tc.code, got)
+			}
+		})
+	}
+}
+
+func TestCountStatus(t *testing.T) {
+	entries := []StatusEntry{
+		{DestStatus: StatusUnchanged, TargetStatus: StatusModified},
+		{DestStatus: StatusModified, TargetStatus: StatusModified},
+		{DestStatus: StatusUnchanged, TargetStatus: StatusAdded},
+		{DestStatus: StatusUnchanged, TargetStatus: StatusDeleted},
+		{DestStatus: StatusUnchanged, TargetStatus: StatusRunScript, Kind: EntryScript},
+		{DestStatus: StatusUnchanged, TargetStatus: StatusUnchanged},
+	}
+
+	counts := CountStatus(entries)
+	expected := StatusCounts{Modified: 2, Added: 1, Deleted: 1, Scripts: 1, Unchanged: 1}
+	if counts != expected {
+		t.Errorf("Expected %+v, got %+v", expected, counts)
+	}
+}
+
+func TestParseStatusOutputFixtures(t *testing.T) {
+	client := &Chezmoi{}
+
+	testCases := []struct {
+		fixture  string
+		expected []StatusEntry
+	}{
+		{
+			fixture: "basic.txt",
+			expected: []StatusEntry{
+				{DestStatus: StatusUnchanged, TargetStatus: StatusModified, Path: ".bashrc"},
+				{DestStatus: StatusUnchanged, TargetStatus: StatusAdded, Path: ".config/nvim/init.lua"},
+				{DestStatus: StatusUnchanged, TargetStatus: StatusDeleted, Path: ".oldrc"},
+				{DestStatus: StatusModified, TargetStatus: StatusModified, Path: ".gitconfig"},
+				{DestStatus: StatusDeleted, TargetStatus: StatusAdded, Path: ".zshrc"},
+			},
+		},
+		{
+			fixture: "scripts.txt",
+			expected: []StatusEntry{
+				{DestStatus: StatusUnchanged, TargetStatus: StatusRunScript, Path: "install-packages.sh", Kind: EntryScript},
+				{DestStatus: StatusUnchanged, TargetStatus: StatusRunScript, Path: ".chezmoiscripts/configure-macos.sh", Kind: EntryScript},
+				{DestStatus: StatusModified, TargetStatus: StatusUnchanged, Path: ".ssh/config"},
+			},
+		},
+		{
+			fixture: "spaces.txt",
+			expected: []StatusEntry{
+				{DestStatus: StatusUnchanged, TargetStatus: StatusModified, Path: "Library/Application Support/Code/User/settings.json"},
+				{DestStatus: StatusUnchanged, TargetStatus: StatusAdded, Path: "notes/two  spaces.md"},
+				{DestStatus: StatusUnchanged, TargetStatus: StatusModified, Path: " leading space.txt"},
+				{DestStatus: StatusUnchanged, TargetStatus: StatusModified, Path: "trailing space.txt "},
+			},
+		},
+		{
+			fixture: "crlf.txt",
+			expected: []StatusEntry{
+				{DestStatus: StatusUnchanged, TargetStatus: StatusModified, Path: ".bashrc"},
+				{DestStatus: StatusUnchanged, TargetStatus: StatusAdded, Path: ".vimrc"},
+			},
+		},
+		{
+			fixture:  "empty.txt",
+			expected: nil,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.fixture, func(t *testing.T) {
+			data, err := os.ReadFile(filepath.Join("testdata", "status", tc.fixture))
+			if err != nil {
+				t.Fatalf("Failed to read fixture: %v", err)
+			}
+
+			results := client.ParseStatusOutput(string(data))
+			if len(results) != len(tc.expected) {
+				t.Fatalf("Expected %d entries, got %d: %+v", len(tc.expected), len(results), results)
+			}
+			for i, expected := range tc.expected {
+				if results[i] != expected {
+					t.Errorf("Entry %d: expected %+v, got %+v", i, expected, results[i])
+				}
+			}
+		})
+	}
+}
+
+func TestParseStatusOutputColumnsAreDistinct(t *testing.T) {
+	client := &Chezmoi{}
+
+	results := client.ParseStatusOutput(" M .bashrc\nM  .bashrc\n")
+	if len(results) != 2 {
+		t.Fatalf("Expected 2 entries, got %d", len(results))
+	}
+	if results[0].DestStatus != StatusUnchanged || results[0].TargetStatus != StatusModified {
+		t.Errorf("Expected ' M' to be modified in the target column, got %+v", results[0])
+	}
+	if results[1].DestStatus != StatusModified || results[1].TargetStatus != StatusUnchanged {
+		t.Errorf("Expected 'M ' to be modified in the destination column, got %+v", results[1])
+	}
+}
+
+func TestParseStatusOutputSkipsMalformedLines(t *testing.T) {
+	client := &Chezmoi{}
+
+	input := "warning: config file template has changed\nXY path\n M\n M .bashrc\n"
+	results := client.ParseStatusOutput(input)
+	if len(results) != 1 || results[0].Path != ".bashrc" {
+		t.Errorf("Expected only .bashrc to be parsed, got %+v", results)
+	}
+}
+
+func FuzzParseStatusOutput(f *testing.F) {
+	fixtures, _ := filepath.Glob(filepath.Join("testdata", "status", "*.txt"))
+	for _, fixture := range fixtures {
+		data, err := os.ReadFile(fixture)
+		if err != nil {
+			f.Fatalf("Failed to read fixture: %v", err)
+		}
+		f.Add(string(data))
+	}
+
+	client := &Chezmoi{}
+	f.Fuzz(func(t *testing.T, output string) {
+		results := client.ParseStatusOutput(output)
+
+		var formatted strings.Builder
+		for _, entry := range results {
+			if !entry.DestStatus.valid() || !entry.TargetStatus.valid() {
+				t.Fatalf("Invalid status codes in %+v", entry)
+			}
+			if entry.Path == "" || strings.ContainsAny(entry.Path, "\n") {
+				t.Fatalf("Invalid path in %+v", entry)
+			}
+			formatted.WriteString(entry.DestStatus.Symbol() + entry.TargetStatus.Symbol() + " " + entry.Path + "\r\n")
+		}
+
+		// Formatting the entries back into chezmoi's layout must round-trip. CRLF
+		// line endings keep paths that end in a carriage return intact.
+		reparsed := client.ParseStatusOutput(formatted.String())
+		if len(reparsed) != len(results) {
+			t.Fatalf("Round trip changed the number of entries: %d != %d", len(reparsed), len(results))
+		}
+		for i := range results {
+			if reparsed[i] != results[i] {
+				t.Fatalf("Round trip changed entry %d: %+v != %+v", i, reparsed[i], results[i])
+			}
+		}
+	})
+}
@@ -0,0 +1,81 @@
+package chezmoi
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/mattn/go-isatty"
+)
+
+// IOStreams connects a streaming chezmoi command to its caller. Nil fields
+// are connected to the null device.
+type IOStreams struct {
+	In  io.Reader
+	Out io.Writer
+	Err io.Writer
+}
+
+// interactive reports whether the command's input is a terminal, in which
+// case chezmoi may prompt the user
+func (s IOStreams) interactive() bool {
+	f, ok := s.In.(*os.File)
+	return ok && (isatty.IsTerminal(f.Fd()) || isatty.IsCygwinTerminal(f.Fd()))
+}
+
+// OutputLine is a single line of output from a streaming chezmoi command
+type OutputLine struct {
+	// Text is the line without its trailing newline
+	Text string
+	// Stderr is true if the line was written to standard error
+	Stderr bool
+}
+
+// LineWriter is an io.Writer that splits its input into lines and delivers
+// each complete line on a channel. Call Flush after the command exits to
+// deliver a final line that has no trailing newline.
+type LineWriter struct {
+	mu     sync.Mutex
+	lines  chan<- OutputLine
+	stderr bool
+	buf    []byte
+}
+
+// NewLineWriter returns a LineWriter sending to lines. stderr marks every
+// line it produces as standard error output.
+func NewLineWriter(lines chan<- OutputLine, stderr bool) *LineWriter {
+	return &LineWriter{lines: lines, stderr: stderr}
+}
+
+// Write implements io.Writer
+func (w *LineWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		w.send(w.buf[:i])
+		w.buf = w.buf[i+1:]
+	}
+	return len(p), nil
+}
+
+// Flush delivers any buffered partial line
+func (w *LineWriter) Flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if len(w.buf) > 0 {
+		w.send(w.buf)
+		w.buf = nil
+	}
+}
+
+func (w *LineWriter) send(line []byte) {
+	w.lines <- OutputLine{Text: string(bytes.TrimSuffix(line, []byte("\r"))), Stderr: w.stderr}
+}
@@ -0,0 +1,69 @@
+package chezmoi
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"runtime"
+	"testing"
+)
+
+func TestLineWriter(t *testing.T) {
+	lines := make(chan OutputLine, 10)
+	w := NewLineWriter(lines, true)
+
+	// Lines split across writes must be reassembled
+	w.Write([]byte("first li"))
+	w.Write([]byte("ne\r\nsecond line\nthi"))
+	w.Write([]byte("rd"))
+	w.Flush()
+	close(lines)
+
+	var got []OutputLine
+	for line := range lines {
+		got = append(got, line)
+	}
+
+	expected := []string{"first line", "second line", "third"}
+	if len(got) != len(expected) {
+		t.Fatalf("Expected %d lines, got %d: %v", len(expected), len(got), got)
+	}
+	for i, line := range got {
+		if line.Text != expected[i] {
+			t.Errorf("Expected line %d to be %q, got %q", i, expected[i], line.Text)
+		}
+		if !line.Stderr {
+			t.Errorf("Expected line %d to be marked as stderr", i)
+		}
+	}
+}
+
+func TestStream(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Skipping test: requires a POSIX shell")
+	}
+
+	client := &Chezmoi{binaryPath: "/bin/sh"}
+
+	var stdout, stderr bytes.Buffer
+	err := client.Stream(context.Background(), IOStreams{Out: &stdout, Err: &stderr},
+		"-c", "echo out; echo err >&2; exit 2")
+
+	if stdout.String() != "out\n" {
+		t.Errorf("Expected stdout to be streamed, got %q", stdout.String())
+	}
+	if stderr.String() != "err\n" {
+		t.Errorf("Expected stderr to be streamed, got %q", stderr.String())
+	}
+
+	var execErr *ExecError
+	if !errors.As(err, &execErr) {
+		t.Fatalf("Expected *ExecError, got %T: %v", err, err)
+	}
+	if execErr.ExitCode != 2 {
+		t.Errorf("Expected exit code 2, got %d", execErr.ExitCode)
+	}
+	if execErr.Stderr != "err\n" {
+		t.Errorf("Expected stderr to be captured for the error, got %q", execErr.Stderr)
+	}
+}
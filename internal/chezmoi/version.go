@@ -0,0 +1,201 @@
+package chezmoi
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+// Version is a chezmoi release version
+type Version struct {
+	Major int
+	Minor int
+	Patch int
+	// Dev is set for development builds, which report no release number and
+	// are assumed to support every capability
+	Dev bool
+}
+
+// MinimumVersion is the oldest chezmoi release the wrapper supports (v2.9.0)
+var MinimumVersion = Version{Major: 2, Minor: 9}
+
+// Capability is an optional chezmoi feature that the wrapper relies on
+type Capability string
+
+const (
+	// CapabilityManagedExclude is the --exclude flag of chezmoi managed
+	CapabilityManagedExclude Capability = "managed --exclude"
+	// CapabilityNULPathSeparator is the --nul-path-separator flag of the
+	// managed, unmanaged and ignored commands
+	CapabilityNULPathSeparator Capability = "--nul-path-separator"
+	// CapabilityJSONFormat is --format json with --path-style all for chezmoi managed
+	CapabilityJSONFormat Capability = "managed --format json"
+	// CapabilityBuiltinDiff is the --use-builtin-diff flag of chezmoi diff
+	CapabilityBuiltinDiff Capability = "diff --use-builtin-diff"
+)
+
+// capabilities maps each capability to the first release that has it, see
+// https://github.com/twpayne/chezmoi/releases/tag/<version>
+var capabilities = map[Capability]Version{
+	CapabilityBuiltinDiff:      {Major: 2, Minor: 10}, // v2.10.0
+	CapabilityManagedExclude:   {Major: 2, Minor: 13}, // v2.13.0
+	CapabilityNULPathSeparator: {Major: 2, Minor: 41}, // v2.41.0
+	CapabilityJSONFormat:       {Major: 2, Minor: 47}, // v2.47.0
+}
+
+// versionPattern matches the version in the first line of chezmoi --version,
+// e.g. "chezmoi version v2.52.1, commit 1a2b3c, built at ..."
+var versionPattern = regexp.MustCompile(`^chezmoi version (?:v?(\d+)\.(\d+)\.(\d+)\S*|(dev))(?:,|\s|$)`)
+
+// ParseVersion parses the output of chezmoi --version
+func ParseVersion(output string) (Version, error) {
+	line := firstLine(output)
+	match := versionPattern.FindStringSubmatch(line)
+	if match == nil {
+		return Version{}, fmt.Errorf("unrecognized chezmoi version output %q", line)
+	}
+	if match[4] != "" {
+		return Version{Dev: true}, nil
+	}
+
+	var v Version
+	for i, field := range []*int{&v.Major, &v.Minor, &v.Patch} {
+		n, err := strconv.Atoi(match[i+1])
+		if err != nil {
+			return Version{}, fmt.Errorf("invalid chezmoi version %q: %w", line, err)
+		}
+		*field = n
+	}
+	return v, nil
+}
+
+// String returns the version without the leading v, or "dev" for
+// development builds
+func (v Version) String() string {
+	if v.Dev {
+		return "dev"
+	}
+	return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+}
+
+// IsZero reports whether the version is unknown
+func (v Version) IsZero() bool {
+	return v == Version{}
+}
+
+// Compare returns -1, 0 or +1 depending on whether v is older than, the same
+// as or newer than other. Development builds are newer than any release.
+func (v Version) Compare(other Version) int {
+	switch {
+	case v.Dev && other.Dev:
+		return 0
+	case v.Dev:
+		return 1
+	case other.Dev:
+		return -1
+	}
+
+	for _, d := range []int{v.Major - other.Major, v.Minor - other.Minor, v.Patch - other.Patch} {
+		if d < 0 {
+			return -1
+		}
+		if d > 0 {
+			return 1
+		}
+	}
+	return 0
+}
+
+// AtLeast reports whether v is the same as or newer than other
+func (v Version) AtLeast(other Version) bool {
+	return v.Compare(other) >= 0
+}
+
+// Supports reports whether chezmoi v has capability c
+func (v Version) Supports(c Capability) bool {
+	required, ok := capabilities[c]
+	return !ok || v.AtLeast(required)
+}
+
+// VersionError reports that the installed chezmoi is too old for the
+// wrapper as a whole or for a single capability
+type VersionError struct {
+	// Version is the installed chezmoi version
+	Version Version
+	// Required is the oldest version that would work
+	Required Version
+	// Capability is the missing capability, or empty if chezmoi is older than
+	// MinimumVersion
+	Capability Capability
+}
+
+// Error implements the error interface
+func (e *VersionError) Error() string {
+	if e.Capability != "" {
+		return fmt.Sprintf("chezmoi %s does not support %s, which requires chezmoi %s or later", e.Version, e.Capability, e.Required)
+	}
+	return fmt.Sprintf("chezmoi %s is too old, chezmoi-tui requires chezmoi %s or later", e.Version, e.Required)
+}
+
+// Is matches ErrVersionTooOld
+func (e *VersionError) Is(target error) bool {
+	return target == ErrVersionTooOld
+}
+
+// Remediation returns a suggestion for resolving the failure
+func (e *VersionError) Remediation() string {
+	return "Upgrade chezmoi to the latest release, e.g. with 'chezmoi upgrade'."
+}
+
+// Version runs chezmoi --version and returns the installed version. Global
+// flags are not passed so that a broken config file does not prevent
+// detection.
+func (c *Chezmoi) Version(ctx context.Context) (Version, error) {
+	var stdout bytes.Buffer
+	if err := c.invoke(ctx, IOStreams{Out: &stdout}, []string{"--version"}, c.timeout); err != nil {
+		var execErr *ExecError
+		if errors.As(err, &execErr) {
+			execErr.Stdout = stdout.String()
+		}
+		return Version{}, err
+	}
+
+	version, err := ParseVersion(stdout.String())
+	if err != nil {
+		return Version{}, err
+	}
+	c.version = version
+	return version, nil
+}
+
+// DetectedVersion returns the chezmoi version detected by New, or the zero
+// Version if it has not been detected
+func (c *Chezmoi) DetectedVersion() Version {
+	return c.version
+}
+
+// Supports reports whether the installed chezmoi has capability. A wrapper
+// whose version has not been detected assumes it does.
+func (c *Chezmoi) Supports(capability Capability) bool {
+	return c.version.IsZero() || c.version.Supports(capability)
+}
+
+// require returns a *VersionError if the installed chezmoi lacks capability
+func (c *Chezmoi) require(capability Capability) error {
+	if c.Supports(capability) {
+		return nil
+	}
+	return &VersionError{Version: c.version, Required: capabilities[capability], Capability: capability}
+}
+
+// checkMinimumVersion returns a *VersionError if v is older than
+// MinimumVersion
+func checkMinimumVersion(v Version) error {
+	if v.AtLeast(MinimumVersion) {
+		return nil
+	}
+	return &VersionError{Version: v, Required: MinimumVersion}
+}
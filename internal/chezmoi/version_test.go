@@ -0,0 +1,81 @@
+package chezmoi
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseVersion(t *testing.T) {
+	tests := []struct {
+		name     string
+		output   string
+		expected Version
+	}{
+		{"Release", "chezmoi version v2.52.1, commit 0123abc, built at 2024-08-18T12:00:00Z, built by goreleaser\n", Version{Major: 2, Minor: 52, Patch: 1}},
+		{"WithoutPrefix", "chezmoi version 2.40.0, built by Debian\n", Version{Major: 2, Minor: 40}},
+		{"PreRelease", "chezmoi version v2.53.0-rc.1, commit 0123abc\n", Version{Major: 2, Minor: 53}},
+		{"Dev", "chezmoi version dev, commit 0123abc, built at 2024-08-18T12:00:00Z\n", Version{Dev: true}},
+		{"BareLine", "chezmoi version v1.8.11", Version{Major: 1, Minor: 8, Patch: 11}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			version, err := ParseVersion(tt.output)
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+			if version != tt.expected {
+				t.Errorf("Expected %+v, got %+v", tt.expected, version)
+			}
+		})
+	}
+
+	for _, output := range []string{"", "chezmoi 2.52.1", "chezmoi version unknown"} {
+		if _, err := ParseVersion(output); err == nil {
+			t.Errorf("Expected an error for %q", output)
+		}
+	}
+}
+
+func TestVersionCompare(t *testing.T) {
+	older := Version{Major: 2, Minor: 9, Patch: 3}
+	newer := Version{Major: 2, Minor: 10}
+	dev := Version{Dev: true}
+
+	if older.Compare(newer) != -1 || newer.Compare(older) != 1 || older.Compare(older) != 0 {
+		t.Error("Expected versions to be ordered numerically, not lexically")
+	}
+	if !dev.AtLeast(newer) || newer.AtLeast(dev) {
+		t.Error("Expected development builds to be newer than any release")
+	}
+	if older.String() != "2.9.3" || dev.String() != "dev" {
+		t.Errorf("Unexpected version strings %q and %q", older, dev)
+	}
+}
+
+func TestVersionSupports(t *testing.T) {
+	v := Version{Major: 2, Minor: 41}
+
+	if !v.Supports(CapabilityNULPathSeparator) {
+		t.Error("Expected 2.41.0 to support --nul-path-separator")
+	}
+	if v.Supports(CapabilityJSONFormat) {
+		t.Error("Expected 2.41.0 not to support managed --format json")
+	}
+	if !(Version{Dev: true}).Supports(CapabilityJSONFormat) {
+		t.Error("Expected development builds to support every capability")
+	}
+}
+
+func TestVersionError(t *testing.T) {
+	err := checkMinimumVersion(Version{Major: 1, Minor: 8, Patch: 11})
+	if !errors.Is(err, ErrVersionTooOld) {
+		t.Fatalf("Expected ErrVersionTooOld, got: %v", err)
+	}
+	if KindOf(err) != ErrorVersionTooOld || Remediation(err) == "" {
+		t.Errorf("Expected kind and remediation for %v", err)
+	}
+	if checkMinimumVersion(MinimumVersion) != nil {
+		t.Error("Expected the minimum version itself to be accepted")
+	}
+}
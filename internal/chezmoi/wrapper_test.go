@@ -0,0 +1,286 @@
+package chezmoi_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"chezmoi-tui/internal/chezmoi"
+	"chezmoi-tui/internal/chezmoi/chezmoitest"
+)
+
+func TestWrapperWithFakeExecutor(t *testing.T) {
+	t.Run("Status", func(t *testing.T) {
+		fake := chezmoitest.NewFake()
+		fake.On("status").Stdout(" M .bashrc\n")
+		client := chezmoitest.New(t, fake)
+
+		output, err := client.Status(context.Background())
+		if err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		if output != " M .bashrc\n" {
+			t.Errorf("Expected canned status output, got %q", output)
+		}
+		if fake.CallCount("status") != 1 {
+			t.Errorf("Expected one status call, got %v", fake.Calls())
+		}
+	})
+
+	t.Run("ManagedUsesNULSeparator", func(t *testing.T) {
+		fake := chezmoitest.NewFake()
+		fake.On("managed").Stdout(".bashrc\x00.config/nvim/init.lua\x00")
+		client := chezmoitest.New(t, fake)
+
+		files, err := client.Managed(context.Background(), chezmoi.ListOptions{Include: []string{"files"}})
+		if err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		if !reflect.DeepEqual(files, []string{".bashrc", ".config/nvim/init.lua"}) {
+			t.Errorf("Unexpected managed files: %q", files)
+		}
+
+		expected := []string{"managed", "--nul-path-separator", "--include", "files"}
+		if calls := fake.Calls(); !reflect.DeepEqual(calls[len(calls)-1].Args, expected) {
+			t.Errorf("Expected args %v, got %v", expected, calls[len(calls)-1].Args)
+		}
+	})
+
+	t.Run("DataDecodesJSON", func(t *testing.T) {
+		fake := chezmoitest.NewFake()
+		fake.On("data", "--format", "json").Stdout(`{"chezmoi":{"os":"linux"}}`)
+		client := chezmoitest.New(t, fake)
+
+		data, err := client.Data(context.Background())
+		if err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		if os, _ := data.Lookup("chezmoi.os"); os != "linux" {
+			t.Errorf("Expected linux, got %v", os)
+		}
+	})
+
+	t.Run("DoctorReturnsChecksOnFailure", func(t *testing.T) {
+		fake := chezmoitest.NewFake()
+		fake.On("doctor").Stdout("RESULT CHECK MESSAGE\nfailed edit-command vi not found\n").Fail(1, "")
+		client := chezmoitest.New(t, fake)
+
+		checks, err := client.Doctor(context.Background())
+		if err == nil {
+			t.Error("Expected the failed check to be reported as an error")
+		}
+		if len(checks) != 1 || checks[0].Result != chezmoi.DoctorFailed {
+			t.Errorf("Expected the failed check to be returned, got %+v", checks)
+		}
+	})
+
+	t.Run("AddStreamOptions", func(t *testing.T) {
+		fake := chezmoitest.NewFake()
+		fake.On("add")
+		client := chezmoitest.New(t, fake)
+
+		opts := chezmoi.AddOptions{Template: true, Exact: true}
+		if err := client.AddStream(context.Background(), chezmoi.IOStreams{}, opts, "/home/user/.config/nvim"); err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+
+		expected := []string{"add", "--template", "--exact", "/home/user/.config/nvim"}
+		if calls := fake.Calls(); !reflect.DeepEqual(calls[len(calls)-1].Args, expected) {
+			t.Errorf("Expected args %v, got %v", expected, calls[len(calls)-1].Args)
+		}
+	})
+
+	t.Run("ApplyStreamOptions", func(t *testing.T) {
+		fake := chezmoitest.NewFake()
+		fake.On("apply")
+		client := chezmoitest.New(t, fake)
+
+		opts := chezmoi.ApplyOptions{Force: true, NoTTY: true}
+		if err := client.ApplyStream(context.Background(), chezmoi.IOStreams{}, opts, "/home/user/.bashrc"); err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+
+		expected := []string{"apply", "--force", "--no-tty", "/home/user/.bashrc"}
+		if calls := fake.Calls(); !reflect.DeepEqual(calls[len(calls)-1].Args, expected) {
+			t.Errorf("Expected args %v, got %v", expected, calls[len(calls)-1].Args)
+		}
+	})
+
+	t.Run("FailureBecomesExecError", func(t *testing.T) {
+		fake := chezmoitest.NewFake()
+		fake.On("apply").Fail(1, "chezmoi: dot_bashrc.tmpl: template: dot_bashrc.tmpl:1: function \"foo\" not defined\n")
+		client := chezmoitest.New(t, fake)
+
+		_, err := client.Apply(context.Background())
+		if !errors.Is(err, chezmoi.ErrTemplate) {
+			t.Errorf("Expected template error, got: %v", err)
+		}
+
+		var execErr *chezmoi.ExecError
+		if !errors.As(err, &execErr) || execErr.ExitCode != 1 {
+			t.Errorf("Expected ExecError with exit code 1, got: %v", err)
+		}
+	})
+
+	t.Run("DelayHonoursTimeout", func(t *testing.T) {
+		fake := chezmoitest.NewFake()
+		fake.On("status").Delay(time.Minute)
+		client := chezmoitest.New(t, fake, chezmoi.WithTimeout(20*time.Millisecond))
+
+		_, err := client.Status(context.Background())
+		if chezmoi.KindOf(err) != chezmoi.ErrorInterrupted {
+			t.Errorf("Expected interrupted error, got: %v", err)
+		}
+	})
+
+	t.Run("GlobalFlags", func(t *testing.T) {
+		fake := chezmoitest.NewFake()
+		fake.On("--source")
+		client := chezmoitest.New(t, fake,
+			chezmoi.WithSourceDir("/work/dotfiles"),
+			chezmoi.WithDestDir("/tmp/scratch"),
+			chezmoi.WithConfigFile("/work/chezmoi.toml"),
+			chezmoi.WithCacheDir("/tmp/cache"),
+			chezmoi.WithGlobalArgs("--verbose"),
+		)
+
+		if _, err := client.Status(context.Background()); err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+
+		expected := []string{
+			"--source", "/work/dotfiles",
+			"--destination", "/tmp/scratch",
+			"--config", "/work/chezmoi.toml",
+			"--cache", "/tmp/cache",
+			"--verbose",
+			"status",
+		}
+		if calls := fake.Calls(); !reflect.DeepEqual(calls[len(calls)-1].Args, expected) {
+			t.Errorf("Expected args %v, got %v", expected, calls[len(calls)-1].Args)
+		}
+		if client.GetSourceDir() != "/work/dotfiles" {
+			t.Errorf("Expected source dir to be recorded, got %q", client.GetSourceDir())
+		}
+	})
+
+	t.Run("EnvIsEvaluatedPerCommand", func(t *testing.T) {
+		fake := chezmoitest.NewFake()
+		fake.On("status")
+		var session string
+		client := chezmoitest.New(t, fake, chezmoi.WithEnv(func() []string {
+			if session == "" {
+				return nil
+			}
+			return []string{"BW_SESSION=" + session}
+		}))
+
+		session = "key"
+		if _, err := client.Status(context.Background()); err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		if calls := fake.Calls(); !reflect.DeepEqual(calls[len(calls)-1].Env, []string{"BW_SESSION=key"}) {
+			t.Errorf("Expected the session to be passed, got %v", calls[len(calls)-1].Env)
+		}
+	})
+}
+
+func TestNewWithMissingBinary(t *testing.T) {
+	_, err := chezmoi.New(chezmoi.WithBinaryPath("/nonexistent/chezmoi"))
+	if err == nil {
+		t.Fatal("Expected an error for a missing binary")
+	}
+	if !strings.Contains(err.Error(), "/nonexistent/chezmoi") {
+		t.Errorf("Expected the error to name the binary, got: %v", err)
+	}
+}
+
+func TestVersionNegotiation(t *testing.T) {
+	t.Run("DetectsVersion", func(t *testing.T) {
+		fake := chezmoitest.NewFake()
+		client := chezmoitest.New(t, fake)
+
+		expected := chezmoi.Version{Major: 2, Minor: 52, Patch: 1}
+		if client.DetectedVersion() != expected {
+			t.Errorf("Expected %v, got %v", expected, client.DetectedVersion())
+		}
+		if fake.CallCount("--version") != 1 {
+			t.Errorf("Expected one version call, got %+v", fake.Calls())
+		}
+	})
+
+	t.Run("RejectsOldVersion", func(t *testing.T) {
+		fake := chezmoitest.NewFake()
+		fake.On("--version").Stdout("chezmoi version v1.8.11, commit 0123abc\n")
+
+		_, err := chezmoi.New(chezmoi.WithExecutor(fake))
+		var versionErr *chezmoi.VersionError
+		if !errors.As(err, &versionErr) {
+			t.Fatalf("Expected a version error, got: %v", err)
+		}
+		if versionErr.Required != chezmoi.MinimumVersion {
+			t.Errorf("Expected the minimum version to be reported, got %v", versionErr.Required)
+		}
+	})
+
+	t.Run("FallsBackToNewlines", func(t *testing.T) {
+		fake := chezmoitest.NewFake()
+		fake.On("--version").Stdout("chezmoi version v2.30.0\n")
+		fake.On("managed").Stdout(".bashrc\n.vimrc\n")
+		client := chezmoitest.New(t, fake)
+
+		files, err := client.Managed(context.Background(), chezmoi.ListOptions{})
+		if err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		if !reflect.DeepEqual(files, []string{".bashrc", ".vimrc"}) {
+			t.Errorf("Unexpected managed files: %q", files)
+		}
+		if fake.CallCount("managed", "--nul-path-separator") != 0 {
+			t.Error("Expected --nul-path-separator not to be passed to chezmoi 2.30.0")
+		}
+	})
+
+	t.Run("UnifiedDiff", func(t *testing.T) {
+		fake := chezmoitest.NewFake()
+		fake.On("diff").Stdout("--- a/.bashrc\n+++ b/.bashrc\n")
+		client := chezmoitest.New(t, fake)
+
+		if _, err := client.UnifiedDiff(context.Background(), "/home/user/.bashrc"); err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		expected := []string{"diff", "--use-builtin-diff", "--no-pager", "--color=false", "/home/user/.bashrc"}
+		if calls := fake.Calls(); !reflect.DeepEqual(calls[len(calls)-1].Args, expected) {
+			t.Errorf("Expected args %v, got %v", expected, calls[len(calls)-1].Args)
+		}
+
+		fake = chezmoitest.NewFake()
+		fake.On("--version").Stdout("chezmoi version v2.9.3\n")
+		fake.On("diff")
+		client = chezmoitest.New(t, fake)
+		if _, err := client.UnifiedDiff(context.Background()); err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		expected = []string{"diff", "--no-pager", "--color=false"}
+		if calls := fake.Calls(); !reflect.DeepEqual(calls[len(calls)-1].Args, expected) {
+			t.Errorf("Expected args %v for chezmoi 2.9.3, got %v", expected, calls[len(calls)-1].Args)
+		}
+	})
+
+	t.Run("MissingCapability", func(t *testing.T) {
+		fake := chezmoitest.NewFake()
+		fake.On("--version").Stdout("chezmoi version v2.30.0\n")
+		client := chezmoitest.New(t, fake)
+
+		_, err := client.ManagedEntries(context.Background(), chezmoi.ListOptions{})
+		if !errors.Is(err, chezmoi.ErrVersionTooOld) {
+			t.Errorf("Expected ErrVersionTooOld, got: %v", err)
+		}
+		if fake.CallCount("managed") != 0 {
+			t.Error("Expected chezmoi not to be run without the capability")
+		}
+	})
+}
@@ -0,0 +1,332 @@
+// Package config loads the chezmoi-tui configuration file and merges it with
+// environment variable and command line overrides.
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"chezmoi-tui/internal/fsutil"
+)
+
+// EnvPrefix prefixes the environment variables that override settings, e.g.
+// CHEZMOI_TUI_TUI_REFRESH_INTERVAL overrides tui.refresh_interval
+const EnvPrefix = "CHEZMOI_TUI_"
+
+// EnvConfigFile is the environment variable naming the configuration file
+const EnvConfigFile = EnvPrefix + "CONFIG"
+
+// Config is the chezmoi-tui configuration
+type Config struct {
+	Theme       ThemeConfig       `yaml:"theme" json:"theme"`
+	TUI         TUIConfig         `yaml:"tui" json:"tui"`
+	CLI         CLIConfig         `yaml:"cli" json:"cli"`
+	Integration IntegrationConfig `yaml:"integration" json:"integration"`
+	Bitwarden   BitwardenConfig   `yaml:"bitwarden" json:"bitwarden"`
+
+	// File is the file the configuration was read from, or empty if no
+	// configuration file exists
+	File string `yaml:"-" json:"-"`
+
+	// sources records the source of every setting not at its default
+	sources map[string]Source
+}
+
+// ThemeConfig holds the TUI colours. Colours are hex values such as
+// "#1793d1" or ANSI colour numbers.
+type ThemeConfig struct {
+	PrimaryColor   string `yaml:"primary_color" json:"primary_color"`
+	SecondaryColor string `yaml:"secondary_color" json:"secondary_color"`
+	SuccessColor   string `yaml:"success_color" json:"success_color"`
+	WarningColor   string `yaml:"warning_color" json:"warning_color"`
+	DangerColor    string `yaml:"danger_color" json:"danger_color"`
+}
+
+// TUIConfig holds the behaviour of the terminal user interface
+type TUIConfig struct {
+	// ShowHelp shows the key bindings below the menu
+	ShowHelp bool `yaml:"show_help" json:"show_help"`
+	// RefreshInterval is how often, in seconds, the status screen reloads
+	// when files are not watched. Zero disables polling.
+	RefreshInterval int `yaml:"refresh_interval" json:"refresh_interval"`
+	// Watch refreshes the status screen when files in the source directory
+	// or managed targets change
+	Watch bool `yaml:"watch" json:"watch"`
+	// MaxFileDisplay limits the number of files listed at once. Zero
+	// disables the limit.
+	MaxFileDisplay int `yaml:"max_file_display" json:"max_file_display"`
+}
+
+// CLIConfig holds the behaviour of the command line interface
+type CLIConfig struct {
+	// Verbose passes --verbose to chezmoi
+	Verbose bool `yaml:"verbose" json:"verbose"`
+	// Color enables coloured output
+	Color bool `yaml:"color" json:"color"`
+	// Pager is the command long output is piped through on a terminal. An
+	// empty pager disables paging.
+	Pager string `yaml:"pager" json:"pager"`
+}
+
+// IntegrationConfig holds the settings of the external tools
+type IntegrationConfig struct {
+	// ChezmoiBinaryPath is the chezmoi binary, empty to look it up in PATH
+	ChezmoiBinaryPath string `yaml:"chezmoi_binary_path" json:"chezmoi_binary_path"`
+	// BitwardenBinaryPath is the bw binary, empty to look it up in PATH
+	BitwardenBinaryPath string `yaml:"bitwarden_binary_path" json:"bitwarden_binary_path"`
+	// Timeout is the maximum duration of a chezmoi command in seconds. Zero
+	// disables the limit.
+	Timeout int `yaml:"timeout" json:"timeout"`
+}
+
+// BitwardenConfig holds the default paths of the Bitwarden commands
+type BitwardenConfig struct {
+	// TemplatePath is where generated templates are written
+	TemplatePath string `yaml:"template_path" json:"template_path"`
+	// ExportPath is where secrets are exported to
+	ExportPath string `yaml:"export_path" json:"export_path"`
+	// SessionTimeout is how long, in minutes, the session key of an
+	// unlocked vault is kept without being used. Zero keeps it until the
+	// vault is locked.
+	SessionTimeout int `yaml:"session_timeout" json:"session_timeout"`
+}
+
+// Default returns the default configuration
+func Default() *Config {
+	return &Config{
+		Theme: ThemeConfig{
+			PrimaryColor:   "#1793d1",
+			SecondaryColor: "#0366d6",
+			SuccessColor:   "#28a745",
+			WarningColor:   "#ffc107",
+			DangerColor:    "#dc3545",
+		},
+		TUI: TUIConfig{
+			ShowHelp:        true,
+			RefreshInterval: 5,
+			Watch:           true,
+			MaxFileDisplay:  100,
+		},
+		CLI: CLIConfig{
+			Color: true,
+			Pager: "less",
+		},
+		Integration: IntegrationConfig{
+			Timeout: 30,
+		},
+		Bitwarden: BitwardenConfig{
+			TemplatePath:   "~/.local/share/chezmoi/dot_secrets.tmpl",
+			ExportPath:     ".env",
+			SessionTimeout: 30,
+		},
+	}
+}
+
+// DefaultPath returns the location config generate writes to
+func DefaultPath() string {
+	return fsutil.ExpandHome("~/.config/chezmoi-tui/config.yaml")
+}
+
+// SearchPaths returns the locations searched for a configuration file, in
+// order of preference
+func SearchPaths() []string {
+	home := fsutil.ExpandHome("~")
+	dir := filepath.Join(home, ".config", "chezmoi-tui")
+	return []string{
+		filepath.Join(dir, "config.yaml"),
+		filepath.Join(dir, "config.yml"),
+		filepath.Join(dir, "config.json"),
+		filepath.Join(home, ".chezmoi-tui.yaml"),
+		filepath.Join(home, ".chezmoi-tui.json"),
+	}
+}
+
+// FindFile returns the configuration file to read: path if it is not empty,
+// then the file named by CHEZMOI_TUI_CONFIG, then the first of SearchPaths
+// that exists. It returns an empty string if there is none.
+func FindFile(path string) string {
+	if path != "" {
+		return path
+	}
+	if path := os.Getenv(EnvConfigFile); path != "" {
+		return path
+	}
+	for _, path := range SearchPaths() {
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return ""
+}
+
+// Load reads the configuration file found by FindFile(path) on top of the
+// defaults and applies the environment variable overrides. A missing default
+// file is not an error, but a file given explicitly must exist. The result
+// is not validated so that callers can apply further overrides first.
+func Load(path string) (*Config, error) {
+	cfg := Default()
+
+	file := FindFile(path)
+	if file == "" {
+		return cfg, cfg.ApplyEnv()
+	}
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+	if err := cfg.decode(file, data); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", file, err)
+	}
+	cfg.File = file
+	for _, key := range fileKeys(data) {
+		cfg.setSource(key, SourceFile)
+	}
+
+	return cfg, cfg.ApplyEnv()
+}
+
+// decode merges data onto c, choosing the format from the file extension
+func (c *Config) decode(file string, data []byte) error {
+	if isJSON(file) {
+		return json.Unmarshal(data, c)
+	}
+	return yaml.Unmarshal(data, c)
+}
+
+// ApplyEnv applies CHEZMOI_TUI_<SECTION>_<KEY> environment variables
+func (c *Config) ApplyEnv() error {
+	var errs []error
+	for _, key := range Keys() {
+		name := EnvName(key)
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			continue
+		}
+		if err := c.Override(key, value, SourceEnv); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// EnvName returns the environment variable overriding key
+func EnvName(key string) string {
+	return EnvPrefix + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+}
+
+// Keys returns the dotted keys of every setting, such as
+// "tui.refresh_interval", in file order
+func Keys() []string {
+	var keys []string
+	cfg := reflect.TypeOf(Config{})
+	for i := 0; i < cfg.NumField(); i++ {
+		section := cfg.Field(i)
+		name := tagName(section)
+		if name == "" {
+			continue
+		}
+		for j := 0; j < section.Type.NumField(); j++ {
+			keys = append(keys, name+"."+tagName(section.Type.Field(j)))
+		}
+	}
+	return keys
+}
+
+// Set parses value according to the type of the setting key and stores it
+func (c *Config) Set(key, value string) error {
+	field, err := c.field(key)
+	if err != nil {
+		return err
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q for %s", value, key)
+		}
+		field.SetBool(b)
+	case reflect.Int:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("invalid integer %q for %s", value, key)
+		}
+		field.SetInt(int64(n))
+	}
+	return nil
+}
+
+// field returns the settable struct field of key
+func (c *Config) field(key string) (reflect.Value, error) {
+	sectionName, fieldName, ok := strings.Cut(key, ".")
+	if !ok {
+		return reflect.Value{}, fmt.Errorf("unknown setting %q, expected section.key", key)
+	}
+
+	section, ok := fieldByTag(reflect.ValueOf(c).Elem(), sectionName)
+	if !ok || section.Kind() != reflect.Struct {
+		return reflect.Value{}, fmt.Errorf("unknown config section %q", sectionName)
+	}
+	field, ok := fieldByTag(section, fieldName)
+	if !ok {
+		return reflect.Value{}, fmt.Errorf("unknown setting %q", key)
+	}
+	return field, nil
+}
+
+// fieldByTag returns the field of the struct v whose yaml tag is name
+func fieldByTag(v reflect.Value, name string) (reflect.Value, bool) {
+	field, ok := structFieldByTag(v.Type(), name)
+	if !ok {
+		return reflect.Value{}, false
+	}
+	return v.FieldByIndex(field.Index), true
+}
+
+// structFieldByTag returns the field of the struct type t whose yaml tag is
+// name
+func structFieldByTag(t reflect.Type, name string) (reflect.StructField, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		if field := t.Field(i); name != "" && tagName(field) == name {
+			return field, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+// tagName returns the yaml name of a struct field, or an empty string for
+// fields that are not settings
+func tagName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+	if name == "-" {
+		return ""
+	}
+	return name
+}
+
+// RefreshInterval returns tui.refresh_interval as a duration
+func (c *Config) RefreshInterval() time.Duration {
+	return time.Duration(c.TUI.RefreshInterval) * time.Second
+}
+
+// Timeout returns integration.timeout as a duration
+func (c *Config) Timeout() time.Duration {
+	return time.Duration(c.Integration.Timeout) * time.Second
+}
+
+// SessionTimeout returns bitwarden.session_timeout as a duration
+func (c *Config) SessionTimeout() time.Duration {
+	return time.Duration(c.Bitwarden.SessionTimeout) * time.Minute
+}
@@ -0,0 +1,321 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// writeFile writes content to name in a temporary directory and returns its path
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatalf("Failed to write %s: %v", path, err)
+	}
+	return path
+}
+
+func TestTemplateMatchesDefault(t *testing.T) {
+	cfg, err := Load(writeFile(t, "config.yaml", Template))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	cfg.File = ""
+	cfg.sources = nil
+
+	if !reflect.DeepEqual(cfg, Default()) {
+		t.Errorf("Expected the generated template to hold the defaults, got %+v", cfg)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Expected the defaults to be valid, got: %v", err)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	t.Run("YAMLMergesOntoDefaults", func(t *testing.T) {
+		path := writeFile(t, "config.yaml", "tui:\n  refresh_interval: 10\ncli:\n  pager: \"\"\n")
+		cfg, err := Load(path)
+		if err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		if cfg.TUI.RefreshInterval != 10 || cfg.CLI.Pager != "" {
+			t.Errorf("Expected file values to be applied, got %+v", cfg)
+		}
+		if cfg.TUI.MaxFileDisplay != 100 || cfg.Integration.Timeout != 30 {
+			t.Errorf("Expected unset values to keep their defaults, got %+v", cfg)
+		}
+		if cfg.File != path {
+			t.Errorf("Expected file %s, got %s", path, cfg.File)
+		}
+		if cfg.Source("cli.pager") != SourceFile || cfg.Source("cli.color") != SourceDefault {
+			t.Errorf("Expected only settings in the file to come from it, got %v", cfg.sources)
+		}
+	})
+
+	t.Run("JSON", func(t *testing.T) {
+		cfg, err := Load(writeFile(t, "config.json", `{"theme": {"primary_color": "#ff0000"}, "integration": {"timeout": 5}}`))
+		if err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		if cfg.Theme.PrimaryColor != "#ff0000" || cfg.Timeout().Seconds() != 5 {
+			t.Errorf("Expected JSON values to be applied, got %+v", cfg)
+		}
+	})
+
+	t.Run("SearchesDefaultLocations", func(t *testing.T) {
+		home := t.TempDir()
+		t.Setenv("HOME", home)
+		if err := os.WriteFile(filepath.Join(home, ".chezmoi-tui.json"), []byte(`{"tui": {"show_help": false}}`), 0600); err != nil {
+			t.Fatal(err)
+		}
+
+		cfg, err := Load("")
+		if err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		if cfg.TUI.ShowHelp || cfg.File != filepath.Join(home, ".chezmoi-tui.json") {
+			t.Errorf("Expected ~/.chezmoi-tui.json to be read, got %+v", cfg)
+		}
+	})
+
+	t.Run("MissingDefaultFile", func(t *testing.T) {
+		cfg, err := Load("")
+		if err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		if cfg.File != "" || !reflect.DeepEqual(cfg, Default()) {
+			t.Errorf("Expected the defaults, got %+v", cfg)
+		}
+	})
+
+	t.Run("MissingExplicitFile", func(t *testing.T) {
+		if _, err := Load(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+			t.Error("Expected an error for a missing config file")
+		}
+	})
+
+	t.Run("EnvConfigFile", func(t *testing.T) {
+		t.Setenv(EnvConfigFile, writeFile(t, "custom.yml", "cli:\n  verbose: true\n"))
+		cfg, err := Load("")
+		if err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		if !cfg.CLI.Verbose {
+			t.Error("Expected the file named by CHEZMOI_TUI_CONFIG to be read")
+		}
+	})
+
+	t.Run("InvalidSyntax", func(t *testing.T) {
+		if _, err := Load(writeFile(t, "config.yaml", "tui: [")); err == nil {
+			t.Error("Expected an error for invalid YAML")
+		}
+	})
+}
+
+func TestApplyEnv(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("CHEZMOI_TUI_TUI_REFRESH_INTERVAL", "0")
+	t.Setenv("CHEZMOI_TUI_CLI_COLOR", "false")
+	t.Setenv("CHEZMOI_TUI_INTEGRATION_CHEZMOI_BINARY_PATH", "/opt/bin/chezmoi")
+
+	cfg, err := Load(writeFile(t, "config.yaml", "tui:\n  refresh_interval: 10\n"))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if cfg.TUI.RefreshInterval != 0 || cfg.CLI.Color || cfg.Integration.ChezmoiBinaryPath != "/opt/bin/chezmoi" {
+		t.Errorf("Expected environment overrides to win over the file, got %+v", cfg)
+	}
+
+	sources := map[string]Source{
+		"tui.refresh_interval":              SourceEnv,
+		"tui.show_help":                     SourceDefault,
+		"integration.chezmoi_binary_path":   SourceEnv,
+		"integration.bitwarden_binary_path": SourceDefault,
+	}
+	for key, expected := range sources {
+		if source := cfg.Source(key); source != expected {
+			t.Errorf("Expected %s to come from %s, got %s", key, expected, source)
+		}
+	}
+
+	t.Setenv("CHEZMOI_TUI_INTEGRATION_TIMEOUT", "soon")
+	if _, err := Load(""); err == nil || !strings.Contains(err.Error(), "CHEZMOI_TUI_INTEGRATION_TIMEOUT") {
+		t.Errorf("Expected an error naming the variable, got: %v", err)
+	}
+}
+
+func TestSet(t *testing.T) {
+	cfg := Default()
+
+	if err := cfg.Set("tui.max_file_display", "25"); err != nil || cfg.TUI.MaxFileDisplay != 25 {
+		t.Errorf("Expected max_file_display to be set, got %d (%v)", cfg.TUI.MaxFileDisplay, err)
+	}
+	if value, err := cfg.Get("tui.max_file_display"); err != nil || value != "25" {
+		t.Errorf("Expected 25, got %q (%v)", value, err)
+	}
+	for _, key := range []string{"tui", "tui.unknown", "unknown.key", "file"} {
+		if err := cfg.Set(key, "1"); err == nil {
+			t.Errorf("Expected an error for key %q", key)
+		}
+	}
+	if err := cfg.Set("cli.verbose", "maybe"); err == nil {
+		t.Error("Expected an error for an invalid boolean")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	cfg := Default()
+	cfg.Theme.WarningColor = "yellow"
+	cfg.Theme.DangerColor = "196"
+	cfg.TUI.RefreshInterval = -1
+	cfg.Bitwarden.SessionTimeout = -5
+
+	var validationErr ValidationError
+	if err := cfg.Validate(); !errors.As(err, &validationErr) {
+		t.Fatalf("Expected a validation error, got: %v", err)
+	}
+
+	var keys []string
+	for _, fieldErr := range validationErr {
+		keys = append(keys, fieldErr.Key)
+	}
+	expected := []string{"theme.warning_color", "tui.refresh_interval", "bitwarden.session_timeout"}
+	if !reflect.DeepEqual(keys, expected) {
+		t.Errorf("Expected errors for %v, got %v", expected, keys)
+	}
+}
+
+func TestCheckFile(t *testing.T) {
+	path := writeFile(t, "config.yaml", `theme:
+  primary_color: "blue"
+tui:
+  refresh_interval: soon
+  colour: true
+plugins:
+  - backup
+`)
+
+	problems, err := CheckFile(path)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	var got []string
+	for _, problem := range problems {
+		got = append(got, problem.String())
+	}
+	expected := []string{
+		`line 4: tui.refresh_interval: expected an integer, got "soon"`,
+		`line 5: tui.colour: unknown setting`,
+		`line 6: plugins: unknown section`,
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected problems %q, got %q", expected, got)
+	}
+
+	// Value checks run once the types are right
+	path = writeFile(t, "config.json", "{\n  \"theme\": {\n    \"primary_color\": \"blue\"\n  }\n}\n")
+	problems, err = CheckFile(path)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(problems) != 1 || problems[0].Line != 3 || problems[0].Key != "theme.primary_color" {
+		t.Errorf("Expected an invalid colour on line 3, got %+v", problems)
+	}
+
+	if _, err := CheckFile(writeFile(t, "config.yaml", "tui: [")); err == nil {
+		t.Error("Expected an error for invalid YAML")
+	}
+}
+
+func TestSetInFile(t *testing.T) {
+	t.Run("PreservesComments", func(t *testing.T) {
+		path := writeFile(t, "config.yaml", Template)
+		if err := SetInFile(path, "tui.refresh_interval", "10"); err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		if err := SetInFile(path, "cli.pager", "more"); err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+
+		// Everything but the two values is kept, including blank lines
+		expected := strings.NewReplacer(
+			"refresh_interval: 5 #", "refresh_interval: 10 #",
+			`pager: "less" #`, `pager: "more" #`,
+		).Replace(Template)
+		if data, _ := os.ReadFile(path); string(data) != expected {
+			t.Errorf("Expected only the edited values to change, got:\n%s", data)
+		}
+	})
+
+	t.Run("AddsMissingKeys", func(t *testing.T) {
+		path := writeFile(t, "config.yaml", "# mine\ntui:\n  show_help: false\n\n# CLI\ncli:\n  pager:\n")
+		if err := SetInFile(path, "integration.timeout", "60"); err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		if err := SetInFile(path, "tui.watch", "false"); err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		if err := SetInFile(path, "cli.pager", "more"); err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+
+		expected := "# mine\ntui:\n  show_help: false\n  watch: false\n\n# CLI\ncli:\n  pager: \"more\"\n\nintegration:\n  timeout: 60\n"
+		if data, _ := os.ReadFile(path); string(data) != expected {
+			t.Errorf("Expected the keys to be added, got:\n%s", data)
+		}
+
+		cfg, err := Load(path)
+		if err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		if cfg.Integration.Timeout != 60 || cfg.TUI.ShowHelp {
+			t.Errorf("Expected both settings in the file, got %+v", cfg)
+		}
+	})
+
+	t.Run("JSON", func(t *testing.T) {
+		path := writeFile(t, "config.json", `{"tui": {"show_help": false}}`)
+		if err := SetInFile(path, "tui.max_file_display", "50"); err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+
+		cfg, err := Load(path)
+		if err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		if cfg.TUI.MaxFileDisplay != 50 || cfg.TUI.ShowHelp {
+			t.Errorf("Expected both settings in the file, got %+v", cfg)
+		}
+	})
+
+	t.Run("CreatesFile", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "new", "config.yaml")
+		if err := SetInFile(path, "cli.verbose", "true"); err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		data, _ := os.ReadFile(path)
+		if !strings.Contains(string(data), "verbose: true") || !strings.Contains(string(data), "# Theme settings") {
+			t.Errorf("Expected the template with the new value, got:\n%s", data)
+		}
+	})
+
+	t.Run("RejectsInvalidValues", func(t *testing.T) {
+		path := writeFile(t, "config.yaml", Template)
+		for key, value := range map[string]string{"theme.primary_color": "blue", "tui.refresh_interval": "soon", "tui.unknown": "1"} {
+			if err := SetInFile(path, key, value); err == nil {
+				t.Errorf("Expected an error for %s=%s", key, value)
+			}
+		}
+		if data, _ := os.ReadFile(path); string(data) != Template {
+			t.Error("Expected the file to be unchanged")
+		}
+	})
+}
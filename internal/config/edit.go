@@ -0,0 +1,347 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"chezmoi-tui/internal/fsutil"
+)
+
+// SetInFile sets key to value in the configuration file at path, creating
+// the file from Template if it does not exist. YAML files are edited in place
+// so that comments, blank lines and the order of settings are preserved.
+func SetInFile(path, key, value string) error {
+	// Parse the value first so that invalid input never touches the file
+	cfg := Default()
+	if err := cfg.Set(key, value); err != nil {
+		return err
+	}
+	field, _ := cfg.field(key)
+	typed := field.Interface()
+
+	data, err := os.ReadFile(path)
+	mode := os.FileMode(0644)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		data = []byte(Template)
+		if isJSON(path) {
+			data, _ = json.Marshal(Default())
+		}
+	case err != nil:
+		return fmt.Errorf("failed to read config file: %w", err)
+	default:
+		if info, err := os.Stat(path); err == nil {
+			mode = info.Mode().Perm()
+		}
+	}
+
+	var edited []byte
+	if isJSON(path) {
+		edited, err = setJSON(data, key, typed)
+	} else {
+		edited, err = setYAML(data, key, typed)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to edit config file %s: %w", path, err)
+	}
+
+	// Reject values that parse but are invalid, such as malformed colours
+	result := Default()
+	if err := result.decode(path, edited); err != nil {
+		return fmt.Errorf("failed to edit config file %s: %w", path, err)
+	}
+	var validationErr ValidationError
+	if errors.As(result.Validate(), &validationErr) {
+		for _, fieldErr := range validationErr {
+			if fieldErr.Key == key {
+				return fieldErr
+			}
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+	if err := fsutil.WriteFileAtomic(path, edited, mode); err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+	return nil
+}
+
+// setYAML sets key to value in a YAML document, adding the section and key
+// if they are missing. The new value is spliced into the original text where
+// possible, so that everything else, including blank lines, is kept as is;
+// documents too unusual for that are re-encoded.
+func setYAML(data []byte, key string, value any) ([]byte, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	sectionName, name, _ := strings.Cut(key, ".")
+	if edited, ok := spliceYAML(data, &doc, sectionName, name, value); ok {
+		return edited, nil
+	}
+
+	if len(doc.Content) == 0 {
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}}
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("expected a mapping of sections on line %d", root.Line)
+	}
+
+	section := mappingValue(root, sectionName)
+	if section.Kind != yaml.MappingNode {
+		*section = yaml.Node{Kind: yaml.MappingNode, Tag: "!!map", LineComment: section.LineComment}
+	}
+
+	setScalar(mappingValue(section, name), value)
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// setScalar makes node a scalar holding value. Strings keep the quoting of
+// the value they replace and are double quoted otherwise.
+func setScalar(node *yaml.Node, value any) {
+	node.Kind = yaml.ScalarNode
+	switch value := value.(type) {
+	case bool:
+		node.Tag, node.Value, node.Style = "!!bool", strconv.FormatBool(value), 0
+	case int:
+		node.Tag, node.Value, node.Style = "!!int", strconv.Itoa(value), 0
+	default:
+		node.Tag, node.Value = "!!str", fmt.Sprint(value)
+		if node.Style == 0 {
+			node.Style = yaml.DoubleQuotedStyle
+		}
+	}
+}
+
+// spliceYAML sets the key name of section to value by editing the text of
+// the document parsed into doc: an existing value is replaced on its line, a
+// missing key is added below the last key of its section and a missing
+// section is appended. It reports false if the document uses constructs it
+// does not handle, such as flow mappings or multi-line values.
+func spliceYAML(data []byte, doc *yaml.Node, sectionName, name string, value any) ([]byte, bool) {
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode || doc.Content[0].Style&yaml.FlowStyle != 0 {
+		return nil, false
+	}
+	root := doc.Content[0]
+	lines := bytes.SplitAfter(data, []byte("\n"))
+
+	section := lookup(root, sectionName)
+	if section == nil {
+		indent := sectionIndent(root)
+		var out bytes.Buffer
+		out.Write(data)
+		if len(data) > 0 && !bytes.HasSuffix(data, []byte("\n")) {
+			out.WriteByte('\n')
+		}
+		if len(root.Content) > 0 {
+			out.WriteByte('\n')
+		}
+		fmt.Fprintf(&out, "%s:\n%s%s: %s\n", sectionName, indent, name, scalarText(nil, value))
+		return out.Bytes(), true
+	}
+	if section.Kind != yaml.MappingNode || section.Style&yaml.FlowStyle != 0 || len(section.Content) == 0 {
+		return nil, false
+	}
+
+	if node := lookup(section, name); node != nil {
+		start, end, ok := scalarSpan(lines, node)
+		if !ok {
+			return nil, false
+		}
+		text := scalarText(node, value)
+		if start == end {
+			// An empty value starts right after the colon of its key
+			if start == 0 || data[start-1] != ':' {
+				return nil, false
+			}
+			text = " " + text
+		}
+		return splice(data, start, end, []byte(text)), true
+	}
+
+	// Add the key on the line after the last value of the section
+	last := section.Content[len(section.Content)-1]
+	if _, _, ok := scalarSpan(lines, last); !ok {
+		return nil, false
+	}
+	offset := 0
+	for _, line := range lines[:last.Line] {
+		offset += len(line)
+	}
+	indent := strings.Repeat(" ", section.Content[0].Column-1)
+	line := fmt.Sprintf("%s%s: %s\n", indent, name, scalarText(nil, value))
+	if !bytes.HasSuffix(lines[last.Line-1], []byte("\n")) {
+		line = "\n" + line
+	}
+	return splice(data, offset, offset, []byte(line)), true
+}
+
+// lookup returns the value node of key in the mapping node m, or nil
+func lookup(m *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			return m.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// sectionIndent returns the indentation of the keys of the first section,
+// two spaces if there is none
+func sectionIndent(root *yaml.Node) string {
+	for i := 1; i < len(root.Content); i += 2 {
+		if section := root.Content[i]; section.Kind == yaml.MappingNode && len(section.Content) > 0 && section.Style&yaml.FlowStyle == 0 {
+			return strings.Repeat(" ", section.Content[0].Column-1)
+		}
+	}
+	return "  "
+}
+
+// scalarText returns value as YAML text, quoted like old if it is a string
+func scalarText(old *yaml.Node, value any) string {
+	node := &yaml.Node{}
+	if old != nil {
+		node.Style = old.Style
+	}
+	setScalar(node, value)
+	out, err := yaml.Marshal(node)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return strings.TrimSuffix(string(out), "\n")
+}
+
+// scalarSpan returns the byte offsets of the text of a scalar that is
+// written on a single line without a tag or anchor. ok is false for any
+// other node.
+func scalarSpan(lines [][]byte, node *yaml.Node) (start, end int, ok bool) {
+	if node.Kind != yaml.ScalarNode || node.Style&(yaml.LiteralStyle|yaml.FoldedStyle|yaml.TaggedStyle) != 0 ||
+		node.Anchor != "" || strings.Contains(node.Value, "\n") || node.Line < 1 || node.Line > len(lines) {
+		return 0, 0, false
+	}
+	line := lines[node.Line-1]
+	// Columns count characters, not bytes
+	runes := []rune(string(line))
+	if node.Column < 1 || node.Column > len(runes) {
+		return 0, 0, false
+	}
+	col := len(string(runes[:node.Column-1]))
+	text := string(line[col:])
+
+	length := -1
+	switch {
+	case strings.HasPrefix(text, `"`):
+		for i := 1; i < len(text); i++ {
+			if text[i] == '\\' {
+				i++
+			} else if text[i] == '"' {
+				length = i + 1
+				break
+			}
+		}
+	case strings.HasPrefix(text, "'"):
+		for i := 1; i < len(text); i++ {
+			if text[i] != '\'' {
+				continue
+			}
+			if i+1 < len(text) && text[i+1] == '\'' {
+				i++
+				continue
+			}
+			length = i + 1
+			break
+		}
+	case strings.HasPrefix(text, "!"), strings.HasPrefix(text, "&"), strings.HasPrefix(text, "*"):
+	default:
+		plain := text
+		if i := strings.Index(plain, " #"); i >= 0 {
+			plain = plain[:i]
+		}
+		if i := strings.Index(plain, "\t#"); i >= 0 {
+			plain = plain[:i]
+		}
+		length = len(strings.TrimRight(plain, " \t\r\n"))
+	}
+	if length < 0 {
+		return 0, 0, false
+	}
+
+	offset := 0
+	for _, l := range lines[:node.Line-1] {
+		offset += len(l)
+	}
+	return offset + col, offset + col + length, true
+}
+
+// splice returns data with the bytes from start to end replaced by text
+func splice(data []byte, start, end int, text []byte) []byte {
+	out := make([]byte, 0, len(data)-(end-start)+len(text))
+	out = append(out, data[:start]...)
+	out = append(out, text...)
+	return append(out, data[end:]...)
+}
+
+// mappingValue returns the value node of key in the mapping node m,
+// appending an empty one if the key is missing
+func mappingValue(m *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			return m.Content[i+1]
+		}
+	}
+
+	value := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null"}
+	m.Content = append(m.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, value)
+	return value
+}
+
+// setJSON sets key to value in a JSON document. JSON has no comments to
+// preserve, but the keys are written in sorted order.
+func setJSON(data []byte, key string, value any) ([]byte, error) {
+	doc := make(map[string]any)
+	if len(bytes.TrimSpace(data)) > 0 {
+		if err := json.Unmarshal(data, &doc); err != nil {
+			return nil, err
+		}
+	}
+
+	sectionName, name, _ := strings.Cut(key, ".")
+	section, ok := doc[sectionName].(map[string]any)
+	if !ok {
+		section = make(map[string]any)
+		doc[sectionName] = section
+	}
+	section[name] = value
+
+	out, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(out, '\n'), nil
+}
+
+// isJSON reports whether path names a JSON configuration file
+func isJSON(path string) bool {
+	return strings.EqualFold(filepath.Ext(path), ".json")
+}
@@ -0,0 +1,87 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Source identifies where the effective value of a setting came from
+type Source int
+
+const (
+	// SourceDefault is a built-in default
+	SourceDefault Source = iota
+	// SourceFile is the configuration file
+	SourceFile
+	// SourceEnv is a CHEZMOI_TUI_* environment variable
+	SourceEnv
+	// SourceFlag is a command line flag
+	SourceFlag
+)
+
+// String returns the lower case name of the source
+func (s Source) String() string {
+	switch s {
+	case SourceFile:
+		return "file"
+	case SourceEnv:
+		return "env"
+	case SourceFlag:
+		return "flag"
+	default:
+		return "default"
+	}
+}
+
+// Source returns where the value of key came from
+func (c *Config) Source(key string) Source {
+	return c.sources[key]
+}
+
+// Override sets key from a string value and records its source
+func (c *Config) Override(key, value string, source Source) error {
+	if err := c.Set(key, value); err != nil {
+		return err
+	}
+	c.setSource(key, source)
+	return nil
+}
+
+// setSource records the source of key
+func (c *Config) setSource(key string, source Source) {
+	if c.sources == nil {
+		c.sources = make(map[string]Source)
+	}
+	c.sources[key] = source
+}
+
+// Get returns the value of key formatted as it would be passed to Set
+func (c *Config) Get(key string) (string, error) {
+	field, err := c.field(key)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprint(field.Interface()), nil
+}
+
+// fileKeys returns the dotted keys of the known settings present in a
+// configuration file. JSON is parsed as YAML, of which it is a subset.
+func fileKeys(data []byte) []string {
+	var file struct {
+		Sections map[string]map[string]yaml.Node `yaml:",inline"`
+	}
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil
+	}
+
+	var keys []string
+	for _, key := range Keys() {
+		section, name, _ := strings.Cut(key, ".")
+		if _, ok := file.Sections[section][name]; ok {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
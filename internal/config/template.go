@@ -0,0 +1,42 @@
+package config
+
+// Template is the commented configuration written by config generate. It
+// holds the same values as Default.
+const Template = `# Chezmoi TUI Configuration
+# This file configures the enhanced TUI and CLI for chezmoi.
+# Every setting can be overridden with a CHEZMOI_TUI_<SECTION>_<KEY>
+# environment variable, e.g. CHEZMOI_TUI_TUI_REFRESH_INTERVAL=10.
+
+# Theme settings
+theme:
+  primary_color: "#1793d1"
+  secondary_color: "#0366d6"
+  success_color: "#28a745"
+  warning_color: "#ffc107"
+  danger_color: "#dc3545"
+
+# TUI settings
+tui:
+  show_help: true
+  refresh_interval: 5 # seconds, 0 to disable
+  watch: true # refresh on file changes, polling is the fallback
+  max_file_display: 100 # 0 for no limit
+
+# CLI settings
+cli:
+  verbose: false
+  color: true
+  pager: "less" # empty to disable paging
+
+# Integration settings
+integration:
+  chezmoi_binary_path: ""
+  bitwarden_binary_path: ""
+  timeout: 30 # seconds, 0 to disable
+
+# Bitwarden settings
+bitwarden:
+  template_path: "~/.local/share/chezmoi/dot_secrets.tmpl"
+  export_path: ".env"
+  session_timeout: 30 # minutes of inactivity before the vault is locked, 0 to disable
+`
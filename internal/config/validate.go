@@ -0,0 +1,212 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FieldError describes an invalid setting
+type FieldError struct {
+	// Key is the dotted key of the setting, e.g. "tui.refresh_interval"
+	Key string
+	// Message explains what is wrong with the value
+	Message string
+}
+
+// Error implements the error interface
+func (e FieldError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Key, e.Message)
+}
+
+// ValidationError lists every invalid setting of a configuration
+type ValidationError []FieldError
+
+// Error implements the error interface
+func (e ValidationError) Error() string {
+	messages := make([]string, len(e))
+	for i, fieldErr := range e {
+		messages[i] = fieldErr.Error()
+	}
+	return "invalid configuration: " + strings.Join(messages, "; ")
+}
+
+// hexColorPattern matches #rgb and #rrggbb colours
+var hexColorPattern = regexp.MustCompile(`^#(?:[0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+
+// Validate checks every setting and returns a ValidationError listing the
+// invalid ones, or nil
+func (c *Config) Validate() error {
+	var errs ValidationError
+
+	colors := []struct{ key, value string }{
+		{"theme.primary_color", c.Theme.PrimaryColor},
+		{"theme.secondary_color", c.Theme.SecondaryColor},
+		{"theme.success_color", c.Theme.SuccessColor},
+		{"theme.warning_color", c.Theme.WarningColor},
+		{"theme.danger_color", c.Theme.DangerColor},
+	}
+	for _, color := range colors {
+		if !validColor(color.value) {
+			errs = append(errs, FieldError{color.key, fmt.Sprintf("invalid colour %q, expected #rrggbb or an ANSI colour number", color.value)})
+		}
+	}
+
+	if c.TUI.RefreshInterval < 0 {
+		errs = append(errs, FieldError{"tui.refresh_interval", "must not be negative"})
+	}
+	if c.TUI.MaxFileDisplay < 0 {
+		errs = append(errs, FieldError{"tui.max_file_display", "must not be negative"})
+	}
+	if c.Integration.Timeout < 0 {
+		errs = append(errs, FieldError{"integration.timeout", "must not be negative"})
+	}
+	if c.Bitwarden.SessionTimeout < 0 {
+		errs = append(errs, FieldError{"bitwarden.session_timeout", "must not be negative"})
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// validColor reports whether s is a hex colour or an ANSI colour number
+func validColor(s string) bool {
+	if hexColorPattern.MatchString(s) {
+		return true
+	}
+	n, err := strconv.Atoi(s)
+	return err == nil && n >= 0 && n <= 255
+}
+
+// Problem is an error at a position in a configuration file
+type Problem struct {
+	// Line is the 1-based line of the offending key, or 0 if unknown
+	Line int
+	// Key is the dotted key or section the problem concerns
+	Key string
+	// Message describes the problem
+	Message string
+}
+
+// String formats the problem as "line N: key: message"
+func (p Problem) String() string {
+	if p.Line > 0 {
+		return fmt.Sprintf("line %d: %s: %s", p.Line, p.Key, p.Message)
+	}
+	return fmt.Sprintf("%s: %s", p.Key, p.Message)
+}
+
+// CheckFile validates the configuration file at path against the settings
+// schema: unknown sections and keys, values of the wrong type and invalid
+// values are reported as problems with their line numbers. An error is only
+// returned if the file cannot be read or parsed.
+func CheckFile(path string) ([]Problem, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	// JSON is parsed as YAML, of which it is a subset, to get line numbers
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+	if len(doc.Content) == 0 {
+		return nil, nil
+	}
+
+	problems, lines := checkSchema(doc.Content[0])
+	if len(problems) > 0 {
+		// Values of the wrong type cannot be decoded for further checks
+		return problems, nil
+	}
+
+	cfg := Default()
+	if err := cfg.decode(path, data); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+	var validationErr ValidationError
+	if errors.As(cfg.Validate(), &validationErr) {
+		for _, fieldErr := range validationErr {
+			problems = append(problems, Problem{Line: lines[fieldErr.Key], Key: fieldErr.Key, Message: fieldErr.Message})
+		}
+	}
+	return problems, nil
+}
+
+// checkSchema reports unknown keys and values of the wrong type in a parsed
+// configuration file and returns the line of every known key
+func checkSchema(root *yaml.Node) ([]Problem, map[string]int) {
+	var problems []Problem
+	lines := make(map[string]int)
+
+	if root.Kind != yaml.MappingNode {
+		return []Problem{{Line: root.Line, Key: "(root)", Message: "expected a mapping of sections"}}, lines
+	}
+
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		sectionKey, sectionValue := root.Content[i], root.Content[i+1]
+		section, ok := structFieldByTag(reflect.TypeOf(Config{}), sectionKey.Value)
+		if !ok {
+			problems = append(problems, Problem{Line: sectionKey.Line, Key: sectionKey.Value, Message: "unknown section"})
+			continue
+		}
+		if sectionValue.Tag == "!!null" {
+			continue
+		}
+		if sectionValue.Kind != yaml.MappingNode {
+			problems = append(problems, Problem{Line: sectionKey.Line, Key: sectionKey.Value, Message: "expected a mapping of settings"})
+			continue
+		}
+
+		for j := 0; j+1 < len(sectionValue.Content); j += 2 {
+			settingKey, settingValue := sectionValue.Content[j], sectionValue.Content[j+1]
+			key := sectionKey.Value + "." + settingKey.Value
+			setting, ok := structFieldByTag(section.Type, settingKey.Value)
+			if !ok {
+				problems = append(problems, Problem{Line: settingKey.Line, Key: key, Message: "unknown setting"})
+				continue
+			}
+			lines[key] = settingKey.Line
+			if !scalarMatches(settingValue, setting.Type.Kind()) {
+				problems = append(problems, Problem{Line: settingKey.Line, Key: key, Message: fmt.Sprintf("expected %s, got %q", kindName(setting.Type.Kind()), settingValue.Value)})
+			}
+		}
+	}
+	return problems, lines
+}
+
+// scalarMatches reports whether node is a scalar that decodes into kind
+func scalarMatches(node *yaml.Node, kind reflect.Kind) bool {
+	if node.Kind != yaml.ScalarNode {
+		return false
+	}
+	switch kind {
+	case reflect.Bool:
+		return node.Tag == "!!bool"
+	case reflect.Int:
+		return node.Tag == "!!int"
+	default:
+		return true
+	}
+}
+
+// kindName returns the name of a setting type used in messages
+func kindName(kind reflect.Kind) string {
+	switch kind {
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Int:
+		return "an integer"
+	default:
+		return "a string"
+	}
+}
@@ -0,0 +1,325 @@
+// Package diff parses unified diffs, such as the output of chezmoi diff, into
+// files, hunks and lines.
+package diff
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// DevNull is the path of the missing side of a created or deleted file
+const DevNull = "/dev/null"
+
+// LineKind is the type of a line of a hunk
+type LineKind int
+
+const (
+	// Context is a line present in both versions
+	Context LineKind = iota
+	// Added is a line only present in the new version
+	Added
+	// Removed is a line only present in the old version
+	Removed
+)
+
+// Prefix returns the character that starts lines of kind k in a unified diff
+func (k LineKind) Prefix() string {
+	switch k {
+	case Added:
+		return "+"
+	case Removed:
+		return "-"
+	default:
+		return " "
+	}
+}
+
+// Line is a line of a hunk
+type Line struct {
+	Kind LineKind
+	// Text is the line without its prefix and newline
+	Text string
+	// OldNumber and NewNumber are the 1-based line numbers in the old and new
+	// versions, or zero if the line is not present in that version
+	OldNumber int
+	NewNumber int
+	// NoNewline is set on the last line of a version that does not end with a
+	// newline
+	NoNewline bool
+}
+
+// Hunk is a contiguous region of changes
+type Hunk struct {
+	OldStart int
+	OldLines int
+	NewStart int
+	NewLines int
+	// Section is the text following the range, usually the enclosing
+	// function or heading
+	Section string
+	Lines   []Line
+}
+
+// Header returns the @@ line of the hunk
+func (h *Hunk) Header() string {
+	header := fmt.Sprintf("@@ -%s +%s @@", formatRange(h.OldStart, h.OldLines), formatRange(h.NewStart, h.NewLines))
+	if h.Section != "" {
+		header += " " + h.Section
+	}
+	return header
+}
+
+// formatRange formats a hunk range, omitting a length of one
+func formatRange(start, lines int) string {
+	if lines == 1 {
+		return strconv.Itoa(start)
+	}
+	return fmt.Sprintf("%d,%d", start, lines)
+}
+
+// Row is a line of a side-by-side rendering of a hunk. Context rows have the
+// same line on both sides; a removed line is paired with the added line that
+// replaces it, if any.
+type Row struct {
+	Old *Line
+	New *Line
+}
+
+// Rows pairs the lines of the hunk for a side-by-side rendering
+func (h *Hunk) Rows() []Row {
+	var rows []Row
+	for i := 0; i < len(h.Lines); {
+		line := &h.Lines[i]
+		if line.Kind == Context {
+			rows = append(rows, Row{Old: line, New: line})
+			i++
+			continue
+		}
+
+		// Pair a run of removed lines with the run of added lines after it
+		var removed, added []*Line
+		for ; i < len(h.Lines) && h.Lines[i].Kind == Removed; i++ {
+			removed = append(removed, &h.Lines[i])
+		}
+		for ; i < len(h.Lines) && h.Lines[i].Kind == Added; i++ {
+			added = append(added, &h.Lines[i])
+		}
+		for j := 0; j < max(len(removed), len(added)); j++ {
+			var row Row
+			if j < len(removed) {
+				row.Old = removed[j]
+			}
+			if j < len(added) {
+				row.New = added[j]
+			}
+			rows = append(rows, row)
+		}
+	}
+	return rows
+}
+
+// File is the diff of a single file
+type File struct {
+	// OldPath and NewPath are the paths of the two versions without the a/
+	// and b/ prefixes, or DevNull for a created or deleted file
+	OldPath string
+	NewPath string
+	// Headers are the extended header lines, such as "diff --git" and mode
+	// changes
+	Headers []string
+	// Binary is set if the file contents are binary and not shown
+	Binary bool
+	Hunks  []Hunk
+}
+
+// Path returns the path of the file, whichever version exists
+func (f *File) Path() string {
+	if f.NewPath == DevNull || f.NewPath == "" {
+		return f.OldPath
+	}
+	return f.NewPath
+}
+
+// IsNew reports whether the file is created by the diff
+func (f *File) IsNew() bool {
+	return f.OldPath == DevNull
+}
+
+// IsDeleted reports whether the file is removed by the diff
+func (f *File) IsDeleted() bool {
+	return f.NewPath == DevNull
+}
+
+// hunkPattern matches a hunk header, e.g. "@@ -1,3 +1,4 @@ func main() {"
+var hunkPattern = regexp.MustCompile(`^@@ -(\d+)(?:,(\d+))? \+(\d+)(?:,(\d+))? @@ ?(.*)$`)
+
+// Parse parses a unified diff, either plain or in git's extended format
+func Parse(text string) ([]File, error) {
+	var (
+		files            []File
+		file             *File
+		hunk             *Hunk
+		oldLeft, newLeft int
+		oldLine, newLine int
+	)
+
+	newFile := func() {
+		files = append(files, File{})
+		file = &files[len(files)-1]
+		hunk = nil
+	}
+
+	lines := strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+	if text == "" {
+		lines = nil
+	}
+	for n, line := range lines {
+		// Inside a hunk, lines are counted against its header
+		if hunk != nil && (oldLeft > 0 || newLeft > 0) {
+			kind := Context
+			switch {
+			case line == "" || line[0] == ' ':
+				// Some tools strip the trailing space of empty context lines
+			case line[0] == '+':
+				kind = Added
+			case line[0] == '-':
+				kind = Removed
+			case line[0] == '\\':
+				markNoNewline(hunk)
+				continue
+			default:
+				return nil, fmt.Errorf("line %d: unexpected %q in hunk", n+1, line)
+			}
+
+			l := Line{Kind: kind}
+			if line != "" {
+				l.Text = line[1:]
+			}
+			if kind != Added {
+				if oldLeft == 0 {
+					return nil, fmt.Errorf("line %d: hunk has more lines than its header %q", n+1, hunk.Header())
+				}
+				oldLeft--
+				l.OldNumber = oldLine
+				oldLine++
+			}
+			if kind != Removed {
+				if newLeft == 0 {
+					return nil, fmt.Errorf("line %d: hunk has more lines than its header %q", n+1, hunk.Header())
+				}
+				newLeft--
+				l.NewNumber = newLine
+				newLine++
+			}
+			hunk.Lines = append(hunk.Lines, l)
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(line, `\`) && hunk != nil:
+			markNoNewline(hunk)
+		case strings.HasPrefix(line, "diff "):
+			newFile()
+			file.Headers = append(file.Headers, line)
+			file.OldPath, file.NewPath = parseGitPaths(line)
+		case strings.HasPrefix(line, "--- "):
+			if file == nil || len(file.Hunks) > 0 {
+				newFile()
+			}
+			file.OldPath = parsePath(line[4:])
+		case strings.HasPrefix(line, "+++ "):
+			if file == nil {
+				return nil, fmt.Errorf("line %d: %q without a preceding ---", n+1, line)
+			}
+			file.NewPath = parsePath(line[4:])
+		case strings.HasPrefix(line, "@@ "):
+			if file == nil {
+				return nil, fmt.Errorf("line %d: hunk outside of a file", n+1)
+			}
+			h, err := parseHunkHeader(line)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", n+1, err)
+			}
+			file.Hunks = append(file.Hunks, h)
+			hunk = &file.Hunks[len(file.Hunks)-1]
+			oldLeft, newLeft = h.OldLines, h.NewLines
+			oldLine, newLine = h.OldStart, h.NewStart
+		case strings.HasPrefix(line, "Binary files "):
+			if file == nil {
+				newFile()
+			}
+			file.Binary = true
+		case file != nil && len(file.Hunks) == 0:
+			// Extended headers such as index, mode and rename lines
+			file.Headers = append(file.Headers, line)
+		}
+	}
+
+	if hunk != nil && (oldLeft > 0 || newLeft > 0) {
+		return nil, fmt.Errorf("hunk %q of %s is truncated", hunk.Header(), file.Path())
+	}
+	return files, nil
+}
+
+// parseHunkHeader parses an @@ line
+func parseHunkHeader(line string) (Hunk, error) {
+	match := hunkPattern.FindStringSubmatch(line)
+	if match == nil {
+		return Hunk{}, fmt.Errorf("invalid hunk header %q", line)
+	}
+
+	h := Hunk{OldLines: 1, NewLines: 1, Section: match[5]}
+	h.OldStart, _ = strconv.Atoi(match[1])
+	h.NewStart, _ = strconv.Atoi(match[3])
+	if match[2] != "" {
+		h.OldLines, _ = strconv.Atoi(match[2])
+	}
+	if match[4] != "" {
+		h.NewLines, _ = strconv.Atoi(match[4])
+	}
+	return h, nil
+}
+
+// markNoNewline records a "\ No newline at end of file" marker on the last
+// line of the hunk
+func markNoNewline(hunk *Hunk) {
+	if len(hunk.Lines) > 0 {
+		hunk.Lines[len(hunk.Lines)-1].NoNewline = true
+	}
+}
+
+// parsePath parses the path of a --- or +++ line, removing the timestamp
+// some tools append and the a/ or b/ prefix
+func parsePath(s string) string {
+	if i := strings.IndexByte(s, '\t'); i >= 0 {
+		s = s[:i]
+	}
+	if strings.HasPrefix(s, `"`) {
+		if unquoted, err := strconv.Unquote(s); err == nil {
+			s = unquoted
+		}
+	}
+	if s == DevNull {
+		return s
+	}
+	if strings.HasPrefix(s, "a/") || strings.HasPrefix(s, "b/") {
+		return s[2:]
+	}
+	return s
+}
+
+// parseGitPaths parses the paths of a "diff --git a/old b/new" line, which
+// are all that identifies files without contents, such as binary files
+func parseGitPaths(line string) (string, string) {
+	rest, ok := strings.CutPrefix(line, "diff --git ")
+	if !ok || !strings.HasPrefix(rest, "a/") {
+		return "", ""
+	}
+	i := strings.Index(rest, " b/")
+	if i < 0 {
+		return "", ""
+	}
+	return rest[2:i], rest[i+3:]
+}
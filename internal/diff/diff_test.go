@@ -0,0 +1,194 @@
+package diff
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	data, err := os.ReadFile("testdata/chezmoi.diff")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := Parse(string(data))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(files) != 3 {
+		t.Fatalf("Expected three files, got %d", len(files))
+	}
+
+	bashrc := files[0]
+	if bashrc.Path() != ".bashrc" || len(bashrc.Hunks) != 2 || len(bashrc.Headers) != 2 {
+		t.Fatalf("Unexpected .bashrc diff: %+v", bashrc)
+	}
+	first := bashrc.Hunks[0]
+	expected := []Line{
+		{Kind: Context, Text: "# .bashrc", OldNumber: 1, NewNumber: 1},
+		{Kind: Removed, Text: "export EDITOR=vi", OldNumber: 2},
+		{Kind: Added, Text: "export EDITOR=nvim", NewNumber: 2},
+		{Kind: Added, Text: "export PAGER=less", NewNumber: 3},
+		{Kind: Context, Text: "", OldNumber: 3, NewNumber: 4},
+		{Kind: Context, Text: `alias ll="ls -l"`, OldNumber: 4, NewNumber: 5},
+	}
+	if !reflect.DeepEqual(first.Lines, expected) {
+		t.Errorf("Unexpected lines:\n%+v", first.Lines)
+	}
+	second := bashrc.Hunks[1]
+	if second.Header() != "@@ -10 +11 @@ prompt()" || !second.Lines[1].NoNewline || second.Lines[0].NoNewline {
+		t.Errorf("Unexpected second hunk: %+v", second)
+	}
+
+	ignore := files[1]
+	if !ignore.IsNew() || ignore.Path() != ".config/git/ignore" || len(ignore.Hunks[0].Lines) != 2 {
+		t.Errorf("Unexpected new file: %+v", ignore)
+	}
+
+	tool := files[2]
+	if !tool.Binary || tool.Path() != ".local/bin/tool" || len(tool.Hunks) != 0 {
+		t.Errorf("Unexpected binary file: %+v", tool)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	testCases := []struct {
+		name string
+		diff string
+	}{
+		{name: "Hunk without file", diff: "@@ -1 +1 @@\n-a\n+b\n"},
+		{name: "Invalid hunk header", diff: "--- a/x\n+++ b/x\n@@ -a +b @@\n"},
+		{name: "Truncated hunk", diff: "--- a/x\n+++ b/x\n@@ -1,3 +1,3 @@\n a\n"},
+		{name: "Unexpected line", diff: "--- a/x\n+++ b/x\n@@ -1,2 +1,2 @@\n a\n*b\n"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := Parse(tc.diff); err == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+
+	if files, err := Parse(""); err != nil || len(files) != 0 {
+		t.Errorf("Expected an empty diff to have no files, got %v, %v", files, err)
+	}
+}
+
+func TestRows(t *testing.T) {
+	files, err := Parse("--- a/x\n+++ b/x\n@@ -1,4 +1,3 @@\n a\n-b\n-c\n+B\n d\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var rows []string
+	for _, row := range files[0].Hunks[0].Rows() {
+		var old, new string
+		if row.Old != nil {
+			old = row.Old.Text
+		}
+		if row.New != nil {
+			new = row.New.Text
+		}
+		rows = append(rows, old+"|"+new)
+	}
+	if expected := []string{"a|a", "b|B", "c|", "d|d"}; !reflect.DeepEqual(rows, expected) {
+		t.Errorf("Expected rows %q, got %q", expected, rows)
+	}
+}
+
+func TestWords(t *testing.T) {
+	old, new := Words("export EDITOR=vi", "export EDITOR=nvim")
+
+	expectedOld := []Segment{{Text: "export EDITOR="}, {Text: "vi", Changed: true}}
+	expectedNew := []Segment{{Text: "export EDITOR="}, {Text: "nvim", Changed: true}}
+	if !reflect.DeepEqual(old, expectedOld) {
+		t.Errorf("Unexpected old segments: %+v", old)
+	}
+	if !reflect.DeepEqual(new, expectedNew) {
+		t.Errorf("Unexpected new segments: %+v", new)
+	}
+}
+
+func TestApply(t *testing.T) {
+	const old = "a\nb\nc\nd\ne\nf\ng\nh\n"
+	files, err := Parse("--- a/x\n+++ b/x\n@@ -1,3 +1,3 @@\n a\n-b\n+B\n c\n@@ -6,3 +6,4 @@\n f\n g\n+G\n h\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	hunks := files[0].Hunks
+
+	testCases := []struct {
+		name     string
+		hunks    []Hunk
+		expected string
+	}{
+		{name: "All hunks", hunks: hunks, expected: "a\nB\nc\nd\ne\nf\ng\nG\nh\n"},
+		{name: "First hunk", hunks: hunks[:1], expected: "a\nB\nc\nd\ne\nf\ng\nh\n"},
+		{name: "Second hunk", hunks: hunks[1:], expected: "a\nb\nc\nd\ne\nf\ng\nG\nh\n"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := Apply(old, tc.hunks)
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+			if result != tc.expected {
+				t.Errorf("Expected %q, got %q", tc.expected, result)
+			}
+
+			// Reversing the hunks restores the original
+			var reversed []Hunk
+			for _, h := range tc.hunks {
+				reversed = append(reversed, h.Reverse())
+			}
+			restored, err := Apply(result, reversed)
+			if err != nil || restored != old {
+				t.Errorf("Expected the reversed hunks to restore the original, got %q, %v", restored, err)
+			}
+		})
+	}
+
+	if _, err := Apply("a\nX\nc\n", hunks[:1]); err == nil {
+		t.Error("Expected a hunk whose context changed not to apply")
+	}
+}
+
+func TestApplyNoNewline(t *testing.T) {
+	files, err := Parse("--- a/x\n+++ b/x\n@@ -1,2 +1,2 @@\n a\n-b\n\\ No newline at end of file\n+b\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	hunk := files[0].Hunks[0]
+
+	result, err := Apply("a\nb", []Hunk{hunk})
+	if err != nil || result != "a\nb\n" {
+		t.Errorf("Expected a final newline to be added, got %q, %v", result, err)
+	}
+	restored, err := Apply(result, []Hunk{hunk.Reverse()})
+	if err != nil || restored != "a\nb" {
+		t.Errorf("Expected the final newline to be removed, got %q, %v", restored, err)
+	}
+}
+
+func TestFormatRoundTrip(t *testing.T) {
+	data, err := os.ReadFile("testdata/chezmoi.diff")
+	if err != nil {
+		t.Fatal(err)
+	}
+	files, err := Parse(string(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reparsed, err := Parse(Format(files[:2]))
+	if err != nil {
+		t.Fatalf("Expected the formatted diff to parse, got: %v", err)
+	}
+	for i := range reparsed {
+		if !reflect.DeepEqual(reparsed[i].Hunks, files[i].Hunks) || reparsed[i].Path() != files[i].Path() {
+			t.Errorf("Expected file %d to round trip, got %+v", i, reparsed[i])
+		}
+	}
+}
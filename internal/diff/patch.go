@@ -0,0 +1,148 @@
+package diff
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// Reverse returns the hunk that undoes h
+func (h Hunk) Reverse() Hunk {
+	r := Hunk{
+		OldStart: h.NewStart,
+		OldLines: h.NewLines,
+		NewStart: h.OldStart,
+		NewLines: h.OldLines,
+		Section:  h.Section,
+	}
+
+	// Added lines become removed ones and must move before the lines they
+	// replace
+	for i := 0; i < len(h.Lines); {
+		if h.Lines[i].Kind == Context {
+			r.Lines = append(r.Lines, reverseLine(h.Lines[i]))
+			i++
+			continue
+		}
+
+		var removed, added []Line
+		for ; i < len(h.Lines) && h.Lines[i].Kind == Removed; i++ {
+			removed = append(removed, reverseLine(h.Lines[i]))
+		}
+		for ; i < len(h.Lines) && h.Lines[i].Kind == Added; i++ {
+			added = append(added, reverseLine(h.Lines[i]))
+		}
+		r.Lines = append(r.Lines, added...)
+		r.Lines = append(r.Lines, removed...)
+	}
+	return r
+}
+
+// reverseLine swaps the versions of a line
+func reverseLine(l Line) Line {
+	switch l.Kind {
+	case Added:
+		l.Kind = Removed
+	case Removed:
+		l.Kind = Added
+	}
+	l.OldNumber, l.NewNumber = l.NewNumber, l.OldNumber
+	return l
+}
+
+// Apply applies hunks, which must be in file order and must not overlap, to
+// the old version of a file and returns the new version. Unlike patch(1) it
+// does not search for moved context: every hunk must match exactly at its
+// position, adjusted for the hunks of the same file that are not applied.
+func Apply(content string, hunks []Hunk) (string, error) {
+	lines, noNewline := splitLines(content)
+
+	var out []string
+	pos := 0
+	for _, h := range hunks {
+		// A hunk without old lines inserts after line OldStart
+		start := h.OldStart - 1
+		if h.OldLines == 0 {
+			start = h.OldStart
+		}
+		if start < pos {
+			return "", fmt.Errorf("hunk %q overlaps the hunk before it", h.Header())
+		}
+		if start > len(lines) {
+			return "", fmt.Errorf("hunk %q starts after the end of the file", h.Header())
+		}
+		out = append(out, lines[pos:start]...)
+
+		i := start
+		for _, l := range h.Lines {
+			if l.Kind == Added {
+				out = append(out, l.Text)
+				continue
+			}
+			if i >= len(lines) || lines[i] != l.Text {
+				return "", fmt.Errorf("hunk %q does not apply: line %d has changed", h.Header(), i+1)
+			}
+			if l.Kind == Context {
+				out = append(out, l.Text)
+			}
+			i++
+		}
+		pos = i
+
+		// The new version of the last line decides whether the file ends with
+		// a newline
+		if pos == len(lines) {
+			noNewline = false
+			for j := len(h.Lines) - 1; j >= 0; j-- {
+				if h.Lines[j].Kind != Removed {
+					noNewline = h.Lines[j].NoNewline
+					break
+				}
+			}
+		}
+	}
+	out = append(out, lines[pos:]...)
+
+	result := strings.Join(out, "\n")
+	if len(out) > 0 && !noNewline {
+		result += "\n"
+	}
+	return result, nil
+}
+
+// splitLines splits content into lines and reports whether it lacks a final
+// newline
+func splitLines(content string) ([]string, bool) {
+	if content == "" {
+		return nil, false
+	}
+	trimmed, hadNewline := strings.CutSuffix(content, "\n")
+	return strings.Split(trimmed, "\n"), !hadNewline
+}
+
+// Format formats files as a unified diff that Parse accepts. Extended
+// headers are not written.
+func Format(files []File) string {
+	var b strings.Builder
+	for _, f := range files {
+		fmt.Fprintf(&b, "--- %s\n+++ %s\n", formatPath("a/", f.OldPath), formatPath("b/", f.NewPath))
+		for _, h := range f.Hunks {
+			b.WriteString(h.Header() + "\n")
+			for _, l := range h.Lines {
+				b.WriteString(l.Kind.Prefix() + l.Text + "\n")
+				if l.NoNewline {
+					b.WriteString("\\ No newline at end of file\n")
+				}
+			}
+		}
+	}
+	return b.String()
+}
+
+// formatPath adds the a/ or b/ prefix to relative paths
+func formatPath(prefix, path string) string {
+	if path == DevNull || filepath.IsAbs(path) {
+		return path
+	}
+	return prefix + path
+}
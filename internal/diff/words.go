@@ -0,0 +1,96 @@
+package diff
+
+import "unicode"
+
+// Segment is a run of text of a changed line
+type Segment struct {
+	Text string
+	// Changed is set if the text differs from the other version of the line
+	Changed bool
+}
+
+// maxWordCells bounds the work of comparing two lines word by word. Longer
+// lines are reported as changed as a whole.
+const maxWordCells = 250000
+
+// Words compares two versions of a line word by word and returns the
+// segments of each, marking the words that were changed
+func Words(old, new string) ([]Segment, []Segment) {
+	a, b := tokenize(old), tokenize(new)
+	if len(a)*len(b) > maxWordCells {
+		return []Segment{{Text: old, Changed: true}}, []Segment{{Text: new, Changed: true}}
+	}
+
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and
+	// b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var oldSegs, newSegs []Segment
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			oldSegs = appendSegment(oldSegs, a[i], false)
+			newSegs = appendSegment(newSegs, b[j], false)
+			i++
+			j++
+		case j < len(b) && (i == len(a) || lcs[i][j+1] >= lcs[i+1][j]):
+			newSegs = appendSegment(newSegs, b[j], true)
+			j++
+		default:
+			oldSegs = appendSegment(oldSegs, a[i], true)
+			i++
+		}
+	}
+	return oldSegs, newSegs
+}
+
+// appendSegment appends text to segs, merging it into the last segment if
+// that has the same state
+func appendSegment(segs []Segment, text string, changed bool) []Segment {
+	if n := len(segs); n > 0 && segs[n-1].Changed == changed {
+		segs[n-1].Text += text
+		return segs
+	}
+	return append(segs, Segment{Text: text, Changed: changed})
+}
+
+// tokenize splits a line into words, runs of whitespace and single
+// punctuation characters
+func tokenize(s string) []string {
+	var tokens []string
+	runes := []rune(s)
+	for i := 0; i < len(runes); {
+		j := i + 1
+		switch {
+		case isWord(runes[i]):
+			for j < len(runes) && isWord(runes[j]) {
+				j++
+			}
+		case unicode.IsSpace(runes[i]):
+			for j < len(runes) && unicode.IsSpace(runes[j]) {
+				j++
+			}
+		}
+		tokens = append(tokens, string(runes[i:j]))
+		i = j
+	}
+	return tokens
+}
+
+// isWord reports whether r is part of a word
+func isWord(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
@@ -0,0 +1,60 @@
+package fsutil
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestExpandHome(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	tests := map[string]string{
+		"~":                  home,
+		"~/.config/app.yaml": filepath.Join(home, ".config", "app.yaml"),
+		"~other/file":        "~other/file",
+		"/etc/~/file":        "/etc/~/file",
+		"relative":           "relative",
+	}
+	for path, expected := range tests {
+		if got := ExpandHome(path); got != expected {
+			t.Errorf("ExpandHome(%q) = %q, expected %q", path, got, expected)
+		}
+	}
+
+	t.Setenv("HOME", "")
+	if got := ExpandHome("~/file"); got != "~/file" {
+		t.Errorf("Expected the path to be kept without a home directory, got %q", got)
+	}
+}
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "secrets.env")
+	if err := os.WriteFile(path, []byte("old contents\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := WriteFileAtomic(path, []byte("TOKEN=s3cret\n"), 0o600); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil || string(data) != "TOKEN=s3cret\n" {
+		t.Errorf("Expected the file to be replaced, got %q (%v)", data, err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0o600 {
+		t.Errorf("Expected mode 0600, got %v", info.Mode().Perm())
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("Expected the temporary file to be removed, got %v", entries)
+	}
+
+	if err := WriteFileAtomic(filepath.Join(dir, "missing", "file"), nil, 0o600); err == nil {
+		t.Error("Expected an error for a missing directory")
+	}
+}
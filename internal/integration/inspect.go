@@ -0,0 +1,73 @@
+package integration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"chezmoi-tui/internal/chezmoi"
+)
+
+// TargetDetails describes how the source state produces a managed target
+type TargetDetails struct {
+	// Target is the absolute target path
+	Target string
+	// SourcePath is the absolute path of the entry in the source state
+	SourcePath string
+	// SourceRelative is SourcePath relative to the source directory
+	SourceRelative string
+	// Attributes are decoded from the name of the source entry
+	Attributes chezmoi.SourceAttributes
+	// Dest describes the destination file, or is nil if it does not exist
+	Dest fs.FileInfo
+	// Commit is the last commit that touched the source entry, or nil if it
+	// has never been committed
+	Commit *chezmoi.Commit
+	// CommitErr reports why the last commit could not be looked up, e.g.
+	// because the source directory is not a git repository
+	CommitErr error
+}
+
+// InspectTarget returns the details of the source state entry of the
+// absolute target path
+func (ci *ChezmoiIntegration) InspectTarget(ctx context.Context, target string) (*TargetDetails, error) {
+	sourceDir, err := ci.sourceDir(ctx)
+	if err != nil {
+		return nil, err
+	}
+	paths, err := ci.client.SourcePath(ctx, target)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find the source of %s: %w", target, err)
+	}
+	if len(paths) != 1 {
+		return nil, fmt.Errorf("failed to find the source of %s", target)
+	}
+
+	details := &TargetDetails{Target: target, SourcePath: paths[0], SourceRelative: filepath.Base(paths[0])}
+	if rel, err := filepath.Rel(sourceDir, paths[0]); err == nil {
+		details.SourceRelative = filepath.ToSlash(rel)
+	}
+	info, err := os.Lstat(details.SourcePath)
+	_, details.Attributes = chezmoi.ParseSourcePath(details.SourceRelative, err == nil && info.IsDir())
+	if info, err := os.Lstat(target); err == nil {
+		details.Dest = info
+	}
+	details.Commit, details.CommitErr = ci.client.LastCommit(ctx, details.SourcePath)
+	return details, nil
+}
+
+// sourceDir returns the absolute path of the source directory
+func (ci *ChezmoiIntegration) sourceDir(ctx context.Context) (string, error) {
+	// Without targets chezmoi source-path prints the source directory
+	paths, err := ci.client.SourcePath(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to find the source directory: %w", err)
+	}
+	if len(paths) != 1 {
+		return "", errors.New("failed to find the source directory")
+	}
+	return paths[0], nil
+}
@@ -0,0 +1,73 @@
+package integration
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"chezmoi-tui/internal/chezmoi"
+	"chezmoi-tui/internal/chezmoi/chezmoitest"
+)
+
+func TestInspectTarget(t *testing.T) {
+	dest, source := t.TempDir(), t.TempDir()
+	target := filepath.Join(dest, ".ssh", "config")
+	sourceFile := filepath.Join(source, "private_dot_ssh", "private_config.tmpl")
+	for _, path := range []string{target, sourceFile} {
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	fake := chezmoitest.NewFake()
+	fake.On("source-path").Stdout(source + "\n")
+	fake.On("source-path", target).Stdout(sourceFile + "\n")
+	fake.On("git").Stdout("0123abcd\x00Jane Doe\x002024-03-01T12:30:00Z\x00Use a jump host\n")
+	integ, err := New(chezmoi.WithExecutor(fake))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	details, err := integ.InspectTarget(context.Background(), target)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if details.SourceRelative != "private_dot_ssh/private_config.tmpl" {
+		t.Errorf("Unexpected source path %q", details.SourceRelative)
+	}
+	if attrs := details.Attributes; !attrs.Private || !attrs.Template || attrs.Perm() != 0o600 {
+		t.Errorf("Unexpected attributes %+v", attrs)
+	}
+	if details.Dest == nil || details.Dest.Mode().Perm() != 0o600 {
+		t.Errorf("Expected the destination mode, got %v", details.Dest)
+	}
+	if details.Commit == nil || details.Commit.Subject != "Use a jump host" {
+		t.Errorf("Unexpected commit %+v (%v)", details.Commit, details.CommitErr)
+	}
+	if fake.CallCount("git", "--", "log", "-1") != 1 {
+		t.Error("Expected git log to run through chezmoi git")
+	}
+}
+
+func TestInspectTargetWithoutGit(t *testing.T) {
+	fake := chezmoitest.NewFake()
+	fake.On("source-path").Stdout("/src\n")
+	fake.On("source-path", "/home/user/.bashrc").Stdout("/src/dot_bashrc\n")
+	fake.On("git").Fail(128, "fatal: not a git repository")
+	integ, err := New(chezmoi.WithExecutor(fake))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	details, err := integ.InspectTarget(context.Background(), "/home/user/.bashrc")
+	if err != nil {
+		t.Fatalf("Expected the details without a commit, got: %v", err)
+	}
+	if details.CommitErr == nil || details.Commit != nil || details.Dest != nil {
+		t.Errorf("Expected only the commit to be missing, got %+v", details)
+	}
+}
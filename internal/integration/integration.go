@@ -2,17 +2,26 @@ package integration
 
 import (
 	"chezmoi-tui/internal/chezmoi"
+	"context"
+	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
 )
 
 // ChezmoiIntegration provides high-level operations for interacting with chezmoi
 type ChezmoiIntegration struct {
 	client *chezmoi.Chezmoi
+
+	// destDir is chezmoi's destination directory once DestDir resolved it
+	mu      sync.Mutex
+	destDir string
 }
 
 // New creates a new integration layer
-func New() (*ChezmoiIntegration, error) {
-	client, err := chezmoi.New()
+func New(opts ...chezmoi.Option) (*ChezmoiIntegration, error) {
+	client, err := chezmoi.New(opts...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create chezmoi client: %w", err)
 	}
@@ -22,10 +31,14 @@ func New() (*ChezmoiIntegration, error) {
 	}, nil
 }
 
-// GetStatus returns the current status of all managed files
-func (ci *ChezmoiIntegration) GetStatus() ([]map[string]string, error) {
-	statusOutput, err := ci.client.Status()
+// GetStatus returns the current status of the specified targets, or of all
+// managed files if none are given
+func (ci *ChezmoiIntegration) GetStatus(ctx context.Context, targets ...string) ([]chezmoi.StatusEntry, error) {
+	statusOutput, err := ci.client.Status(ctx, targets...)
 	if err != nil {
+		if errors.Is(err, chezmoi.ErrNotInitialized) {
+			return nil, fmt.Errorf("source directory has not been initialized: %w", err)
+		}
 		return nil, fmt.Errorf("failed to get status: %w", err)
 	}
 
@@ -33,47 +46,140 @@ func (ci *ChezmoiIntegration) GetStatus() ([]map[string]string, error) {
 }
 
 // ApplyFiles applies the specified target files
-func (ci *ChezmoiIntegration) ApplyFiles(targets ...string) (string, error) {
-	return ci.client.Apply(targets...)
+func (ci *ChezmoiIntegration) ApplyFiles(ctx context.Context, targets ...string) (string, error) {
+	return ci.client.Apply(ctx, targets...)
+}
+
+// StreamApplyFiles applies the specified target files with opts, streaming
+// chezmoi's output as it is produced
+func (ci *ChezmoiIntegration) StreamApplyFiles(ctx context.Context, streams chezmoi.IOStreams, opts chezmoi.ApplyOptions, targets ...string) error {
+	return ci.client.ApplyStream(ctx, streams, opts, targets...)
 }
 
 // AddFiles adds the specified files to the source state
-func (ci *ChezmoiIntegration) AddFiles(targets ...string) (string, error) {
-	return ci.client.Add(targets...)
+func (ci *ChezmoiIntegration) AddFiles(ctx context.Context, targets ...string) (string, error) {
+	return ci.client.Add(ctx, targets...)
+}
+
+// StreamAddFiles adds the specified files to the source state with opts,
+// streaming chezmoi's output as it is produced
+func (ci *ChezmoiIntegration) StreamAddFiles(ctx context.Context, streams chezmoi.IOStreams, opts chezmoi.AddOptions, targets ...string) error {
+	return ci.client.AddStream(ctx, streams, opts, targets...)
+}
+
+// StreamReAddFiles updates the source state of the specified targets from
+// their destination files, streaming chezmoi's output as it is produced
+func (ci *ChezmoiIntegration) StreamReAddFiles(ctx context.Context, streams chezmoi.IOStreams, targets ...string) error {
+	return ci.client.ReAddStream(ctx, streams, targets...)
+}
+
+// StreamForgetFiles removes the specified targets from the source state
+// without touching the destination files, streaming chezmoi's output
+func (ci *ChezmoiIntegration) StreamForgetFiles(ctx context.Context, streams chezmoi.IOStreams, targets ...string) error {
+	return ci.client.ForgetStream(ctx, streams, targets...)
+}
+
+// EditSourceFiles opens the source files of the specified targets in the
+// user's editor. streams must be connected to a terminal.
+func (ci *ChezmoiIntegration) EditSourceFiles(ctx context.Context, streams chezmoi.IOStreams, targets ...string) error {
+	return ci.client.Edit(ctx, streams, targets...)
+}
+
+// DestDir returns chezmoi's destination directory. It is asked from chezmoi
+// once, falling back to the home directory, chezmoi's default, if chezmoi
+// cannot tell. A cancelled ctx is reported and the directory asked again by
+// the next call.
+func (ci *ChezmoiIntegration) DestDir(ctx context.Context) (string, error) {
+	ci.mu.Lock()
+	defer ci.mu.Unlock()
+
+	if ci.destDir != "" {
+		return ci.destDir, nil
+	}
+	destDir, err := ci.client.DestDir(ctx)
+	if err != nil {
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		home, homeErr := os.UserHomeDir()
+		if homeErr != nil {
+			return "", fmt.Errorf("failed to find the destination directory: %w", err)
+		}
+		destDir = home
+	}
+	ci.destDir = destDir
+	return destDir, nil
+}
+
+// TargetPath returns the absolute destination path of a target path
+// relative to the destination directory, as printed by chezmoi status
+func (ci *ChezmoiIntegration) TargetPath(ctx context.Context, path string) (string, error) {
+	if filepath.IsAbs(path) {
+		return path, nil
+	}
+	destDir, err := ci.DestDir(ctx)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(destDir, path), nil
 }
 
 // GetManagedFiles returns a list of all managed files
-func (ci *ChezmoiIntegration) GetManagedFiles() (string, error) {
-	return ci.client.Managed()
+func (ci *ChezmoiIntegration) GetManagedFiles(ctx context.Context) ([]string, error) {
+	return ci.client.Managed(ctx, chezmoi.ListOptions{})
+}
+
+// GetManagedEntries returns all managed entries with their source paths
+func (ci *ChezmoiIntegration) GetManagedEntries(ctx context.Context) ([]chezmoi.ManagedEntry, error) {
+	return ci.client.ManagedEntries(ctx, chezmoi.ListOptions{})
 }
 
 // GetUnmanagedFiles returns a list of all unmanaged files
-func (ci *ChezmoiIntegration) GetUnmanagedFiles() (string, error) {
-	return ci.client.Unmanaged()
+func (ci *ChezmoiIntegration) GetUnmanagedFiles(ctx context.Context) ([]string, error) {
+	return ci.client.Unmanaged(ctx, chezmoi.ListOptions{})
 }
 
 // GetIgnoredFiles returns a list of all ignored files
-func (ci *ChezmoiIntegration) GetIgnoredFiles() (string, error) {
-	return ci.client.Ignored()
+func (ci *ChezmoiIntegration) GetIgnoredFiles(ctx context.Context) ([]string, error) {
+	return ci.client.Ignored(ctx)
 }
 
 // GetConfigData returns the template data
-func (ci *ChezmoiIntegration) GetConfigData() (string, error) {
-	return ci.client.Data()
+func (ci *ChezmoiIntegration) GetConfigData(ctx context.Context) (chezmoi.TemplateData, error) {
+	return ci.client.Data(ctx)
 }
 
 // RunDoctor checks the system for potential problems
-func (ci *ChezmoiIntegration) RunDoctor() (string, error) {
-	return ci.client.Doctor()
+func (ci *ChezmoiIntegration) RunDoctor(ctx context.Context) ([]chezmoi.DoctorCheck, error) {
+	return ci.client.Doctor(ctx)
+}
+
+// DiffFiles returns the differences for the specified files as a unified
+// diff that diff.Parse can read, whatever diff.command and pager the user
+// configured
+func (ci *ChezmoiIntegration) DiffFiles(ctx context.Context, targets ...string) (string, error) {
+	return ci.client.UnifiedDiff(ctx, targets...)
 }
 
-// DiffFiles shows the differences for the specified files
-func (ci *ChezmoiIntegration) DiffFiles(targets ...string) (string, error) {
-	return ci.client.Diff(targets...)
+// StreamDiffFiles shows the differences for the specified files, streaming
+// the diff as it is produced
+func (ci *ChezmoiIntegration) StreamDiffFiles(ctx context.Context, streams chezmoi.IOStreams, targets ...string) error {
+	return ci.client.DiffStream(ctx, streams, targets...)
 }
 
 // InitializeRepo initializes the source directory with a remote repository
-func (ci *ChezmoiIntegration) InitializeRepo(repo string, apply bool) (string, error) {
+func (ci *ChezmoiIntegration) InitializeRepo(ctx context.Context, repo string, apply bool) (string, error) {
+	return ci.client.Init(ctx, initArgs(repo, apply)...)
+}
+
+// StreamInitializeRepo initializes the source directory like InitializeRepo,
+// streaming chezmoi's output as it is produced
+func (ci *ChezmoiIntegration) StreamInitializeRepo(ctx context.Context, streams chezmoi.IOStreams, repo string, apply bool) error {
+	return ci.client.InitStream(ctx, streams, initArgs(repo, apply)...)
+}
+
+// initArgs builds the arguments for chezmoi init
+func initArgs(repo string, apply bool) []string {
 	args := []string{}
 	if repo != "" {
 		args = append(args, repo)
@@ -81,5 +187,5 @@ func (ci *ChezmoiIntegration) InitializeRepo(repo string, apply bool) (string, e
 	if apply {
 		args = append(args, "--apply")
 	}
-	return ci.client.Init(args...)
+	return args
 }
@@ -1,7 +1,13 @@
 package integration
 
 import (
+	"context"
+	"errors"
 	"testing"
+	"time"
+
+	"chezmoi-tui/internal/chezmoi"
+	"chezmoi-tui/internal/chezmoi/chezmoitest"
 )
 
 func TestNewIntegration(t *testing.T) {
@@ -27,12 +33,134 @@ func TestGetConfigData(t *testing.T) {
 		t.Skipf("Skipping test: %v", err)
 	}
 
-	data, err := integ.GetConfigData()
+	data, err := integ.GetConfigData(context.Background())
 	if err != nil {
 		t.Errorf("Expected no error, got: %v", err)
 	}
 
-	if data == "" {
+	if len(data) == 0 {
 		t.Log("Config data is empty, which may be normal if chezmoi is not initialized")
 	}
 }
+
+func TestGetStatusWithFake(t *testing.T) {
+	fake := chezmoitest.NewFake()
+	fake.On("status").Stdout(" M .bashrc\nA  .gitconfig\n")
+
+	integ, err := New(chezmoi.WithExecutor(fake))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	entries, err := integ.GetStatus(context.Background())
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(entries) != 2 || entries[0].Path != ".bashrc" || !entries[1].IsAdded() {
+		t.Errorf("Unexpected status entries: %+v", entries)
+	}
+}
+
+func TestGetStatusNotInitialized(t *testing.T) {
+	fake := chezmoitest.NewFake()
+	fake.On("status").Fail(1, "chezmoi: stat /home/user/.local/share/chezmoi: no such file or directory\n")
+
+	integ, err := New(chezmoi.WithExecutor(fake))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	_, err = integ.GetStatus(context.Background())
+	if !errors.Is(err, chezmoi.ErrNotInitialized) {
+		t.Errorf("Expected not initialized error, got: %v", err)
+	}
+}
+
+func TestInitializeRepoArgs(t *testing.T) {
+	fake := chezmoitest.NewFake()
+	fake.On("init")
+
+	integ, err := New(chezmoi.WithExecutor(fake))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if _, err := integ.InitializeRepo(context.Background(), "github.com/user/dotfiles", true); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if fake.CallCount("init", "github.com/user/dotfiles", "--apply") != 1 {
+		t.Errorf("Expected init with repo and --apply, got %+v", fake.Calls())
+	}
+}
+
+func TestTargetPath(t *testing.T) {
+	t.Run("ConfiguredDestDir", func(t *testing.T) {
+		fake := chezmoitest.NewFake()
+		fake.On("dump-config", "--format", "json").Stdout(`{"destDir":"/srv/dotfiles","sourceDir":"/home/user/.local/share/chezmoi"}`)
+
+		integ, err := New(chezmoi.WithExecutor(fake))
+		if err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		if path, _ := integ.TargetPath(context.Background(), ".bashrc"); path != "/srv/dotfiles/.bashrc" {
+			t.Errorf("Expected the path in chezmoi's destDir, got %q", path)
+		}
+		if path, _ := integ.TargetPath(context.Background(), ".config/git/config"); path != "/srv/dotfiles/.config/git/config" {
+			t.Errorf("Expected the path in chezmoi's destDir, got %q", path)
+		}
+		if fake.CallCount("dump-config") != 1 {
+			t.Errorf("Expected the destDir to be resolved once, got %+v", fake.Calls())
+		}
+	})
+
+	t.Run("DestinationFlag", func(t *testing.T) {
+		fake := chezmoitest.NewFake()
+		integ, err := New(chezmoi.WithExecutor(fake), chezmoi.WithDestDir("/tmp/dest"))
+		if err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		if path, _ := integ.TargetPath(context.Background(), ".bashrc"); path != "/tmp/dest/.bashrc" {
+			t.Errorf("Expected the path in the --destination directory, got %q", path)
+		}
+		if fake.CallCount("dump-config") != 0 {
+			t.Errorf("Expected --destination to be used without asking chezmoi, got %+v", fake.Calls())
+		}
+	})
+
+	t.Run("HomeFallback", func(t *testing.T) {
+		t.Setenv("HOME", "/home/user")
+		fake := chezmoitest.NewFake()
+		fake.On("dump-config").Fail(1, "chezmoi: invalid config\n")
+
+		integ, err := New(chezmoi.WithExecutor(fake))
+		if err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		if path, _ := integ.TargetPath(context.Background(), ".bashrc"); path != "/home/user/.bashrc" {
+			t.Errorf("Expected the path in the home directory, got %q", path)
+		}
+		if path, _ := integ.TargetPath(context.Background(), "/etc/hosts"); path != "/etc/hosts" {
+			t.Errorf("Expected an absolute path to be kept, got %q", path)
+		}
+	})
+
+	t.Run("Cancelled", func(t *testing.T) {
+		fake := chezmoitest.NewFake()
+		fake.On("dump-config").Delay(time.Minute)
+
+		integ, err := New(chezmoi.WithExecutor(fake))
+		if err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		if _, err := integ.TargetPath(ctx, ".bashrc"); !errors.Is(err, context.Canceled) {
+			t.Fatalf("Expected the cancellation to be reported, got: %v", err)
+		}
+
+		fake.On("dump-config").Stdout(`{"destDir":"/srv/dotfiles"}`)
+		if path, _ := integ.TargetPath(context.Background(), ".bashrc"); path != "/srv/dotfiles/.bashrc" {
+			t.Errorf("Expected the destDir to be asked again after a cancellation, got %q", path)
+		}
+	})
+}
@@ -0,0 +1,158 @@
+package integration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"chezmoi-tui/internal/chezmoi"
+	"chezmoi-tui/internal/diff"
+	"chezmoi-tui/internal/fsutil"
+)
+
+// PatchTarget selects which side of chezmoi diff selected hunks are written
+// to
+type PatchTarget int
+
+const (
+	// PatchDestination writes hunks into the destination files, like a
+	// chezmoi apply of part of a file
+	PatchDestination PatchTarget = iota
+	// PatchSource merges the destination side of hunks back into the source
+	// state, like a chezmoi re-add of part of a file
+	PatchSource
+)
+
+// String describes the target of a patch
+func (t PatchTarget) String() string {
+	if t == PatchSource {
+		return "source state"
+	}
+	return "destination"
+}
+
+// FilePatch is a change to a single file prepared by PreparePatch
+type FilePatch struct {
+	// Path is the absolute path of the file that is written
+	Path string
+	// Diff is the change to Path
+	Diff diff.File
+
+	// file is Path with symlinks resolved, e.g. for targets applied with
+	// --mode=symlink, so that the file they point to is written rather than
+	// the link replaced
+	file     string
+	original string
+	patched  string
+	mode     os.FileMode
+}
+
+// PreparePatch applies the hunks of files, parsed from chezmoi diff, to
+// the destination or source files in memory without writing anything, so
+// that the result can be previewed and then written with WritePatch. It
+// fails if any hunk does not apply. Only files that exist on both sides can
+// be patched, and only source files that are neither templates nor
+// encrypted can be merged into.
+func (ci *ChezmoiIntegration) PreparePatch(ctx context.Context, target PatchTarget, files []diff.File) ([]FilePatch, error) {
+	var patches []FilePatch
+	for _, file := range files {
+		if len(file.Hunks) == 0 {
+			continue
+		}
+		if file.Binary || file.IsNew() || file.IsDeleted() {
+			return nil, fmt.Errorf("%s: hunks can only be applied to files that exist in both the destination and the target state", file.Path())
+		}
+
+		destPath, err := ci.TargetPath(ctx, file.Path())
+		if err != nil {
+			return nil, err
+		}
+		patch := FilePatch{Path: destPath, Diff: diff.File{OldPath: destPath, NewPath: destPath, Hunks: file.Hunks}}
+		if target == PatchSource {
+			sourcePaths, err := ci.client.SourcePath(ctx, destPath)
+			if err != nil {
+				return nil, fmt.Errorf("failed to find the source of %s: %w", file.Path(), err)
+			}
+			if len(sourcePaths) != 1 {
+				return nil, fmt.Errorf("failed to find the source of %s", file.Path())
+			}
+			if err := checkMergeable(sourcePaths[0]); err != nil {
+				return nil, fmt.Errorf("%s: %w", file.Path(), err)
+			}
+
+			// The source file holds the target state, the new side of the diff
+			patch.Path = sourcePaths[0]
+			patch.Diff = diff.File{OldPath: patch.Path, NewPath: patch.Path}
+			for _, hunk := range file.Hunks {
+				patch.Diff.Hunks = append(patch.Diff.Hunks, hunk.Reverse())
+			}
+		}
+
+		patch.file, err = filepath.EvalSymlinks(patch.Path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", patch.Path, err)
+		}
+		info, err := os.Stat(patch.file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", patch.Path, err)
+		}
+		data, err := os.ReadFile(patch.file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", patch.Path, err)
+		}
+		patch.original = string(data)
+		patch.mode = info.Mode().Perm()
+		patch.patched, err = diff.Apply(patch.original, patch.Diff.Hunks)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", patch.Path, err)
+		}
+		patches = append(patches, patch)
+	}
+	return patches, nil
+}
+
+// WritePatch writes prepared patches. Each file is replaced atomically; if
+// a write fails, the files already written are restored so that the patch
+// is applied completely or not at all. A file that changed since the patch
+// was prepared is not overwritten. Symlinks are written through.
+func WritePatch(patches []FilePatch) error {
+	for _, patch := range patches {
+		data, err := os.ReadFile(patch.file)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", patch.Path, err)
+		}
+		if string(data) != patch.original {
+			return fmt.Errorf("%s changed since the patch was prepared", patch.Path)
+		}
+	}
+
+	for i, patch := range patches {
+		if err := fsutil.WriteFileAtomic(patch.file, []byte(patch.patched), patch.mode); err != nil {
+			errs := []error{fmt.Errorf("failed to write %s: %w", patch.Path, err)}
+			for _, written := range patches[:i] {
+				if err := fsutil.WriteFileAtomic(written.file, []byte(written.original), written.mode); err != nil {
+					errs = append(errs, fmt.Errorf("failed to restore %s: %w", written.Path, err))
+				}
+			}
+			return errors.Join(errs...)
+		}
+	}
+	return nil
+}
+
+// checkMergeable returns an error if the source file at path cannot be
+// patched directly because chezmoi transforms its contents
+func checkMergeable(path string) error {
+	attrs := chezmoi.ParseSourceName(filepath.Base(path), false)
+	switch {
+	case attrs.Template:
+		return errors.New("templates cannot be patched, edit the source with chezmoi edit instead")
+	case attrs.Encrypted:
+		return errors.New("encrypted files cannot be patched, edit the source with chezmoi edit instead")
+	case attrs.Type == chezmoi.SourceModify, attrs.Type == chezmoi.SourceSymlink:
+		return errors.New("the source is a script or symlink, not the file contents")
+	}
+	return nil
+}
@@ -0,0 +1,177 @@
+package integration
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"chezmoi-tui/internal/chezmoi"
+	"chezmoi-tui/internal/chezmoi/chezmoitest"
+	"chezmoi-tui/internal/diff"
+)
+
+// patchDiff changes two lines of .bashrc in separate hunks
+const patchDiff = `diff --git a/.bashrc b/.bashrc
+--- a/.bashrc
++++ b/.bashrc
+@@ -1,2 +1,2 @@
+-export EDITOR=vi
++export EDITOR=nvim
+ a
+@@ -5,2 +5,2 @@
+ d
+-export PAGER=more
++export PAGER=less
+`
+
+// newPatchIntegration returns an integration with a destination directory
+// holding .bashrc and a source directory holding dot_bashrc, and the parsed
+// patchDiff
+func newPatchIntegration(t *testing.T, fake *chezmoitest.Fake) (*ChezmoiIntegration, string, string, []diff.File) {
+	t.Helper()
+
+	dest, source := t.TempDir(), t.TempDir()
+	destFile, sourceFile := filepath.Join(dest, ".bashrc"), filepath.Join(source, "dot_bashrc")
+	if err := os.WriteFile(destFile, []byte("export EDITOR=vi\na\nb\nc\nd\nexport PAGER=more\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(sourceFile, []byte("export EDITOR=nvim\na\nb\nc\nd\nexport PAGER=less\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	fake.On("--destination", dest, "source-path").Stdout(sourceFile + "\n")
+
+	integ, err := New(chezmoi.WithExecutor(fake), chezmoi.WithDestDir(dest))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	files, err := diff.Parse(patchDiff)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return integ, destFile, sourceFile, files
+}
+
+func TestPatchDestination(t *testing.T) {
+	integ, destFile, _, files := newPatchIntegration(t, chezmoitest.NewFake())
+
+	// Take the editor from the source state and keep the local pager
+	files[0].Hunks = files[0].Hunks[:1]
+	patches, err := integ.PreparePatch(context.Background(), PatchDestination, files)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(patches) != 1 || patches[0].Path != destFile {
+		t.Fatalf("Unexpected patches: %+v", patches)
+	}
+	if preview := diff.Format([]diff.File{patches[0].Diff}); !strings.Contains(preview, "+export EDITOR=nvim") {
+		t.Errorf("Expected the preview to add the new editor, got:\n%s", preview)
+	}
+
+	if err := WritePatch(patches); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	data, _ := os.ReadFile(destFile)
+	if string(data) != "export EDITOR=nvim\na\nb\nc\nd\nexport PAGER=more\n" {
+		t.Errorf("Unexpected destination file:\n%s", data)
+	}
+	if info, _ := os.Stat(destFile); info.Mode().Perm() != 0600 {
+		t.Errorf("Expected the file mode to be kept, got %v", info.Mode().Perm())
+	}
+}
+
+func TestPatchDestinationSymlink(t *testing.T) {
+	integ, destFile, _, files := newPatchIntegration(t, chezmoitest.NewFake())
+
+	// A target applied with --mode=symlink points into another directory
+	linked := filepath.Join(t.TempDir(), "bashrc")
+	data, _ := os.ReadFile(destFile)
+	if err := os.WriteFile(linked, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(destFile); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(linked, destFile); err != nil {
+		t.Fatal(err)
+	}
+
+	patches, err := integ.PreparePatch(context.Background(), PatchDestination, files)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if err := WritePatch(patches); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if target, err := os.Readlink(destFile); err != nil || target != linked {
+		t.Errorf("Expected the symlink to be kept, got %q (%v)", target, err)
+	}
+	data, _ = os.ReadFile(linked)
+	if string(data) != "export EDITOR=nvim\na\nb\nc\nd\nexport PAGER=less\n" {
+		t.Errorf("Expected the linked file to be patched, got:\n%s", data)
+	}
+}
+
+func TestPatchSource(t *testing.T) {
+	fake := chezmoitest.NewFake()
+	integ, destFile, sourceFile, files := newPatchIntegration(t, fake)
+
+	// Merge the local pager back into the source state
+	files[0].Hunks = files[0].Hunks[1:]
+	patches, err := integ.PreparePatch(context.Background(), PatchSource, files)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if fake.CallCount("--destination", filepath.Dir(destFile), "source-path", destFile) != 1 {
+		t.Errorf("Expected the source path to be looked up, got %+v", fake.Calls())
+	}
+	if err := WritePatch(patches); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	data, _ := os.ReadFile(sourceFile)
+	if string(data) != "export EDITOR=nvim\na\nb\nc\nd\nexport PAGER=more\n" {
+		t.Errorf("Unexpected source file:\n%s", data)
+	}
+}
+
+func TestPatchRejected(t *testing.T) {
+	t.Run("Template", func(t *testing.T) {
+		fake := chezmoitest.NewFake()
+		integ, _, _, files := newPatchIntegration(t, fake)
+		fake.On("--destination").Stdout("/src/dot_bashrc.tmpl\n")
+
+		if _, err := integ.PreparePatch(context.Background(), PatchSource, files); err == nil || !strings.Contains(err.Error(), "templates") {
+			t.Errorf("Expected templates to be rejected, got: %v", err)
+		}
+	})
+
+	t.Run("Stale", func(t *testing.T) {
+		integ, destFile, _, files := newPatchIntegration(t, chezmoitest.NewFake())
+
+		patches, err := integ.PreparePatch(context.Background(), PatchDestination, files)
+		if err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		if err := os.WriteFile(destFile, []byte("edited\n"), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if err := WritePatch(patches); err == nil {
+			t.Error("Expected a file changed since preparing to be rejected")
+		}
+		if data, _ := os.ReadFile(destFile); string(data) != "edited\n" {
+			t.Errorf("Expected the changed file to be left alone, got:\n%s", data)
+		}
+	})
+
+	t.Run("HunkDoesNotApply", func(t *testing.T) {
+		integ, destFile, _, files := newPatchIntegration(t, chezmoitest.NewFake())
+		if err := os.WriteFile(destFile, []byte("export EDITOR=emacs\n"), 0600); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := integ.PreparePatch(context.Background(), PatchDestination, files); err == nil {
+			t.Error("Expected a hunk that does not apply to be rejected")
+		}
+	})
+}
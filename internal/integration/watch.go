@@ -0,0 +1,30 @@
+package integration
+
+import (
+	"context"
+	"fmt"
+
+	"chezmoi-tui/internal/chezmoi"
+)
+
+// WatchPaths returns the source directory and the absolute destination
+// paths of the managed targets, which are the files whose changes can
+// change the status
+func (ci *ChezmoiIntegration) WatchPaths(ctx context.Context) (string, []string, error) {
+	sourceDir, err := ci.sourceDir(ctx)
+	if err != nil {
+		return "", nil, err
+	}
+
+	managed, err := ci.client.Managed(ctx, chezmoi.ListOptions{})
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to list managed files: %w", err)
+	}
+	targets := make([]string, len(managed))
+	for i, path := range managed {
+		if targets[i], err = ci.TargetPath(ctx, path); err != nil {
+			return "", nil, err
+		}
+	}
+	return sourceDir, targets, nil
+}
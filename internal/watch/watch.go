@@ -0,0 +1,211 @@
+// Package watch reports changes to the files that decide the chezmoi status:
+// the contents of the source directory and the managed targets.
+package watch
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// Change describes the files that changed during a burst of events
+type Change struct {
+	// Source is set if anything in the source directory changed. A change
+	// of the source state can affect any target.
+	Source bool
+	// Targets are the managed targets that changed, in sorted order
+	Targets []string
+}
+
+// Watcher watches a source directory recursively and a set of managed
+// targets using the notification mechanism of the operating system, such
+// as inotify on Linux. Events are debounced: a Change is delivered once no
+// event has arrived for the debounce interval.
+type Watcher struct {
+	fs        *fsnotify.Watcher
+	sourceDir string
+	targets   map[string]bool
+	debounce  time.Duration
+	changes   chan Change
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// New starts watching sourceDir and the targets, which must be absolute
+// paths. Targets are watched through their parent directories so that files
+// replaced by renaming, as editors do, are still noticed; targets whose
+// directory does not exist are not watched. It fails if the source
+// directory cannot be watched, e.g. because the limit of watches is
+// reached, in which case callers should fall back to polling.
+func New(sourceDir string, targets []string, debounce time.Duration) (*Watcher, error) {
+	fsw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create watcher: %w", err)
+	}
+
+	w := &Watcher{
+		fs:        fsw,
+		sourceDir: filepath.Clean(sourceDir),
+		targets:   make(map[string]bool, len(targets)),
+		debounce:  debounce,
+		changes:   make(chan Change),
+		done:      make(chan struct{}),
+	}
+	if err := w.addTree(w.sourceDir); err != nil {
+		fsw.Close()
+		return nil, err
+	}
+
+	dirs := make(map[string]bool)
+	for _, target := range targets {
+		target = filepath.Clean(target)
+		w.targets[target] = true
+
+		dir := filepath.Dir(target)
+		if dirs[dir] || w.inSource(dir) {
+			continue
+		}
+		dirs[dir] = true
+		if err := fsw.Add(dir); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			fsw.Close()
+			return nil, fmt.Errorf("failed to watch %s: %w", dir, err)
+		}
+	}
+
+	go w.run()
+	return w, nil
+}
+
+// Changes returns the channel changes are delivered on. It is closed when
+// the watcher is closed.
+func (w *Watcher) Changes() <-chan Change {
+	return w.changes
+}
+
+// Close stops watching
+func (w *Watcher) Close() error {
+	var err error
+	w.closeOnce.Do(func() {
+		close(w.done)
+		err = w.fs.Close()
+	})
+	return err
+}
+
+// run collects events until the debounce interval passes without any, then
+// delivers them as a single Change
+func (w *Watcher) run() {
+	defer close(w.changes)
+
+	var (
+		source  bool
+		targets = make(map[string]bool)
+	)
+	timer := time.NewTimer(w.debounce)
+	timer.Stop()
+
+	for {
+		select {
+		case <-w.done:
+			return
+
+		case event, ok := <-w.fs.Events:
+			if !ok {
+				return
+			}
+			path := filepath.Clean(event.Name)
+			switch {
+			case w.inSource(path):
+				if isGitPath(w.sourceDir, path) {
+					continue
+				}
+				source = true
+				if event.Has(fsnotify.Create) {
+					// Watch directories created in the source directory too.
+					// A failure only means that changes below it are missed.
+					if info, err := os.Stat(path); err == nil && info.IsDir() {
+						_ = w.addTree(path)
+					}
+				}
+			case w.targets[path]:
+				targets[path] = true
+			default:
+				continue
+			}
+			timer.Reset(w.debounce)
+
+		case _, ok := <-w.fs.Errors:
+			if !ok {
+				return
+			}
+			// Events may have been lost, e.g. because the event queue
+			// overflowed, so anything may have changed
+			source = true
+			timer.Reset(w.debounce)
+
+		case <-timer.C:
+			change := Change{Source: source}
+			for path := range targets {
+				change.Targets = append(change.Targets, path)
+			}
+			sort.Strings(change.Targets)
+
+			select {
+			case w.changes <- change:
+			case <-w.done:
+				return
+			}
+			source = false
+			targets = make(map[string]bool)
+		}
+	}
+}
+
+// addTree watches root and every directory below it, except for the git
+// repository of the source directory
+func (w *Watcher) addTree(root string) error {
+	return filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return fmt.Errorf("failed to watch %s: %w", path, err)
+		}
+		if !entry.IsDir() {
+			return nil
+		}
+		if entry.Name() == ".git" {
+			return filepath.SkipDir
+		}
+		if err := w.fs.Add(path); err != nil {
+			return fmt.Errorf("failed to watch %s: %w", path, err)
+		}
+		return nil
+	})
+}
+
+// inSource reports whether path is in the source directory
+func (w *Watcher) inSource(path string) bool {
+	rel, err := filepath.Rel(w.sourceDir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// isGitPath reports whether path, which is in sourceDir, belongs to its git
+// repository
+func isGitPath(sourceDir, path string) bool {
+	rel, err := filepath.Rel(sourceDir, path)
+	if err != nil {
+		return false
+	}
+	for _, part := range strings.Split(rel, string(filepath.Separator)) {
+		if part == ".git" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,126 @@
+package watch
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// newTestWatcher watches a new source directory and the given targets in a
+// new destination directory
+func newTestWatcher(t *testing.T, targets ...string) (*Watcher, string, string) {
+	t.Helper()
+
+	sourceDir, destDir := t.TempDir(), t.TempDir()
+	if err := os.Mkdir(filepath.Join(sourceDir, ".git"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	var paths []string
+	for _, target := range targets {
+		paths = append(paths, filepath.Join(destDir, target))
+	}
+
+	w, err := New(sourceDir, paths, 50*time.Millisecond)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	t.Cleanup(func() { w.Close() })
+	return w, sourceDir, destDir
+}
+
+// nextChange waits for the next change reported by w
+func nextChange(t *testing.T, w *Watcher) Change {
+	t.Helper()
+
+	select {
+	case change := <-w.Changes():
+		return change
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected a change to be reported")
+	}
+	return Change{}
+}
+
+// writeFile writes a file, failing the test on error
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWatchTargets(t *testing.T) {
+	w, _, destDir := newTestWatcher(t, ".bashrc", ".vimrc")
+
+	// A burst of writes is reported once, without unmanaged files
+	writeFile(t, filepath.Join(destDir, ".bashrc"), "one")
+	writeFile(t, filepath.Join(destDir, ".bashrc"), "two")
+	writeFile(t, filepath.Join(destDir, ".vimrc"), "set nu")
+	writeFile(t, filepath.Join(destDir, "notes.txt"), "unmanaged")
+
+	change := nextChange(t, w)
+	expected := Change{Targets: []string{filepath.Join(destDir, ".bashrc"), filepath.Join(destDir, ".vimrc")}}
+	if !reflect.DeepEqual(change, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, change)
+	}
+
+	// Replacing a file by renaming, as editors do, is noticed
+	tmp := filepath.Join(destDir, ".vimrc.tmp")
+	writeFile(t, tmp, "set nonu")
+	if err := os.Rename(tmp, filepath.Join(destDir, ".vimrc")); err != nil {
+		t.Fatal(err)
+	}
+	change = nextChange(t, w)
+	if change.Source || len(change.Targets) != 1 || change.Targets[0] != filepath.Join(destDir, ".vimrc") {
+		t.Errorf("Expected .vimrc to have changed, got %+v", change)
+	}
+}
+
+func TestWatchSource(t *testing.T) {
+	w, sourceDir, _ := newTestWatcher(t, ".bashrc")
+
+	// Changes to the git repository are ignored
+	writeFile(t, filepath.Join(sourceDir, ".git", "index"), "")
+	select {
+	case change := <-w.Changes():
+		t.Fatalf("Expected git changes to be ignored, got %+v", change)
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	// Directories created in the source directory are watched too
+	dir := filepath.Join(sourceDir, "dot_config")
+	if err := os.Mkdir(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if change := nextChange(t, w); !change.Source {
+		t.Errorf("Expected a source change, got %+v", change)
+	}
+	writeFile(t, filepath.Join(dir, "starship.toml"), "")
+	if change := nextChange(t, w); !change.Source {
+		t.Errorf("Expected a source change, got %+v", change)
+	}
+}
+
+func TestWatchMissingSource(t *testing.T) {
+	if _, err := New(filepath.Join(t.TempDir(), "missing"), nil, time.Millisecond); err == nil {
+		t.Error("Expected an error for a missing source directory")
+	}
+}
+
+func TestClose(t *testing.T) {
+	w, _, _ := newTestWatcher(t)
+	if err := w.Close(); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	select {
+	case _, ok := <-w.Changes():
+		if ok {
+			t.Error("Expected no change after closing")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the changes channel to be closed")
+	}
+}
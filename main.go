@@ -2,13 +2,21 @@ package main
 
 import (
 	_ "chezmoi-tui/pkg/commands"
+	"context"
 	"log"
+	"os"
+	"os/signal"
+	"syscall"
 
 	"chezmoi-tui/pkg/root"
 )
 
 func main() {
-	if err := root.RootCmd.Execute(); err != nil {
+	// Cancel in-flight chezmoi commands when the user interrupts us
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := root.RootCmd.ExecuteContext(ctx); err != nil {
 		log.Fatal(err)
 	}
 }
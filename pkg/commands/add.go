@@ -15,17 +15,17 @@ var addCmd = &cobra.Command{
 	Short: "Add targets to the source state",
 	Long:  `Add targets to the source state. If any target is already in the source state, then its source state is replaced with its current state in the destination directory.`,
 	Run: func(cmd *cobra.Command, args []string) {
-		c, err := chezmoi.New()
+		c, err := chezmoi.New(chezmoiOptions(appConfig())...)
 		if err != nil {
 			log.Fatalf("Failed to initialize chezmoi: %v", err)
 		}
 
-		output, err := c.Add(args...)
+		output, err := c.Add(cmd.Context(), args...)
 		if err != nil {
-			log.Fatalf("Failed to add: %v", err)
+			fatalChezmoi("add", err)
 		}
 
-		fmt.Print(output)
+		fmt.Fprint(cmd.OutOrStdout(), output)
 	},
 }
 
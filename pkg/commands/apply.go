@@ -1,7 +1,6 @@
 package commands
 
 import (
-	"fmt"
 	"log"
 
 	"github.com/spf13/cobra"
@@ -15,17 +14,15 @@ var applyCmd = &cobra.Command{
 	Short: "Update the destination directory to match the target state",
 	Long:  `Update the destination directory to match the target state, applying any changes.`,
 	Run: func(cmd *cobra.Command, args []string) {
-		c, err := chezmoi.New()
+		c, err := chezmoi.New(chezmoiOptions(appConfig())...)
 		if err != nil {
 			log.Fatalf("Failed to initialize chezmoi: %v", err)
 		}
 
-		output, err := c.Apply(args...)
+		err = c.ApplyStream(cmd.Context(), terminalStreams(cmd), chezmoi.ApplyOptions{}, args...)
 		if err != nil {
-			log.Fatalf("Failed to apply: %v", err)
+			fatalChezmoiStreamed("apply", err)
 		}
-
-		fmt.Print(output)
 	},
 }
 
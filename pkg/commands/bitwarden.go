@@ -1,18 +1,63 @@
 package commands
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"os"
 	"os/exec"
 	"strings"
-	"time"
+	"text/tabwriter"
 
 	"github.com/spf13/cobra"
 
+	"chezmoi-tui/internal/bitwarden"
+	"chezmoi-tui/internal/config"
+	"chezmoi-tui/internal/fsutil"
 	"chezmoi-tui/pkg/root"
 )
 
+// bitwardenSession returns the cached session key of the vault, which
+// expires once it has not been used for bitwarden.session_timeout and is
+// locked by the next command that asks for it
+func bitwardenSession(cfg *config.Config) *bitwarden.Session {
+	return bitwarden.NewSession(bitwarden.DefaultSessionPath(), cfg.SessionTimeout(), func(key string) error {
+		client, err := bitwarden.New(bitwarden.WithBinaryPath(cfg.Integration.BitwardenBinaryPath))
+		if err != nil {
+			return err
+		}
+		return client.LockSession(context.Background(), key)
+	})
+}
+
+// newBitwardenClient returns a client of the configured bw that uses the
+// cached session
+func newBitwardenClient(cfg *config.Config) (*bitwarden.Client, error) {
+	return bitwarden.New(
+		bitwarden.WithBinaryPath(cfg.Integration.BitwardenBinaryPath),
+		bitwarden.WithSession(bitwardenSession(cfg)),
+	)
+}
+
+// bitwardenClient returns the client of newBitwardenClient. It exits if bw is
+// not installed.
+func bitwardenClient() *bitwarden.Client {
+	client, err := newBitwardenClient(appConfig())
+	if err != nil {
+		fatalBitwarden("run the Bitwarden CLI", err)
+	}
+	return client
+}
+
+// fatalBitwarden reports a failed Bitwarden operation, with a remediation
+// hint when available, and exits
+func fatalBitwarden(action string, err error) {
+	if hint := bitwarden.Remediation(err); hint != "" {
+		log.Fatalf("Failed to %s: %v\nHint: %s", action, err, hint)
+	}
+	log.Fatalf("Failed to %s: %v", action, err)
+}
+
 var bitwardenCmd = &cobra.Command{
 	Use:   "bitwarden",
 	Short: "Interact with Bitwarden secrets management",
@@ -24,43 +69,68 @@ var bitwardenStatusCmd = &cobra.Command{
 	Short: "Show Bitwarden vault status",
 	Long:  `Show the current status of the Bitwarden vault`,
 	Run: func(cmd *cobra.Command, args []string) {
-		// Check if Bitwarden CLI is installed
-		_, err := exec.LookPath("bw")
+		client := bitwardenClient()
+		status, err := client.Status(cmd.Context())
 		if err != nil {
-			log.Fatalf("Bitwarden CLI (bw) not found. Please install it first.")
+			fatalBitwarden("check Bitwarden status", err)
 		}
 
-		// Check if vault is unlocked
-		cmdExec := exec.Command("bw", "status")
-		output, err := cmdExec.Output()
-		if err != nil {
-			log.Fatalf("Failed to check Bitwarden status: %v", err)
+		out := cmd.OutOrStdout()
+		w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "Bitwarden Vault Status:")
+		fmt.Fprintf(w, "  Status\t%s\n", status.Status)
+		if status.UserEmail != "" {
+			fmt.Fprintf(w, "  Account\t%s\n", status.UserEmail)
+		}
+		if status.ServerURL != "" {
+			fmt.Fprintf(w, "  Server\t%s\n", status.ServerURL)
+		}
+		if !status.LastSync.IsZero() {
+			fmt.Fprintf(w, "  Last sync\t%s\n", status.LastSync.Local().Format("2006-01-02 15:04"))
+		}
+		w.Flush()
+
+		session := client.Session()
+		switch key, _ := session.Peek(); {
+		case os.Getenv(bitwarden.SessionEnv) != "":
+			fmt.Fprintf(out, "\nSession: from the %s environment variable\n", bitwarden.SessionEnv)
+		case key == "":
+			fmt.Fprintln(out, "\nSession: none, unlock the vault with: chezmoi-tui bitwarden unlock")
+		case session.Timeout() > 0:
+			fmt.Fprintf(out, "\nSession: cached in %s, expires after %v without use\n", session.Path(), session.Timeout())
+		default:
+			fmt.Fprintf(out, "\nSession: cached in %s until the vault is locked\n", session.Path())
 		}
-
-		fmt.Printf("Bitwarden Vault Status:\n%s", output)
 	},
 }
 
 var bitwardenUnlockCmd = &cobra.Command{
 	Use:   "unlock",
 	Short: "Unlock the Bitwarden vault",
-	Long:  `Unlock the Bitwarden vault with your master password`,
-	Run: func(cmd *cobra.Command, args []string) {
-		// Check if Bitwarden CLI is installed
-		_, err := exec.LookPath("bw")
-		if err != nil {
-			log.Fatalf("Bitwarden CLI (bw) not found. Please install it first.")
-		}
+	Long: `Unlock the Bitwarden vault with your master password.
 
-		fmt.Println("Unlocking Bitwarden vault...")
-		cmdExec := exec.Command("bw", "unlock")
-		cmdExec.Stdin = os.Stdin
-		cmdExec.Stdout = os.Stdout
-		cmdExec.Stderr = os.Stderr
+The session key printed by bw unlock --raw is cached, readable only by you,
+and passed as BW_SESSION to the bw and chezmoi commands that chezmoi-tui
+runs, so that the bitwarden template functions can read the vault.
 
-		err = cmdExec.Run()
-		if err != nil {
-			log.Fatalf("Failed to unlock Bitwarden vault: %v", err)
+The session expires once it has not been used for bitwarden.session_timeout
+minutes. Nothing runs in the background to enforce this: the vault stays
+unlocked, and the key stays in the cache file, until the next chezmoi-tui
+command or a running TUI notices the expiry and locks the vault. Run
+chezmoi-tui bitwarden lock when you are done to lock it right away.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		client := bitwardenClient()
+
+		// bw prompts on stderr and prints only the session key on stdout
+		out := cmd.OutOrStdout()
+		fmt.Fprintln(out, "Unlocking Bitwarden vault...")
+		if _, err := client.Unlock(cmd.Context(), cmd.InOrStdin(), cmd.ErrOrStderr()); err != nil {
+			fatalBitwarden("unlock Bitwarden vault", err)
+		}
+		if timeout := client.Session().Timeout(); timeout > 0 {
+			fmt.Fprintf(out, "Bitwarden vault unlocked. The session expires after %v without use and is locked by the next chezmoi-tui command.\n", timeout)
+		} else {
+			fmt.Fprintln(out, "Bitwarden vault unlocked until chezmoi-tui bitwarden lock.")
 		}
 	},
 }
@@ -68,21 +138,13 @@ var bitwardenUnlockCmd = &cobra.Command{
 var bitwardenLockCmd = &cobra.Command{
 	Use:   "lock",
 	Short: "Lock the Bitwarden vault",
-	Long:  `Lock the Bitwarden vault`,
+	Long:  `Lock the Bitwarden vault and forget the cached session key`,
 	Run: func(cmd *cobra.Command, args []string) {
-		// Check if Bitwarden CLI is installed
-		_, err := exec.LookPath("bw")
-		if err != nil {
-			log.Fatalf("Bitwarden CLI (bw) not found. Please install it first.")
-		}
-
-		cmdExec := exec.Command("bw", "lock")
-		err = cmdExec.Run()
-		if err != nil {
-			log.Fatalf("Failed to lock Bitwarden vault: %v", err)
+		if err := bitwardenClient().Lock(cmd.Context()); err != nil {
+			fatalBitwarden("lock Bitwarden vault", err)
 		}
 
-		fmt.Println("Bitwarden vault locked successfully.")
+		fmt.Fprintln(cmd.OutOrStdout(), "Bitwarden vault locked successfully.")
 	},
 }
 
@@ -92,25 +154,31 @@ var bitwardenListCmd = &cobra.Command{
 	Long:  `List Bitwarden items, optionally filtered by name`,
 	Args:  cobra.MaximumNArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
-		// Check if Bitwarden CLI is installed
-		_, err := exec.LookPath("bw")
-		if err != nil {
-			log.Fatalf("Bitwarden CLI (bw) not found. Please install it first.")
-		}
-
-		// Build command
-		bwArgs := []string{"list", "items"}
+		var filter bitwarden.ItemFilter
 		if len(args) > 0 {
-			bwArgs = append(bwArgs, "--search", args[0])
+			filter.Search = args[0]
 		}
 
-		cmdExec := exec.Command("bw", bwArgs...)
-		output, err := cmdExec.Output()
+		items, err := bitwardenClient().Items(cmd.Context(), filter)
 		if err != nil {
-			log.Fatalf("Failed to list Bitwarden items: %v", err)
+			fatalBitwarden("list Bitwarden items", err)
 		}
 
-		fmt.Printf("Bitwarden Items:\n%s", output)
+		out := cmd.OutOrStdout()
+		if len(items) == 0 {
+			fmt.Fprintln(out, "No Bitwarden items found.")
+			return
+		}
+		w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "NAME\tTYPE\tUSERNAME\tID")
+		for _, item := range items {
+			username := ""
+			if item.Login != nil {
+				username = item.Login.Username
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", item.Name, item.Type, username, item.ID)
+		}
+		w.Flush()
 	},
 }
 
@@ -119,20 +187,13 @@ var bitwardenSyncCmd = &cobra.Command{
 	Short: "Sync Bitwarden vault",
 	Long:  `Sync the local Bitwarden vault with the remote server`,
 	Run: func(cmd *cobra.Command, args []string) {
-		// Check if Bitwarden CLI is installed
-		_, err := exec.LookPath("bw")
-		if err != nil {
-			log.Fatalf("Bitwarden CLI (bw) not found. Please install it first.")
+		out := cmd.OutOrStdout()
+		fmt.Fprintln(out, "Syncing Bitwarden vault...")
+		if err := bitwardenClient().Sync(cmd.Context()); err != nil {
+			fatalBitwarden("sync Bitwarden vault", err)
 		}
 
-		fmt.Println("Syncing Bitwarden vault...")
-		cmdExec := exec.Command("bw", "sync")
-		output, err := cmdExec.Output()
-		if err != nil {
-			log.Fatalf("Failed to sync Bitwarden vault: %v", err)
-		}
-
-		fmt.Printf("Sync completed:\n%s", output)
+		fmt.Fprintln(out, "Sync completed.")
 	},
 }
 
@@ -150,7 +211,7 @@ var bitwardenTuiCmd = &cobra.Command{
 
 		var foundPath string
 		for _, path := range bwTuiPaths {
-			expandedPath := os.ExpandEnv(strings.Replace(path, "~", os.Getenv("HOME"), -1))
+			expandedPath := fsutil.ExpandHome(path)
 			if _, err := os.Stat(expandedPath); err == nil {
 				foundPath = expandedPath
 				break
@@ -163,6 +224,7 @@ var bitwardenTuiCmd = &cobra.Command{
 			fmt.Printf("Launching Bitwarden TUI from %s...\n", dir)
 
 			cmdExec := exec.Command("/bin/bash", "-c", fmt.Sprintf("cd %s && ./run.sh", dir))
+			cmdExec.Env = append(os.Environ(), bitwardenSession(appConfig()).Env()...)
 			cmdExec.Stdin = os.Stdin
 			cmdExec.Stdout = os.Stdout
 			cmdExec.Stderr = os.Stderr
@@ -176,6 +238,7 @@ var bitwardenTuiCmd = &cobra.Command{
 			_, err := exec.LookPath("bw-secrets-tui")
 			if err == nil {
 				cmdExec := exec.Command("bw-secrets-tui")
+				cmdExec.Env = append(os.Environ(), bitwardenSession(appConfig()).Env()...)
 				cmdExec.Stdin = os.Stdin
 				cmdExec.Stdout = os.Stdout
 				cmdExec.Stderr = os.Stderr
@@ -205,126 +268,3 @@ func init() {
 	// Add the bitwarden command to the root
 	root.RootCmd.AddCommand(bitwardenCmd)
 }
-
-var bitwardenTemplateCmd = &cobra.Command{
-	Use:   "template [item-id]",
-	Short: "Generate Chezmoi template from Bitwarden item",
-	Long:  `Generate a Chezmoi template file from a Bitwarden item for secure secret management`,
-	Args:  cobra.ExactArgs(1),
-	Run: func(cmd *cobra.Command, args []string) {
-		// Check if Bitwarden CLI is installed
-		_, err := exec.LookPath("bw")
-		if err != nil {
-			log.Fatalf("Bitwarden CLI (bw) not found. Please install it first.")
-		}
-
-		itemID := args[0]
-		
-		// Get the item details (we'll use this later for actual processing)
-		_ = itemID // This is just to avoid the unused variable error for now
-
-		// Parse the JSON output to extract fields
-		// For simplicity, we'll just show how to create a template
-		templatePath := "~/.local/share/chezmoi/dot_secrets.tmpl"
-		expandedPath := os.ExpandEnv(strings.Replace(templatePath, "~", os.Getenv("HOME"), -1))
-		
-		// Create directory if it doesn't exist
-		dir := strings.Replace(expandedPath, "/dot_secrets.tmpl", "", -1)
-		err = os.MkdirAll(dir, 0755)
-		if err != nil {
-			log.Fatalf("Failed to create directory: %v", err)
-		}
-
-		// Write template file
-		file, err := os.Create(expandedPath)
-		if err != nil {
-			log.Fatalf("Failed to create template file: %v", err)
-		}
-		defer file.Close()
-
-		// Write template content
-		templateContent := fmt.Sprintf(`# Bitwarden Secrets Template
-# Generated from item ID: %s
-# This file is auto-generated - do not edit manually
-
-# Example template using Bitwarden integration
-{{- if (bitwarden "%s") }}
-export EXAMPLE_SECRET="{{ (bitwarden "%s").password }}"
-{{- end }}
-
-# You can add more secrets here as needed
-`, itemID, itemID, itemID)
-
-		_, err = file.WriteString(templateContent)
-		if err != nil {
-			log.Fatalf("Failed to write template file: %v", err)
-		}
-
-		fmt.Printf("Template generated at: %s\n", expandedPath)
-		fmt.Println("To apply with chezmoi, run: chezmoi apply")
-	},
-}
-
-var bitwardenExportCmd = &cobra.Command{
-	Use:   "export [filename]",
-	Short: "Export Bitwarden secrets to environment file",
-	Long:  `Export Bitwarden secrets to a .env file for use in development`,
-	Args:  cobra.MaximumNArgs(1),
-	Run: func(cmd *cobra.Command, args []string) {
-		// Check if Bitwarden CLI is installed
-		_, err := exec.LookPath("bw")
-		if err != nil {
-			log.Fatalf("Bitwarden CLI (bw) not found. Please install it first.")
-		}
-
-		// Default filename
-		filename := ".env"
-		if len(args) > 0 {
-			filename = args[0]
-		}
-
-		// Check if vault is unlocked
-		statusCmd := exec.Command("bw", "status")
-		statusOutput, err := statusCmd.Output()
-		if err != nil {
-			log.Fatalf("Failed to check Bitwarden status: %v", err)
-		}
-
-		if strings.Contains(string(statusOutput), "\"status\":\"unlocked\"") {
-			fmt.Println("Vault is unlocked. Proceeding with export...")
-		} else {
-			log.Fatalf("Vault is locked. Please unlock it first with: chezmoi-tui bitwarden unlock")
-		}
-
-		// For demonstration, we'll create a simple export
-		file, err := os.Create(filename)
-		if err != nil {
-			log.Fatalf("Failed to create export file: %v", err)
-		}
-		defer file.Close()
-
-		exportContent := `# Bitwarden Exported Secrets
-# This file is auto-generated - do not commit to version control
-# Last exported: %s
-
-# Example secrets - replace with actual values from Bitwarden
-EXAMPLE_API_KEY=your_api_key_here
-EXAMPLE_SECRET=your_secret_here
-`
-
-		timestamp := time.Now().Format("2006-01-02 15:04:05")
-		_, err = file.WriteString(fmt.Sprintf(exportContent, timestamp))
-		if err != nil {
-			log.Fatalf("Failed to write export file: %v", err)
-		}
-
-		// Set secure permissions
-		err = os.Chmod(filename, 0600)
-		if err != nil {
-			log.Printf("Warning: Failed to set secure permissions: %v", err)
-		}
-
-		fmt.Printf("Secrets exported to: %s\n", filename)
-		fmt.Println("Remember to add this file to your .gitignore!")
-	},
-}
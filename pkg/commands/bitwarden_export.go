@@ -0,0 +1,361 @@
+package commands
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"chezmoi-tui/internal/bitwarden"
+	"chezmoi-tui/internal/fsutil"
+)
+
+// defaultExportFields are the fields exported unless --fields is given
+var defaultExportFields = []string{"login.password", "fields.*"}
+
+// exportFormats are the formats secrets can be exported in
+var exportFormats = []string{"env", "shell", "direnv", "json"}
+
+// exportFormatFor returns the format implied by the name of the export
+// file: direnv for .envrc, json for .json, shell for .sh and env otherwise
+func exportFormatFor(filename string) string {
+	switch base := filepath.Base(filename); {
+	case base == ".envrc":
+		return "direnv"
+	case strings.HasSuffix(base, ".json"):
+		return "json"
+	case strings.HasSuffix(base, ".sh"):
+		return "shell"
+	}
+	return "env"
+}
+
+// exportVariable is a variable written by an export
+type exportVariable struct {
+	name  string
+	value string
+	// source names the item and field the value was read from
+	source string
+}
+
+// variableRules map the fields of items to variable names
+type variableRules struct {
+	// fields are the path.Match patterns of the selectors that are exported
+	fields []string
+	// prefix is prepended to every derived name
+	prefix string
+	// names maps item/selector, by item name or ID, to a variable name
+	names map[string]string
+}
+
+// upperVariable matches custom field names that are used as they are
+var upperVariable = regexp.MustCompile(`^[A-Z_][A-Z0-9_]*$`)
+
+// parseVariableRules parses the --fields patterns and the --map rules, given
+// as item/selector=VARIABLE
+func parseVariableRules(fields []string, prefix string, maps []string) (variableRules, error) {
+	rules := variableRules{fields: fields, prefix: prefix, names: make(map[string]string, len(maps))}
+	for _, pattern := range fields {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return rules, fmt.Errorf("invalid field pattern %q: %w", pattern, err)
+		}
+	}
+	for _, rule := range maps {
+		key, name, ok := strings.Cut(rule, "=")
+		if !ok || !strings.Contains(key, "/") {
+			return rules, fmt.Errorf("invalid mapping %q, expected item/selector=VARIABLE", rule)
+		}
+		if !bitwarden.ValidVariable(name) {
+			return rules, fmt.Errorf("%q is not a valid variable name", name)
+		}
+		rules.names[key] = name
+	}
+	return rules, nil
+}
+
+// variables returns the variables exported from the fields of an item that
+// match the rules. Custom fields named like environment variables, such as
+// API_KEY, keep their names; other fields are named after the item and the
+// field, e.g. GITHUB_PASSWORD.
+func (r variableRules) variables(item *bitwarden.Item) []exportVariable {
+	var vars []exportVariable
+	for _, field := range bitwarden.ItemFields(item) {
+		if !r.exports(field.Selector) {
+			continue
+		}
+		name, ok := r.names[item.Name+"/"+field.Selector]
+		if !ok {
+			name, ok = r.names[item.ID+"/"+field.Selector]
+		}
+		if !ok {
+			if custom, isCustom := strings.CutPrefix(field.Selector, "fields."); isCustom && upperVariable.MatchString(custom) {
+				name = r.prefix + custom
+			} else {
+				name = r.prefix + bitwarden.DefaultVariable(item.Name, field.Selector)
+			}
+		}
+		vars = append(vars, exportVariable{name: name, value: field.Value, source: item.Name + "/" + field.Selector})
+	}
+	return vars
+}
+
+// exports reports whether a field selector matches the field patterns
+func (r variableRules) exports(selector string) bool {
+	for _, pattern := range r.fields {
+		if ok, _ := path.Match(pattern, selector); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// checkExportVariables returns an error if two fields are exported as the
+// same variable
+func checkExportVariables(vars []exportVariable) error {
+	sources := make(map[string]string, len(vars))
+	for _, v := range vars {
+		if !bitwarden.ValidVariable(v.name) {
+			return fmt.Errorf("%s: %q is not a valid variable name, map it with --map", v.source, v.name)
+		}
+		if other, ok := sources[v.name]; ok {
+			return fmt.Errorf("%s and %s are both exported as %s, rename one with --map", other, v.source, v.name)
+		}
+		sources[v.name] = v.source
+	}
+	return nil
+}
+
+// safeValue matches values that need no quoting in any format
+var safeValue = regexp.MustCompile(`^[A-Za-z0-9_./:@%+,=-]*$`)
+
+// shellQuote quotes a value for POSIX shells. Nothing is expanded inside
+// single quotes, so only single quotes themselves need escaping.
+func shellQuote(value string) string {
+	if value != "" && safeValue.MatchString(value) {
+		return value
+	}
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
+
+// dotenvQuote quotes a value for dotenv files. Single quotes are literal in
+// dotenv parsers; values with single quotes or line breaks are double quoted
+// with backslash escapes instead.
+func dotenvQuote(value string) string {
+	switch {
+	case value != "" && safeValue.MatchString(value):
+		return value
+	case !strings.ContainsAny(value, "'\n\r"):
+		return "'" + value + "'"
+	}
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`, "$", `\$`).Replace(value) + `"`
+}
+
+// renderExport renders the variables in the given format
+func renderExport(vars []exportVariable, format string, now time.Time) ([]byte, error) {
+	if format == "json" {
+		values := make(map[string]string, len(vars))
+		for _, v := range vars {
+			values[v.name] = v.value
+		}
+		data, err := json.MarshalIndent(values, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		return append(data, '\n'), nil
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Exported from Bitwarden by chezmoi-tui on %s\n", now.Format("2006-01-02 15:04:05"))
+	b.WriteString("# Contains secrets, do not commit this file\n")
+	if format == "direnv" {
+		b.WriteString("# Review it and run direnv allow to load it\n")
+	}
+	for _, v := range vars {
+		switch format {
+		case "env":
+			fmt.Fprintf(&b, "%s=%s\n", v.name, dotenvQuote(v.value))
+		default:
+			fmt.Fprintf(&b, "export %s=%s\n", v.name, shellQuote(v.value))
+		}
+	}
+	return []byte(b.String()), nil
+}
+
+// checkNotInWorkTree returns an error if path is inside a git work tree and
+// not ignored, so that secrets are not committed by accident. Without git,
+// nothing can be committed and the check passes.
+func checkNotInWorkTree(path string) error {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	// git -C needs a directory that exists
+	dir := filepath.Dir(abs)
+	for {
+		if _, err := os.Stat(dir); err == nil || filepath.Dir(dir) == dir {
+			break
+		}
+		dir = filepath.Dir(dir)
+	}
+
+	if err := exec.Command("git", "-C", dir, "rev-parse", "--is-inside-work-tree").Run(); err != nil {
+		return nil
+	}
+	err = exec.Command("git", "-C", dir, "check-ignore", "-q", abs).Run()
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+		return nil
+	case errors.As(err, &exitErr) && exitErr.ExitCode() == 1:
+		return fmt.Errorf("%s is inside a git work tree and not ignored; add it to .gitignore first", path)
+	}
+	return fmt.Errorf("failed to check whether %s is ignored by git: %w", path, err)
+}
+
+var bitwardenExportCmd = &cobra.Command{
+	Use:   "export [filename]",
+	Short: "Export Bitwarden secrets to environment file",
+	Long: `Export fields of Bitwarden items to a .env file, shell exports, a direnv
+.envrc or JSON.
+
+Select items with --item, --folder and --collection, by ID or name. The
+fields matching --fields are exported, by default the password and the custom
+fields. Custom fields named like environment variables, such as API_KEY, keep
+their names; other fields are named after the item and the field, e.g.
+GITHUB_PASSWORD. Use --prefix and --map item/selector=VARIABLE to change the
+names.
+
+The file is written atomically with 0600 permissions. Inside a git work tree
+it must be ignored by git.`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		itemArgs, _ := cmd.Flags().GetStringArray("item")
+		folders, _ := cmd.Flags().GetStringArray("folder")
+		collections, _ := cmd.Flags().GetStringArray("collection")
+		fields, _ := cmd.Flags().GetStringSlice("fields")
+		prefix, _ := cmd.Flags().GetString("prefix")
+		maps, _ := cmd.Flags().GetStringArray("map")
+		format, _ := cmd.Flags().GetString("format")
+		if len(fields) == 0 {
+			fields = defaultExportFields
+		}
+		if len(itemArgs)+len(folders)+len(collections) == 0 {
+			log.Fatalf("Select what to export with --item, --folder or --collection")
+		}
+		rules, err := parseVariableRules(fields, prefix, maps)
+		if err != nil {
+			log.Fatalf("Failed to parse mapping rules: %v", err)
+		}
+
+		// Default filename
+		filename := appConfig().Bitwarden.ExportPath
+		if len(args) > 0 {
+			filename = args[0]
+		}
+		filename = fsutil.ExpandHome(filename)
+		if format == "" {
+			format = exportFormatFor(filename)
+		}
+		if !slices.Contains(exportFormats, format) {
+			log.Fatalf("Unknown format %q, use env, shell, direnv or json", format)
+		}
+		if err := checkNotInWorkTree(filename); err != nil {
+			log.Fatalf("Refusing to export: %v", err)
+		}
+
+		ctx := cmd.Context()
+		client := bitwardenClient()
+		if err := client.CheckUnlocked(ctx); err != nil {
+			fatalBitwarden("export", err)
+		}
+
+		// Collect the items once each, in the order they were selected
+		var items []bitwarden.Item
+		seen := make(map[string]bool)
+		add := func(found ...bitwarden.Item) {
+			for _, item := range found {
+				if !seen[item.ID] {
+					seen[item.ID] = true
+					items = append(items, item)
+				}
+			}
+		}
+		for _, arg := range itemArgs {
+			item, err := client.Item(ctx, arg)
+			if err != nil {
+				fatalBitwarden(fmt.Sprintf("get Bitwarden item %q", arg), err)
+			}
+			add(*item)
+		}
+		for _, name := range folders {
+			folder, err := client.FindFolder(ctx, name)
+			if err != nil {
+				fatalBitwarden("find folder", err)
+			}
+			found, err := client.Items(ctx, bitwarden.ItemFilter{FolderID: folder.ID})
+			if err != nil {
+				fatalBitwarden(fmt.Sprintf("list the items of folder %q", folder.Name), err)
+			}
+			add(found...)
+		}
+		for _, name := range collections {
+			collection, err := client.FindCollection(ctx, name)
+			if err != nil {
+				fatalBitwarden("find collection", err)
+			}
+			found, err := client.Items(ctx, bitwarden.ItemFilter{CollectionID: collection.ID})
+			if err != nil {
+				fatalBitwarden(fmt.Sprintf("list the items of collection %q", collection.Name), err)
+			}
+			add(found...)
+		}
+
+		var vars []exportVariable
+		for i := range items {
+			vars = append(vars, rules.variables(&items[i])...)
+		}
+		if len(vars) == 0 {
+			log.Fatalf("No fields of the %d selected item(s) match %s", len(items), strings.Join(fields, ","))
+		}
+		if err := checkExportVariables(vars); err != nil {
+			log.Fatalf("Failed to export: %v", err)
+		}
+
+		content, err := renderExport(vars, format, time.Now())
+		if err != nil {
+			log.Fatalf("Failed to render export: %v", err)
+		}
+		if err := os.MkdirAll(filepath.Dir(filename), 0o700); err != nil {
+			log.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := fsutil.WriteFileAtomic(filename, content, 0o600); err != nil {
+			log.Fatalf("Failed to write export file: %v", err)
+		}
+
+		out := cmd.OutOrStdout()
+		fmt.Fprintf(out, "Exported %d variable(s) from %d item(s) to: %s\n", len(vars), len(items), filename)
+		if format == "direnv" {
+			fmt.Fprintln(out, "Run direnv allow to load it.")
+		}
+	},
+}
+
+func init() {
+	bitwardenExportCmd.Flags().StringArray("item", nil, "Item to export, by ID or name (repeatable)")
+	bitwardenExportCmd.Flags().StringArray("folder", nil, "Folder whose items are exported, by ID or name (repeatable)")
+	bitwardenExportCmd.Flags().StringArray("collection", nil, "Collection whose items are exported, by ID or name (repeatable)")
+	bitwardenExportCmd.Flags().StringSlice("fields", nil, "Patterns of the fields to export, e.g. login.*,notes (default: login.password,fields.*)")
+	bitwardenExportCmd.Flags().String("prefix", "", "Prefix of the derived variable names")
+	bitwardenExportCmd.Flags().StringArray("map", nil, "Variable of a field, as item/selector=VARIABLE (repeatable)")
+	bitwardenExportCmd.Flags().String("format", "", "Output format: env, shell, direnv or json (default: from the file name)")
+}
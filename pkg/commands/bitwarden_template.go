@@ -0,0 +1,152 @@
+package commands
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"chezmoi-tui/internal/bitwarden"
+	"chezmoi-tui/internal/fsutil"
+)
+
+// mapFields maps the fields named by the --field flags, as selector=VAR, to
+// their variables. A selector without a variable gets the default name.
+func mapFields(item *bitwarden.Item, fields []bitwarden.ItemField, flags []string) ([]bitwarden.TemplateVariable, error) {
+	bySelector := make(map[string]bitwarden.ItemField, len(fields))
+	for _, field := range fields {
+		bySelector[field.Selector] = field
+	}
+
+	var vars []bitwarden.TemplateVariable
+	for _, flag := range flags {
+		selector, name, ok := strings.Cut(flag, "=")
+		field, found := bySelector[selector]
+		if !found {
+			return nil, fmt.Errorf("item %q has no field %s", item.Name, selector)
+		}
+		if !ok {
+			name = bitwarden.DefaultVariable(item.Name, selector)
+		}
+		vars = append(vars, bitwarden.TemplateVariable{Name: name, Field: field})
+	}
+	return vars, nil
+}
+
+// promptFields asks which fields to assign to which variables. An empty
+// answer keeps the suggested name and "-" skips the field.
+func promptFields(in *bufio.Reader, out io.Writer, item *bitwarden.Item, fields []bitwarden.ItemField) []bitwarden.TemplateVariable {
+	fmt.Fprintf(out, "Fields of %q. Press enter to keep the suggested variable, or type - to skip a field.\n", item.Name)
+	var vars []bitwarden.TemplateVariable
+	for _, field := range fields {
+		name := bitwarden.DefaultVariable(item.Name, field.Selector)
+		answer := prompt(in, out, fmt.Sprintf("  %s [%s]: ", field.Selector, name))
+		switch answer {
+		case "-":
+			continue
+		case "":
+		default:
+			name = answer
+		}
+		vars = append(vars, bitwarden.TemplateVariable{Name: name, Field: field})
+	}
+	return vars
+}
+
+// prompt prints a question and returns the trimmed answer, or an empty
+// answer at the end of the input
+func prompt(in *bufio.Reader, out io.Writer, question string) string {
+	fmt.Fprint(out, question)
+	line, err := in.ReadString('\n')
+	if err != nil && line == "" {
+		fmt.Fprintln(out)
+	}
+	return strings.TrimSpace(line)
+}
+
+var bitwardenTemplateCmd = &cobra.Command{
+	Use:   "template <item>",
+	Short: "Generate Chezmoi template from Bitwarden item",
+	Long: `Generate a chezmoi template that reads fields of a Bitwarden item with the
+bitwarden and bitwardenFields template functions.
+
+The item is fetched with bw get item, by ID or by a name that matches a single
+item. Choose fields with --field selector=VARIABLE, where the selector is
+login.username, login.password, login.totp, notes or fields.<name>. Without
+--field, every field of the item is offered interactively.
+
+An existing template is never overwritten unless --force is given; use
+--append to add the variables to it.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		fieldFlags, _ := cmd.Flags().GetStringArray("field")
+		output, _ := cmd.Flags().GetString("output")
+		format, _ := cmd.Flags().GetString("format")
+		appendTo, _ := cmd.Flags().GetBool("append")
+		force, _ := cmd.Flags().GetBool("force")
+		if output == "" {
+			output = appConfig().Bitwarden.TemplatePath
+		}
+		if format != bitwarden.FormatShell && format != bitwarden.FormatEnv {
+			log.Fatalf("Unknown format %q, use shell or env", format)
+		}
+		if appendTo && force {
+			log.Fatalf("--append and --force cannot be combined")
+		}
+
+		item, err := bitwardenClient().Item(cmd.Context(), args[0])
+		if err != nil {
+			fatalBitwarden("get Bitwarden item", err)
+		}
+		fields := bitwarden.ItemFields(item)
+		if len(fields) == 0 {
+			log.Fatalf("Item %q has no login, notes or custom fields", item.Name)
+		}
+
+		out := cmd.OutOrStdout()
+		var vars []bitwarden.TemplateVariable
+		if len(fieldFlags) > 0 {
+			if vars, err = mapFields(item, fields, fieldFlags); err != nil {
+				log.Fatalf("Failed to map fields: %v", err)
+			}
+		} else {
+			in := bufio.NewReader(cmd.InOrStdin())
+			vars = promptFields(in, out, item, fields)
+			if !cmd.Flags().Changed("output") {
+				if answer := prompt(in, out, fmt.Sprintf("Source path [%s]: ", output)); answer != "" {
+					output = answer
+				}
+			}
+		}
+
+		path := fsutil.ExpandHome(output)
+		mode := bitwarden.TemplateCreate
+		switch {
+		case appendTo:
+			mode = bitwarden.TemplateAppend
+		case force:
+			mode = bitwarden.TemplateReplace
+		}
+		if _, err := os.Stat(path); err == nil && mode == bitwarden.TemplateCreate {
+			log.Fatalf("%s already exists. Use --append to add to it or --force to replace it.", path)
+		}
+		if err := bitwarden.WriteTemplate(path, item, vars, format, mode); err != nil {
+			log.Fatalf("Failed to generate template: %v", err)
+		}
+
+		fmt.Fprintf(out, "Template with %d variable(s) written to: %s\n", len(vars), path)
+		fmt.Fprintln(out, "To apply with chezmoi, run: chezmoi apply")
+	},
+}
+
+func init() {
+	bitwardenTemplateCmd.Flags().StringArray("field", nil, "Field to assign, as selector=VARIABLE (repeatable)")
+	bitwardenTemplateCmd.Flags().StringP("output", "o", "", "Source path of the template (default: bitwarden.template_path)")
+	bitwardenTemplateCmd.Flags().String("format", "shell", "Assignment format: shell (export VAR='...') or env (VAR=\"...\")")
+	bitwardenTemplateCmd.Flags().Bool("append", false, "Add the variables to an existing template")
+	bitwardenTemplateCmd.Flags().BoolP("force", "f", false, "Replace an existing template")
+}
@@ -0,0 +1,295 @@
+package commands
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+	"text/template"
+
+	"chezmoi-tui/internal/chezmoi/chezmoitest"
+	"chezmoi-tui/pkg/root"
+)
+
+// githubItem is what the fake bw prints for bw get item
+const githubItem = `{
+	"id": "5f3c0b8e-0000-4000-8000-000000000001",
+	"name": "GitHub",
+	"notes": "",
+	"login": {"username": "octocat", "password": "it's a secret", "totp": null},
+	"fields": [
+		{"name": "token", "value": "ghp_123", "type": 1},
+		{"name": "api key", "value": "k-456", "type": 0}
+	]
+}`
+
+// fakeBitwarden installs a fake bw that runs script with the arguments of
+// bw in "$@"
+func fakeBitwarden(t *testing.T, script string) {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "bw")
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("CHEZMOI_TUI_INTEGRATION_BITWARDEN_BINARY_PATH", path)
+}
+
+// withStdin feeds input to the standard input of the next command
+func withStdin(t *testing.T, input string) {
+	t.Helper()
+
+	root.RootCmd.SetIn(strings.NewReader(input))
+	t.Cleanup(func() { root.RootCmd.SetIn(nil) })
+}
+
+// executeTemplate executes a generated template with stand-ins for the
+// chezmoi template functions that read githubItem
+func executeTemplate(t *testing.T, content string) string {
+	t.Helper()
+
+	funcs := template.FuncMap{
+		"bitwarden": func(args ...string) map[string]any {
+			return map[string]any{"notes": "", "login": map[string]any{"username": "octocat", "password": "it's a secret"}}
+		},
+		"bitwardenFields": func(args ...string) map[string]any {
+			return map[string]any{"token": map[string]any{"value": "ghp_123"}, "api key": map[string]any{"value": "k-456"}}
+		},
+		"replace": func(old, new, s string) string { return strings.ReplaceAll(s, old, new) },
+		"squote":  func(s string) string { return "'" + s + "'" },
+		"quote":   func(s string) string { return `"` + s + `"` },
+	}
+	tmpl, err := template.New("secrets").Funcs(funcs).Parse(content)
+	if err != nil {
+		t.Fatalf("Generated template does not parse: %v\n%s", err, content)
+	}
+	var b strings.Builder
+	if err := tmpl.Execute(&b, nil); err != nil {
+		t.Fatalf("Generated template fails: %v\n%s", err, content)
+	}
+	return b.String()
+}
+
+func TestBitwardenTemplateFields(t *testing.T) {
+	fakeBitwarden(t, `[ "$1 $2 $3" = "get item GitHub" ] && cat <<'EOF'
+`+githubItem+`
+EOF
+`)
+	path := filepath.Join(t.TempDir(), "source", "private_dot_secrets.tmpl")
+
+	runCommand(t, chezmoitest.NewFake(), "bitwarden", "template", "GitHub", "-o", path,
+		"--field", "login.password", "--field", "fields.api key=API_KEY", "--field", "fields.token=GH_TOKEN")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Expected the template to be written: %v", err)
+	}
+	for _, expected := range []string{
+		`export GITHUB_PASSWORD={{ (bitwarden "item" "5f3c0b8e-0000-4000-8000-000000000001").login.password | replace`,
+		`export API_KEY={{ (index (bitwardenFields "item" "5f3c0b8e-0000-4000-8000-000000000001") "api key").value |`,
+		`export GH_TOKEN={{ (bitwardenFields "item" "5f3c0b8e-0000-4000-8000-000000000001").token.value |`,
+	} {
+		if !strings.Contains(string(data), expected) {
+			t.Errorf("Expected the template to contain %q, got:\n%s", expected, data)
+		}
+	}
+
+	rendered := executeTemplate(t, string(data))
+	for _, expected := range []string{`export GITHUB_PASSWORD='it'\''s a secret'`, "export API_KEY='k-456'", "export GH_TOKEN='ghp_123'"} {
+		if !strings.Contains(rendered, expected) {
+			t.Errorf("Expected the rendered template to contain %q, got:\n%s", expected, rendered)
+		}
+	}
+}
+
+func TestBitwardenTemplatePrompt(t *testing.T) {
+	fakeBitwarden(t, "cat <<'EOF'\n"+githubItem+"\nEOF\n")
+	path := filepath.Join(t.TempDir(), "dot_env.tmpl")
+
+	// Keep the username, skip the password, rename the token and skip the
+	// api key, then confirm the source path
+	withStdin(t, "\n-\nGH_TOKEN\n-\n"+path+"\n")
+	output := runCommand(t, chezmoitest.NewFake(), "bitwarden", "template", "GitHub", "--format", "env")
+	if !strings.Contains(output, "login.username [GITHUB_USERNAME]") {
+		t.Errorf("Expected the fields to be offered, got:\n%s", output)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Expected the template to be written: %v", err)
+	}
+	rendered := executeTemplate(t, string(data))
+	if !strings.Contains(rendered, "GITHUB_USERNAME=\"octocat\"\nGH_TOKEN=\"ghp_123\"\n") || strings.Contains(rendered, "PASSWORD") {
+		t.Errorf("Expected the chosen fields only, got:\n%s", rendered)
+	}
+}
+
+func TestBitwardenTemplateAppends(t *testing.T) {
+	fakeBitwarden(t, "cat <<'EOF'\n"+githubItem+"\nEOF\n")
+	path := filepath.Join(t.TempDir(), "dot_secrets.tmpl")
+	if err := os.WriteFile(path, []byte("export EDITOR=nvim\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	runCommand(t, chezmoitest.NewFake(), "bitwarden", "template", "GitHub", "-o", path, "--append", "--field", "fields.token=GH_TOKEN")
+	data, _ := os.ReadFile(path)
+	if !strings.HasPrefix(string(data), "export EDITOR=nvim\n\n# Bitwarden item \"GitHub\"") || !strings.Contains(string(data), "GH_TOKEN=") {
+		t.Errorf("Expected the variables to be appended, got:\n%s", data)
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0o600 {
+		t.Errorf("Expected the permissions to be kept, got %v", info.Mode())
+	}
+}
+
+// vaultScript is a fake bw with an unlocked vault holding githubItem in the
+// folder "work"
+const vaultScript = `case "$1 $2" in
+"status ") echo '{"status":"unlocked"}' ;;
+"get item") cat <<'EOF'
+` + githubItem + `
+EOF
+;;
+"list folders") echo '[{"id":"f1","name":"Work"},{"id":"f2","name":"Workshop"}]' ;;
+"list items") [ "$3 $4" = "--folderid f1" ] && cat <<'EOF'
+[` + githubItem + `, {"id": "i2", "name": "AWS", "notes": "line one\nline two", "login": null,
+  "fields": [{"name": "AWS_ACCESS_KEY_ID", "value": "AKIA123"}, {"name": "secret key", "value": "a\"b$c"}]}]
+EOF
+;;
+*) echo "unexpected bw $*" >&2; exit 1 ;;
+esac
+`
+
+func TestBitwardenExportFormats(t *testing.T) {
+	fakeBitwarden(t, vaultScript)
+	dir := t.TempDir()
+
+	env := filepath.Join(dir, "app.env")
+	output := runCommand(t, chezmoitest.NewFake(), "bitwarden", "export", env, "--folder", "work", "--item", "GitHub")
+	if !strings.Contains(output, "Exported 5 variable(s) from 2 item(s)") {
+		t.Errorf("Expected the items to be exported once each, got %q", output)
+	}
+	data, _ := os.ReadFile(env)
+	for _, expected := range []string{
+		"GITHUB_PASSWORD=\"it's a secret\"\n",
+		"GITHUB_API_KEY=k-456\n",
+		"AWS_ACCESS_KEY_ID=AKIA123\n",
+		"AWS_SECRET_KEY='a\"b$c'\n",
+	} {
+		if !strings.Contains(string(data), expected) {
+			t.Errorf("Expected the export to contain %q, got:\n%s", expected, data)
+		}
+	}
+	if info, _ := os.Stat(env); info.Mode().Perm() != 0o600 {
+		t.Errorf("Expected 0600 permissions, got %v", info.Mode())
+	}
+
+	envrc := filepath.Join(dir, ".envrc")
+	runCommand(t, chezmoitest.NewFake(), "bitwarden", "export", envrc, "--folder", "f1",
+		"--fields", "notes,fields.*", "--map", "AWS/fields.secret key=AWS_SECRET_ACCESS_KEY", "--prefix", "X_")
+	data, _ = os.ReadFile(envrc)
+	for _, expected := range []string{
+		"export X_AWS_NOTES='line one\nline two'\n",
+		"export X_AWS_ACCESS_KEY_ID=AKIA123\n",
+		"export AWS_SECRET_ACCESS_KEY='a\"b$c'\n",
+		"direnv allow",
+	} {
+		if !strings.Contains(string(data), expected) {
+			t.Errorf("Expected the .envrc to contain %q, got:\n%s", expected, data)
+		}
+	}
+
+	jsonPath := filepath.Join(dir, "secrets.json")
+	runCommand(t, chezmoitest.NewFake(), "bitwarden", "export", jsonPath, "--item", "GitHub", "--fields", "login.*")
+	data, _ = os.ReadFile(jsonPath)
+	if string(data) != "{\n  \"GITHUB_PASSWORD\": \"it's a secret\",\n  \"GITHUB_USERNAME\": \"octocat\"\n}\n" {
+		t.Errorf("Unexpected JSON export:\n%s", data)
+	}
+}
+
+func TestQuoting(t *testing.T) {
+	for _, tc := range []struct{ value, shell, dotenv string }{
+		{"plain-value_1", "plain-value_1", "plain-value_1"},
+		{"", "''", "''"},
+		{"with space", "'with space'", "'with space'"},
+		{"it's", `'it'\''s'`, `"it's"`},
+		{"$HOME `x`", "'$HOME `x`'", "'$HOME `x`'"},
+		{"a\nb\\\"$", "'a\nb\\\"$'", `"a\nb\\\"\$"`},
+	} {
+		if got := shellQuote(tc.value); got != tc.shell {
+			t.Errorf("shellQuote(%q) = %s, expected %s", tc.value, got, tc.shell)
+		}
+		if got := dotenvQuote(tc.value); got != tc.dotenv {
+			t.Errorf("dotenvQuote(%q) = %s, expected %s", tc.value, got, tc.dotenv)
+		}
+	}
+}
+
+func TestCheckNotInWorkTree(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	repo := t.TempDir()
+	if output, err := exec.Command("git", "init", "-q", repo).CombinedOutput(); err != nil {
+		t.Fatalf("git init failed: %v\n%s", err, output)
+	}
+
+	if err := checkNotInWorkTree(filepath.Join(repo, "config", ".env")); err == nil {
+		t.Error("Expected a file in a work tree to be refused")
+	}
+	if err := os.WriteFile(filepath.Join(repo, ".gitignore"), []byte(".env\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := checkNotInWorkTree(filepath.Join(repo, "config", ".env")); err != nil {
+		t.Errorf("Expected an ignored file to be allowed, got: %v", err)
+	}
+	if err := checkNotInWorkTree(filepath.Join(t.TempDir(), ".env")); err != nil {
+		t.Errorf("Expected a file outside of work trees to be allowed, got: %v", err)
+	}
+}
+
+func TestBitwardenSession(t *testing.T) {
+	t.Setenv("BW_SESSION", "")
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	calls := filepath.Join(t.TempDir(), "calls")
+	t.Setenv("BW_CALLS", calls)
+	fakeBitwarden(t, `echo "$* BW_SESSION=$BW_SESSION" >> "$BW_CALLS"
+case "$1 $2" in
+"unlock --raw") echo "session-key" ;;
+"list items") [ "$BW_SESSION" = "session-key" ] || { echo "Vault is locked." >&2; exit 1; }
+	echo '[{"id":"i1","type":1,"name":"GitHub","login":{"username":"octocat"}}]' ;;
+esac
+`)
+	cache := filepath.Join(os.Getenv("XDG_CACHE_HOME"), "chezmoi-tui", "bw-session")
+
+	output := runCommand(t, chezmoitest.NewFake(), "bitwarden", "unlock")
+	if !strings.Contains(output, "expires after 30m0s without use and is locked by the next chezmoi-tui command") {
+		t.Errorf("Expected the idle timeout to be reported, got %q", output)
+	}
+	if info, err := os.Stat(cache); err != nil || info.Mode().Perm() != 0o600 {
+		t.Fatalf("Expected the session key to be cached with 0600 permissions, got %v", err)
+	}
+
+	output = runCommand(t, chezmoitest.NewFake(), "bitwarden", "list")
+	if !regexp.MustCompile(`GitHub +login +octocat +i1`).MatchString(output) {
+		t.Errorf("Expected the items to be listed, got:\n%s", output)
+	}
+	fake := chezmoitest.NewFake()
+	fake.On("status")
+	runCommand(t, fake, "status")
+	if calls := fake.Calls(); !slices.Contains(calls[len(calls)-1].Env, "BW_SESSION=session-key") {
+		t.Errorf("Expected chezmoi to receive the session key, got %v", calls[len(calls)-1].Env)
+	}
+
+	runCommand(t, chezmoitest.NewFake(), "bitwarden", "lock")
+	if _, err := os.Stat(cache); !os.IsNotExist(err) {
+		t.Errorf("Expected the cached session to be removed, got %v", err)
+	}
+
+	data, _ := os.ReadFile(calls)
+	expected := "unlock --raw BW_SESSION=\nlist items BW_SESSION=session-key\nlock BW_SESSION=session-key\n"
+	if string(data) != expected {
+		t.Errorf("Expected bw to receive the session key, got:\n%s", data)
+	}
+}
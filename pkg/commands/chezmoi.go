@@ -0,0 +1,73 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"chezmoi-tui/internal/chezmoi"
+	"chezmoi-tui/internal/config"
+	"chezmoi-tui/pkg/root"
+)
+
+// chezmoiExecutor overrides how chezmoi processes are started. It is nil,
+// meaning the real chezmoi binary, except in tests.
+var chezmoiExecutor chezmoi.Executor
+
+// terminalStreams connects a streaming chezmoi command to the command's
+// standard streams so that its output appears as it is produced and it can
+// prompt
+func terminalStreams(cmd *cobra.Command) chezmoi.IOStreams {
+	return chezmoi.IOStreams{In: cmd.InOrStdin(), Out: cmd.OutOrStdout(), Err: cmd.ErrOrStderr()}
+}
+
+// chezmoiOptions returns the options used to construct chezmoi clients,
+// combining the configuration with the global chezmoi command line flags
+func chezmoiOptions(cfg *config.Config) []chezmoi.Option {
+	flags := root.ChezmoiFlags
+
+	opts := []chezmoi.Option{
+		chezmoi.WithTimeout(cfg.Timeout()),
+		chezmoi.WithBinaryPath(cfg.Integration.ChezmoiBinaryPath),
+		chezmoi.WithSourceDir(flags.Source),
+		chezmoi.WithDestDir(flags.Destination),
+		chezmoi.WithConfigFile(flags.Config),
+		chezmoi.WithCacheDir(flags.Cache),
+		// Let the bitwarden template functions read an unlocked vault
+		chezmoi.WithEnv(bitwardenSession(cfg).Env),
+	}
+	if cfg.CLI.Verbose {
+		opts = append(opts, chezmoi.WithGlobalArgs("--verbose"))
+	}
+	opts = append(opts, chezmoi.WithGlobalArgs(flags.Extra...))
+	if chezmoiExecutor != nil {
+		opts = append(opts, chezmoi.WithExecutor(chezmoiExecutor))
+	}
+	return opts
+}
+
+// fatalChezmoi reports a failed chezmoi operation and exits. The full stderr
+// of the failed command and a remediation hint are printed when available.
+func fatalChezmoi(action string, err error) {
+	var execErr *chezmoi.ExecError
+	if errors.As(err, &execErr) {
+		if stderr := strings.TrimSpace(execErr.Stderr); strings.Contains(stderr, "\n") {
+			fmt.Fprintln(os.Stderr, stderr)
+		}
+	}
+
+	fatalChezmoiStreamed(action, err)
+}
+
+// fatalChezmoiStreamed reports a failed chezmoi operation whose stderr has
+// already been streamed to the terminal and exits
+func fatalChezmoiStreamed(action string, err error) {
+	if hint := chezmoi.Remediation(err); hint != "" {
+		log.Fatalf("Failed to %s: %v\nHint: %s", action, err, hint)
+	}
+	log.Fatalf("Failed to %s: %v", action, err)
+}
@@ -0,0 +1,222 @@
+package commands
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"chezmoi-tui/internal/chezmoi/chezmoitest"
+	"chezmoi-tui/internal/config"
+	"chezmoi-tui/pkg/root"
+)
+
+// runCommand executes the root command with args against a fake chezmoi and
+// returns its standard output
+func runCommand(t *testing.T, fake *chezmoitest.Fake, args ...string) string {
+	t.Helper()
+
+	t.Setenv("HOME", t.TempDir())
+	chezmoiExecutor = fake
+	t.Cleanup(func() { chezmoiExecutor = nil })
+
+	// Flags keep their values between commands run by the same test
+	resetFlags()
+	var out bytes.Buffer
+	root.RootCmd.SetOut(&out)
+	root.RootCmd.SetErr(&out)
+	root.RootCmd.SetArgs(args)
+	t.Cleanup(func() {
+		resetFlags()
+		root.RootCmd.SetOut(nil)
+		root.RootCmd.SetErr(nil)
+		root.RootCmd.SetArgs(nil)
+	})
+
+	if err := root.RootCmd.Execute(); err != nil {
+		t.Fatalf("Command %v failed: %v", args, err)
+	}
+	return out.String()
+}
+
+// resetFlags restores the global flags and the flags of every command to
+// their defaults between commands
+func resetFlags() {
+	reset := func(f *pflag.Flag) {
+		if slice, ok := f.Value.(pflag.SliceValue); ok {
+			slice.Replace(nil)
+		} else {
+			f.Value.Set(f.DefValue)
+		}
+		f.Changed = false
+	}
+
+	var visit func(cmd *cobra.Command)
+	visit = func(cmd *cobra.Command) {
+		cmd.PersistentFlags().VisitAll(reset)
+		cmd.Flags().VisitAll(reset)
+		for _, child := range cmd.Commands() {
+			visit(child)
+		}
+	}
+	visit(root.RootCmd)
+}
+
+func TestStatusCommand(t *testing.T) {
+	fake := chezmoitest.NewFake()
+	fake.On("status").Stdout(" M .bashrc\n")
+
+	output := runCommand(t, fake, "status")
+	if output != " M .bashrc\n" {
+		t.Errorf("Expected chezmoi status output, got %q", output)
+	}
+}
+
+func TestAddCommand(t *testing.T) {
+	fake := chezmoitest.NewFake()
+	fake.On("add")
+
+	runCommand(t, fake, "add", "/home/user/.bashrc", "/home/user/.vimrc")
+	if fake.CallCount("add", "/home/user/.bashrc", "/home/user/.vimrc") != 1 {
+		t.Errorf("Expected add to be called with both targets, got %+v", fake.Calls())
+	}
+}
+
+func TestApplyCommandStreamsOutput(t *testing.T) {
+	fake := chezmoitest.NewFake()
+	fake.On("apply").Stdout("applied .bashrc\n")
+
+	output := runCommand(t, fake, "apply", "/home/user/.bashrc")
+	if output != "applied .bashrc\n" {
+		t.Errorf("Expected streamed apply output, got %q", output)
+	}
+}
+
+func TestInitCommand(t *testing.T) {
+	fake := chezmoitest.NewFake()
+	fake.On("init")
+
+	runCommand(t, fake, "init", "user/dotfiles", "--apply")
+	if fake.CallCount("init", "user/dotfiles", "--apply") != 1 {
+		t.Errorf("Expected init with repo and --apply, got %+v", fake.Calls())
+	}
+}
+
+func TestStatsCommand(t *testing.T) {
+	fake := chezmoitest.NewFake()
+	fake.On("status").Stdout(" M .bashrc\n R install.sh\n")
+	fake.On("managed").Stdout(".bashrc\x00.vimrc\x00install.sh\x00")
+	fake.On("unmanaged").Stdout("")
+
+	output := runCommand(t, fake, "stats", "--details")
+	for _, expected := range []string{
+		"Total Managed Files:      3",
+		"Modified:                 1",
+		"Scripts to Run:           1",
+		"Managed files (3)",
+	} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected output to contain %q, got:\n%s", expected, output)
+		}
+	}
+}
+
+func TestChezmoiGlobalFlags(t *testing.T) {
+	fake := chezmoitest.NewFake()
+	fake.On("--source", "/work/dotfiles", "--destination", "/tmp/scratch", "status").Stdout(" M .bashrc\n")
+
+	output := runCommand(t, fake, "--source", "/work/dotfiles", "--destination", "/tmp/scratch", "status")
+	if output != " M .bashrc\n" {
+		t.Errorf("Expected status to run against the given directories, got %q (calls: %+v)", output, fake.Calls())
+	}
+}
+
+func TestVersionCommand(t *testing.T) {
+	fake := chezmoitest.NewFake()
+
+	output := runCommand(t, fake, "version")
+	expected := "chezmoi-tui version " + Version + "\nchezmoi version 2.52.1 (chezmoi)\n"
+	if output != expected {
+		t.Errorf("Expected %q, got %q", expected, output)
+	}
+
+	fake.On("--version").Stdout("chezmoi version v1.8.11\n")
+	output = runCommand(t, fake, "version")
+	if !strings.Contains(output, "chezmoi version 1.8.11 (unsupported, 2.9.0 or later is required)") {
+		t.Errorf("Expected the unsupported version to be reported, got %q", output)
+	}
+}
+
+func TestConfigFileApplied(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("cli:\n  verbose: true\n  pager: \"\"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	fake := chezmoitest.NewFake()
+	fake.On("--verbose", "status").Stdout(" M .bashrc\n")
+
+	output := runCommand(t, fake, "--tui-config", path, "status")
+	if output != " M .bashrc\n" {
+		t.Errorf("Expected cli.verbose to pass --verbose to chezmoi, got %q (calls: %+v)", output, fake.Calls())
+	}
+}
+
+func TestConfigGenerate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "config.yaml")
+
+	output := runCommand(t, chezmoitest.NewFake(), "--tui-config", path, "config", "generate")
+	if !strings.Contains(output, path) {
+		t.Errorf("Expected the generated path to be reported, got %q", output)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Expected the config file to be written: %v", err)
+	}
+	if string(data) != config.Template {
+		t.Errorf("Expected the default template, got:\n%s", data)
+	}
+}
+
+func TestConfigShowGetSet(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("# my settings\ntui:\n  refresh_interval: 10\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("CHEZMOI_TUI_CLI_PAGER", "more")
+
+	output := runCommand(t, chezmoitest.NewFake(), "--tui-config", path, "--timeout", "5", "config", "show")
+	if !strings.Contains(output, "Config file: "+path) {
+		t.Errorf("Expected the config file in:\n%s", output)
+	}
+	rows := []struct{ key, value, source string }{
+		{"tui.refresh_interval", "10", "file"},
+		{"cli.pager", "more", "env"},
+		{"integration.timeout", "5", "flag"},
+		{"tui.max_file_display", "100", "default"},
+	}
+	for _, row := range rows {
+		pattern := `(?m)^` + regexp.QuoteMeta(row.key) + `\s+` + row.value + `\s+` + row.source + `$`
+		if !regexp.MustCompile(pattern).MatchString(output) {
+			t.Errorf("Expected %s = %s from %s in:\n%s", row.key, row.value, row.source, output)
+		}
+	}
+
+	runCommand(t, chezmoitest.NewFake(), "--tui-config", path, "config", "set", "tui.refresh_interval", "20")
+	if output := runCommand(t, chezmoitest.NewFake(), "--tui-config", path, "config", "get", "tui.refresh_interval"); output != "20\n" {
+		t.Errorf("Expected 20, got %q", output)
+	}
+	if data, _ := os.ReadFile(path); !strings.HasPrefix(string(data), "# my settings\n") {
+		t.Errorf("Expected the comment to be preserved, got:\n%s", data)
+	}
+
+	if output := runCommand(t, chezmoitest.NewFake(), "--tui-config", path, "config", "validate"); !strings.Contains(output, "is valid") {
+		t.Errorf("Expected the file to be valid, got %q", output)
+	}
+}
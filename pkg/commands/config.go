@@ -1,12 +1,17 @@
 package commands
 
 import (
+	"encoding/json"
 	"fmt"
 	"log"
 	"os"
+	"path/filepath"
+	"text/tabwriter"
 
 	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
 
+	"chezmoi-tui/internal/config"
 	"chezmoi-tui/pkg/root"
 )
 
@@ -21,38 +26,11 @@ var generateConfigCmd = &cobra.Command{
 	Short: "Generate a default configuration file",
 	Long:  `Generate a default configuration file for chezmoi-tui`,
 	Run: func(cmd *cobra.Command, args []string) {
-		// Define the default config template
-		configTemplate := `# Chezmoi TUI Configuration
-# This file configures the enhanced TUI and CLI for chezmoi
-
-# Theme settings
-theme:
-  primary_color: "#1793d1"
-  secondary_color: "#0366d6"
-  success_color: "#28a745"
-  warning_color: "#ffc107"
-  danger_color: "#dc3545"
-
-# TUI settings
-tui:
-  show_help: true
-  refresh_interval: 5 # seconds
-  max_file_display: 100
-
-# CLI settings
-cli:
-  verbose: false
-  color: true
-  pager: "less"
-
-# Integration settings
-integration:
-  chezmoi_binary_path: ""
-  timeout: 30 # seconds
-`
-
 		// Check if config file already exists
-		configPath := os.Getenv("HOME") + "/.config/chezmoi-tui/config.yaml"
+		configPath := root.ConfigFlags.File
+		if configPath == "" {
+			configPath = config.DefaultPath()
+		}
 		if _, err := os.Stat(configPath); err == nil {
 			force, _ := cmd.Flags().GetBool("force")
 			if !force {
@@ -61,33 +39,199 @@ integration:
 		}
 
 		// Create directory if it doesn't exist
-		dir := os.Getenv("HOME") + "/.config/chezmoi-tui"
-		if err := os.MkdirAll(dir, 0755); err != nil {
+		if err := os.MkdirAll(filepath.Dir(configPath), 0755); err != nil {
 			log.Fatalf("Failed to create config directory: %v", err)
 		}
 
 		// Write the config file
-		file, err := os.Create(configPath)
+		if err := os.WriteFile(configPath, []byte(config.Template), 0644); err != nil {
+			log.Fatalf("Failed to write config file: %v", err)
+		}
+
+		fmt.Fprintf(cmd.OutOrStdout(), "Configuration file generated at: %s\n", configPath)
+	},
+}
+
+var showConfigCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Show the effective configuration",
+	Long: `Show the effective configuration after merging the configuration file,
+CHEZMOI_TUI_* environment variables and command line flags, together with
+where each value came from.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg := appConfig()
+		out := cmd.OutOrStdout()
+
+		if showPath, _ := cmd.Flags().GetBool("path"); showPath {
+			if cfg.File == "" {
+				log.Fatalf("No configuration file found; defaults are used")
+			}
+			fmt.Fprintln(out, cfg.File)
+			return
+		}
+
+		format, _ := cmd.Flags().GetString("format")
+		switch format {
+		case "yaml":
+			data, err := yaml.Marshal(cfg)
+			if err != nil {
+				log.Fatalf("Failed to encode configuration: %v", err)
+			}
+			out.Write(data)
+		case "json":
+			data, err := json.MarshalIndent(cfg, "", "  ")
+			if err != nil {
+				log.Fatalf("Failed to encode configuration: %v", err)
+			}
+			fmt.Fprintln(out, string(data))
+		case "table":
+			file := cfg.File
+			if file == "" {
+				file = "none, using defaults"
+			}
+			fmt.Fprintf(out, "Config file: %s\n\n", file)
+
+			w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "KEY\tVALUE\tSOURCE")
+			for _, key := range config.Keys() {
+				value, _ := cfg.Get(key)
+				if value == "" {
+					value = `""`
+				}
+				fmt.Fprintf(w, "%s\t%s\t%s\n", key, value, cfg.Source(key))
+			}
+			w.Flush()
+		default:
+			log.Fatalf("Unknown format %q, expected table, yaml or json", format)
+		}
+	},
+}
+
+var validateConfigCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Validate a configuration file",
+	Long: `Check a configuration file for unknown sections and settings, values of the
+wrong type and invalid values, reporting the line of each problem.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		path, _ := cmd.Flags().GetString("file")
+		if path == "" {
+			path = config.FindFile(root.ConfigFlags.File)
+		}
+		if path == "" {
+			fmt.Fprintln(cmd.OutOrStdout(), "No configuration file found; defaults are used")
+			return
+		}
+
+		problems, err := config.CheckFile(path)
 		if err != nil {
-			log.Fatalf("Failed to create config file: %v", err)
+			log.Fatalf("%v", err)
+		}
+		for _, problem := range problems {
+			fmt.Fprintf(cmd.ErrOrStderr(), "%s: %s\n", path, problem)
 		}
-		defer file.Close()
+		if len(problems) > 0 {
+			log.Fatalf("%s is invalid: %d problem(s) found", path, len(problems))
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "%s is valid\n", path)
+	},
+}
 
-		_, err = file.WriteString(configTemplate)
+var getConfigCmd = &cobra.Command{
+	Use:   "get <key>",
+	Short: "Print the effective value of a setting",
+	Long:  `Print the effective value of a setting given as a dotted key, e.g. tui.refresh_interval.`,
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		value, err := appConfig().Get(args[0])
 		if err != nil {
-			log.Fatalf("Failed to write config file: %v", err)
+			log.Fatalf("%v", err)
+		}
+		fmt.Fprintln(cmd.OutOrStdout(), value)
+	},
+}
+
+var setConfigCmd = &cobra.Command{
+	Use:   "set <key> <value>",
+	Short: "Change a setting in the configuration file",
+	Long: `Change a setting given as a dotted key, e.g. tui.refresh_interval, in the
+configuration file. The file is created if it does not exist, and comments in
+YAML files are preserved.`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		path := config.FindFile(root.ConfigFlags.File)
+		if path == "" {
+			path = config.DefaultPath()
 		}
 
-		fmt.Printf("Configuration file generated at: %s\n", configPath)
+		if err := config.SetInFile(path, args[0], args[1]); err != nil {
+			log.Fatalf("Failed to set %s: %v", args[0], err)
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "Set %s to %s in %s\n", args[0], args[1], path)
 	},
 }
 
+// loadConfig loads the configuration file and environment overrides, applies
+// the command line flags on top and validates the result
+func loadConfig() (*config.Config, error) {
+	cfg, err := config.Load(root.ConfigFlags.File)
+	if err != nil {
+		return nil, err
+	}
+
+	flags := root.RootCmd.PersistentFlags()
+	overrides := []struct {
+		flag, key string
+	}{
+		{"chezmoi-binary", "integration.chezmoi_binary_path"},
+		{"timeout", "integration.timeout"},
+		{"verbose", "cli.verbose"},
+		{"pager", "cli.pager"},
+	}
+	for _, override := range overrides {
+		if flags.Changed(override.flag) {
+			value := flags.Lookup(override.flag).Value.String()
+			if err := cfg.Override(override.key, value, config.SourceFlag); err != nil {
+				return nil, fmt.Errorf("--%s: %w", override.flag, err)
+			}
+		}
+	}
+	if root.ConfigFlags.NoColor {
+		if err := cfg.Override("cli.color", "false", config.SourceFlag); err != nil {
+			return nil, fmt.Errorf("--no-color: %w", err)
+		}
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// appConfig returns the effective configuration, exiting if it cannot be
+// loaded
+func appConfig() *config.Config {
+	cfg, err := loadConfig()
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+	return cfg
+}
+
 func init() {
-	// Add flags to the generate command
+	// Add flags to the subcommands
 	generateConfigCmd.Flags().BoolP("force", "f", false, "Force overwrite existing config file")
+	showConfigCmd.Flags().String("format", "table", "Output format: table, yaml or json")
+	showConfigCmd.Flags().Bool("path", false, "Only print the path of the configuration file")
+	validateConfigCmd.Flags().String("file", "", "Configuration file to validate (default: the file in use)")
 
 	// Add subcommands to config command
 	configCmd.AddCommand(generateConfigCmd)
+	configCmd.AddCommand(showConfigCmd)
+	configCmd.AddCommand(validateConfigCmd)
+	configCmd.AddCommand(getConfigCmd)
+	configCmd.AddCommand(setConfigCmd)
 
 	// Add the config command to the root
 	root.RootCmd.AddCommand(configCmd)
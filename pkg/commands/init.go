@@ -17,7 +17,7 @@ var initCmd = &cobra.Command{
 	Args:  cobra.MaximumNArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		// Create integration instance
-		integ, err := integration.New()
+		integ, err := integration.New(chezmoiOptions(appConfig())...)
 		if err != nil {
 			log.Fatalf("Failed to initialize integration: %v", err)
 		}
@@ -31,25 +31,15 @@ var initCmd = &cobra.Command{
 		apply, _ := cmd.Flags().GetBool("apply")
 		purge, _ := cmd.Flags().GetBool("purge")
 
-		var output string
-		if repo != "" {
-			output, err = integ.InitializeRepo(repo, apply)
-		} else {
-			// Just init without a repo
-			output, err = integ.InitializeRepo("", apply)
-		}
-
+		// Stream output so that cloning and applying show progress
+		err = integ.StreamInitializeRepo(cmd.Context(), terminalStreams(cmd), repo, apply)
 		if err != nil {
-			log.Fatalf("Failed to initialize: %v", err)
-		}
-
-		if output != "" {
-			fmt.Print(output)
+			fatalChezmoiStreamed("initialize", err)
 		}
 
 		if purge {
 			// In a real implementation, this would purge the config, source, and cache directories
-			fmt.Println("Purge functionality would remove config, source, and cache directories")
+			fmt.Fprintln(cmd.OutOrStdout(), "Purge functionality would remove config, source, and cache directories")
 		}
 	},
 }
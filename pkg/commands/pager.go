@@ -0,0 +1,39 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/mattn/go-isatty"
+	"github.com/spf13/cobra"
+)
+
+// printPaged writes output to the command's standard output. When that is a
+// terminal and a pager is configured, the output is piped through the pager
+// instead. Like git, less is told to quit if the output fits on one screen.
+func printPaged(cmd *cobra.Command, pager, output string) {
+	out := cmd.OutOrStdout()
+	file, ok := out.(*os.File)
+	args := strings.Fields(pager)
+	if !ok || len(args) == 0 || !isatty.IsTerminal(file.Fd()) {
+		fmt.Fprint(out, output)
+		return
+	}
+
+	pagerCmd := exec.Command(args[0], args[1:]...)
+	pagerCmd.Stdin = strings.NewReader(output)
+	pagerCmd.Stdout = file
+	pagerCmd.Stderr = cmd.ErrOrStderr()
+	pagerCmd.Env = os.Environ()
+	if _, ok := os.LookupEnv("LESS"); !ok {
+		pagerCmd.Env = append(pagerCmd.Env, "LESS=FRX")
+	}
+
+	if err := pagerCmd.Start(); err != nil {
+		fmt.Fprint(out, output)
+		return
+	}
+	pagerCmd.Wait()
+}
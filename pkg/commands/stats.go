@@ -3,11 +3,11 @@ package commands
 import (
 	"fmt"
 	"log"
-	"strings"
 	"time"
 
 	"github.com/spf13/cobra"
 
+	"chezmoi-tui/internal/chezmoi"
 	"chezmoi-tui/internal/integration"
 	"chezmoi-tui/pkg/root"
 )
@@ -18,87 +18,59 @@ var statsCmd = &cobra.Command{
 	Long:  `Show statistics and analytics about your dotfiles management`,
 	Run: func(cmd *cobra.Command, args []string) {
 		// Create integration instance
-		integ, err := integration.New()
+		integ, err := integration.New(chezmoiOptions(appConfig())...)
 		if err != nil {
 			log.Fatalf("Failed to initialize integration: %v", err)
 		}
 
 		// Get status information
-		statusData, err := integ.GetStatus()
+		statusData, err := integ.GetStatus(cmd.Context())
 		if err != nil {
 			log.Printf("Could not get status data: %v", err)
 		}
 
 		// Get all managed files
-		managedOutput, err := integ.GetManagedFiles()
+		managedFiles, err := integ.GetManagedFiles(cmd.Context())
 		if err != nil {
 			log.Printf("Could not get managed files: %v", err)
 		}
 
 		// Get unmanaged files
-		unmanagedOutput, err := integ.GetUnmanagedFiles()
+		unmanagedFiles, err := integ.GetUnmanagedFiles(cmd.Context())
 		if err != nil {
 			log.Printf("Could not get unmanaged files: %v", err)
 		}
 
 		// Calculate stats
-		var modifiedCount, addedCount, deletedCount, upToDateCount int
-		for _, entry := range statusData {
-			destStatus := entry["dest_status"]
-			targetStatus := entry["target_status"]
-
-			if strings.Contains(destStatus, "M") || strings.Contains(targetStatus, "M") {
-				modifiedCount++
-			} else if strings.Contains(destStatus, "A") || strings.Contains(targetStatus, "A") {
-				addedCount++
-			} else if strings.Contains(destStatus, "D") || strings.Contains(targetStatus, "D") {
-				deletedCount++
-			} else {
-				upToDateCount++
-			}
-		}
-
-		managedFiles := strings.Split(strings.TrimSpace(managedOutput), "\n")
-		var validManagedFiles []string
-		for _, file := range managedFiles {
-			if strings.TrimSpace(file) != "" {
-				validManagedFiles = append(validManagedFiles, file)
-			}
-		}
-
-		unmanagedFiles := strings.Split(strings.TrimSpace(unmanagedOutput), "\n")
-		var validUnmanagedFiles []string
-		for _, file := range unmanagedFiles {
-			if strings.TrimSpace(file) != "" {
-				validUnmanagedFiles = append(validUnmanagedFiles, file)
-			}
-		}
+		counts := chezmoi.CountStatus(statusData)
 
 		// Display statistics
-		fmt.Println("┌─ Chezmoi Dotfiles Statistics ──────────────────────────────────┐")
-		fmt.Printf("│ Last Updated: %-47s │\n", time.Now().Format("2006-01-02 15:04:05"))
-		fmt.Println("├─────────────────────────────────────────────────────────────────┤")
-		fmt.Printf("│ Total Managed Files:    %3d                                   │\n", len(validManagedFiles))
-		fmt.Printf("│ Total Unmanaged Files:  %3d                                   │\n", len(validUnmanagedFiles))
-		fmt.Println("├─────────────────────────────────────────────────────────────────┤")
-		fmt.Printf("│ Up to Date:             %3d (%3d%%)                           │\n",
-			upToDateCount, calculatePercentage(upToDateCount, len(validManagedFiles)))
-		fmt.Printf("│ Modified:               %3d (%3d%%)                           │\n",
-			modifiedCount, calculatePercentage(modifiedCount, len(validManagedFiles)))
-		fmt.Printf("│ Added:                  %3d (%3d%%)                           │\n",
-			addedCount, calculatePercentage(addedCount, len(validManagedFiles)))
-		fmt.Printf("│ Deleted:                %3d (%3d%%)                           │\n",
-			deletedCount, calculatePercentage(deletedCount, len(validManagedFiles)))
-		fmt.Println("└─────────────────────────────────────────────────────────────────┘")
+		out := cmd.OutOrStdout()
+		fmt.Fprintln(out, "┌─ Chezmoi Dotfiles Statistics ──────────────────────────────────┐")
+		fmt.Fprintf(out, "│ Last Updated: %-47s │\n", time.Now().Format("2006-01-02 15:04:05"))
+		fmt.Fprintln(out, "├─────────────────────────────────────────────────────────────────┤")
+		fmt.Fprintf(out, "│ Total Managed Files:    %3d                                   │\n", len(managedFiles))
+		fmt.Fprintf(out, "│ Total Unmanaged Files:  %3d                                   │\n", len(unmanagedFiles))
+		fmt.Fprintln(out, "├─────────────────────────────────────────────────────────────────┤")
+		fmt.Fprintf(out, "│ Up to Date:             %3d (%3d%%)                           │\n",
+			counts.Unchanged, calculatePercentage(counts.Unchanged, len(managedFiles)))
+		fmt.Fprintf(out, "│ Modified:               %3d (%3d%%)                           │\n",
+			counts.Modified, calculatePercentage(counts.Modified, len(managedFiles)))
+		fmt.Fprintf(out, "│ Added:                  %3d (%3d%%)                           │\n",
+			counts.Added, calculatePercentage(counts.Added, len(managedFiles)))
+		fmt.Fprintf(out, "│ Deleted:                %3d (%3d%%)                           │\n",
+			counts.Deleted, calculatePercentage(counts.Deleted, len(managedFiles)))
+		fmt.Fprintf(out, "│ Scripts to Run:         %3d                                   │\n", counts.Scripts)
+		fmt.Fprintln(out, "└─────────────────────────────────────────────────────────────────┘")
 
 		// Show additional details if requested
 		details, _ := cmd.Flags().GetBool("details")
 		if details {
-			fmt.Println("\nDetailed Breakdown:")
-			fmt.Printf("Managed files (%d): %v\n", len(validManagedFiles), validManagedFiles)
+			fmt.Fprintln(out, "\nDetailed Breakdown:")
+			fmt.Fprintf(out, "Managed files (%d): %v\n", len(managedFiles), managedFiles)
 			// Only show unmanaged if there are any
-			if len(validUnmanagedFiles) > 0 {
-				fmt.Printf("Unmanaged files (%d): %v\n", len(validUnmanagedFiles), validUnmanagedFiles)
+			if len(unmanagedFiles) > 0 {
+				fmt.Fprintf(out, "Unmanaged files (%d): %v\n", len(unmanagedFiles), unmanagedFiles)
 			}
 		}
 	},
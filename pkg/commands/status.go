@@ -1,7 +1,6 @@
 package commands
 
 import (
-	"fmt"
 	"log"
 
 	"github.com/spf13/cobra"
@@ -15,17 +14,18 @@ var statusCmd = &cobra.Command{
 	Short: "Show the status of targets",
 	Long:  `Show the status of targets in a format similar to git status.`,
 	Run: func(cmd *cobra.Command, args []string) {
-		c, err := chezmoi.New()
+		cfg := appConfig()
+		c, err := chezmoi.New(chezmoiOptions(cfg)...)
 		if err != nil {
 			log.Fatalf("Failed to initialize chezmoi: %v", err)
 		}
 
-		output, err := c.Status()
+		output, err := c.Status(cmd.Context())
 		if err != nil {
-			log.Fatalf("Failed to get status: %v", err)
+			fatalChezmoi("get status", err)
 		}
 
-		fmt.Print(output)
+		printPaged(cmd, cfg.CLI.Pager, output)
 	},
 }
 
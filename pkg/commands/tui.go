@@ -17,7 +17,10 @@ var tuiCmd = &cobra.Command{
 	Run: func(cmd *cobra.Command, args []string) {
 		fmt.Println("Launching Chezmoi TUI...")
 		// TUI logic will be implemented here
-		err := ui.RunTUI()
+		cfg := appConfig()
+		// Without bw the Bitwarden manager explains how to install it
+		bw, _ := newBitwardenClient(cfg)
+		err := ui.RunTUI(cfg, bw, chezmoiOptions(cfg)...)
 		if err != nil {
 			log.Fatalf("Failed to run TUI: %v", err)
 		}
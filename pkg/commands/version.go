@@ -1,10 +1,12 @@
 package commands
 
 import (
+	"errors"
 	"fmt"
 
 	"github.com/spf13/cobra"
 
+	"chezmoi-tui/internal/chezmoi"
 	"chezmoi-tui/pkg/root"
 )
 
@@ -14,9 +16,21 @@ var Version = "0.1.0"
 var versionCmd = &cobra.Command{
 	Use:   "version",
 	Short: "Print the version number",
-	Long:  `Print the version number of chezmoi-tui.`,
+	Long:  `Print the version number of chezmoi-tui and of the chezmoi it runs.`,
 	Run: func(cmd *cobra.Command, args []string) {
-		fmt.Printf("chezmoi-tui version %s\\n", Version)
+		out := cmd.OutOrStdout()
+		fmt.Fprintf(out, "chezmoi-tui version %s\n", Version)
+
+		client, err := chezmoi.New(chezmoiOptions(appConfig())...)
+		var versionErr *chezmoi.VersionError
+		switch {
+		case errors.As(err, &versionErr):
+			fmt.Fprintf(out, "chezmoi version %s (unsupported, %s or later is required)\n", versionErr.Version, versionErr.Required)
+		case err != nil:
+			fmt.Fprintf(out, "chezmoi not available: %v\n", err)
+		default:
+			fmt.Fprintf(out, "chezmoi version %s (%s)\n", client.DetectedVersion(), client.GetBinaryPath())
+		}
 	},
 }
 
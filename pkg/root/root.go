@@ -7,6 +7,38 @@ import (
 // Version is the application version
 var Version = "0.1.0"
 
+// ChezmoiFlags holds the chezmoi settings given on the command line. They
+// apply to every chezmoi command run by chezmoi-tui.
+var ChezmoiFlags struct {
+	// Binary is the chezmoi binary to run
+	Binary string
+	// Source is chezmoi's source directory
+	Source string
+	// Destination is chezmoi's destination directory
+	Destination string
+	// Config is chezmoi's config file
+	Config string
+	// Cache is chezmoi's cache directory
+	Cache string
+	// Extra are additional global flags passed through to chezmoi
+	Extra []string
+}
+
+// ConfigFlags holds the chezmoi-tui settings given on the command line. They
+// take precedence over the configuration file and environment variables.
+var ConfigFlags struct {
+	// File is the chezmoi-tui configuration file
+	File string
+	// Timeout is the chezmoi command timeout in seconds
+	Timeout int
+	// Verbose passes --verbose to chezmoi
+	Verbose bool
+	// NoColor disables coloured output
+	NoColor bool
+	// Pager is the pager for long output
+	Pager string
+}
+
 // RootCmd is the root command for the application
 var RootCmd = &cobra.Command{
 	Use:     "chezmoi-tui",
@@ -14,3 +46,19 @@ var RootCmd = &cobra.Command{
 	Long:    `An enhanced Terminal User Interface and Command Line Interface for managing your dotfiles with chezmoi.`,
 	Version: Version,
 }
+
+func init() {
+	flags := RootCmd.PersistentFlags()
+	flags.StringVar(&ChezmoiFlags.Binary, "chezmoi-binary", "", "Path to the chezmoi binary")
+	flags.StringVarP(&ChezmoiFlags.Source, "source", "S", "", "Set chezmoi's source directory")
+	flags.StringVarP(&ChezmoiFlags.Destination, "destination", "D", "", "Set chezmoi's destination directory")
+	flags.StringVarP(&ChezmoiFlags.Config, "config", "c", "", "Set chezmoi's config file")
+	flags.StringVar(&ChezmoiFlags.Cache, "cache", "", "Set chezmoi's cache directory")
+	flags.StringArrayVar(&ChezmoiFlags.Extra, "chezmoi-flag", nil, "Pass an extra global flag to chezmoi (repeatable)")
+
+	flags.StringVar(&ConfigFlags.File, "tui-config", "", "Path to the chezmoi-tui config file (default ~/.config/chezmoi-tui/config.yaml)")
+	flags.IntVar(&ConfigFlags.Timeout, "timeout", 0, "Timeout for chezmoi commands in seconds, 0 to disable")
+	flags.BoolVar(&ConfigFlags.Verbose, "verbose", false, "Make chezmoi print verbose output")
+	flags.BoolVar(&ConfigFlags.NoColor, "no-color", false, "Disable coloured output")
+	flags.StringVar(&ConfigFlags.Pager, "pager", "", "Pager for long output, empty to disable paging")
+}
@@ -9,8 +9,8 @@ import (
 // TestCLICommands tests the basic CLI functionality
 func TestCLICommands(t *testing.T) {
 	// Build the binary first
-	cmd := exec.Command("go", "build", "-o", "test-binary", ".")
-	cmd.Dir = "../" // Build from the parent directory
+	cmd := exec.Command("go", "build", "-o", "tests/e2e/test-binary", ".")
+	cmd.Dir = "../.." // Build from the project root
 	err := cmd.Run()
 	if err != nil {
 		t.Fatalf("Failed to build binary: %v", err)
@@ -13,8 +13,8 @@ const testBinary = "../test-binary"
 func setupTestEnv(t *testing.T) {
 	// Build the binary in the project root directory
 	// Run from project root where go.mod is located
-	cmd := exec.Command("go", "build", "-o", "../../test-binary", ".")
-	cmd.Dir = "../../../" // Go to project root
+	cmd := exec.Command("go", "build", "-o", "tests/test-binary", ".")
+	cmd.Dir = "../.." // Go to project root
 	err := cmd.Run()
 	if err != nil {
 		t.Fatalf("Failed to build binary for testing: %v", err)
@@ -23,8 +23,8 @@ func setupTestEnv(t *testing.T) {
 
 // tearDownTestEnv removes the test binary
 func tearDownTestEnv(t *testing.T) {
-	// Remove the binary from project root
-	err := os.Remove("../../../test-binary")
+	// Remove the binary from the tests directory
+	err := os.Remove(testBinary)
 	if err != nil {
 		t.Logf("Warning: failed to remove test binary: %v", err)
 	}
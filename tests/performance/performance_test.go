@@ -2,7 +2,11 @@ package performance
 
 import (
 	"chezmoi-tui/internal/chezmoi"
+	"chezmoi-tui/internal/chezmoi/chezmoitest"
 	"chezmoi-tui/internal/integration"
+	"context"
+	"fmt"
+	"strings"
 	"testing"
 	"time"
 )
@@ -16,7 +20,7 @@ func BenchmarkIntegrationGetStatus(b *testing.B) {
 
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		_, err := integ.GetStatus()
+		_, err := integ.GetStatus(context.Background())
 		if err != nil {
 			// Don't fail the benchmark if chezmoi isn't initialized
 			b.Logf("GetStatus returned error: %v", err)
@@ -24,6 +28,29 @@ func BenchmarkIntegrationGetStatus(b *testing.B) {
 	}
 }
 
+// BenchmarkIntegrationGetStatusFake benchmarks GetStatus without process
+// overhead, using a fake chezmoi with a large status output
+func BenchmarkIntegrationGetStatusFake(b *testing.B) {
+	var output strings.Builder
+	for i := 0; i < 1000; i++ {
+		fmt.Fprintf(&output, " M .config/app%d/config.toml\n", i)
+	}
+
+	fake := chezmoitest.NewFake()
+	fake.On("status").Stdout(output.String())
+	integ, err := integration.New(chezmoi.WithExecutor(fake))
+	if err != nil {
+		b.Fatalf("Failed to create integration: %v", err)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := integ.GetStatus(context.Background()); err != nil {
+			b.Fatalf("GetStatus returned error: %v", err)
+		}
+	}
+}
+
 // BenchmarkChezmoiParseStatusOutput benchmarks the status parsing function
 func BenchmarkChezmoiParseStatusOutput(b *testing.B) {
 	// Sample status output
@@ -49,7 +76,7 @@ func TestResponseTime(t *testing.T) {
 
 	t.Run("GetConfigDataResponseTime", func(t *testing.T) {
 		start := time.Now()
-		_, err := integ.GetConfigData()
+		_, err := integ.GetConfigData(context.Background())
 		duration := time.Since(start)
 
 		if err != nil {
@@ -66,7 +93,7 @@ func TestResponseTime(t *testing.T) {
 
 	t.Run("GetManagedFilesResponseTime", func(t *testing.T) {
 		start := time.Now()
-		_, err := integ.GetManagedFiles()
+		_, err := integ.GetManagedFiles(context.Background())
 		duration := time.Since(start)
 
 		if err != nil {
@@ -0,0 +1,280 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"chezmoi-tui/internal/chezmoi"
+)
+
+// addHelp lists the Add Files key bindings
+const addHelp = "↑/↓ move · enter open · backspace parent · space select · t/e/x/f toggle options · a add · h back"
+
+// pickerEntry is an entry of the directory shown by the file picker
+type pickerEntry struct {
+	name  string
+	isDir bool
+}
+
+// filePicker browses the filesystem to choose the files to add to the
+// source state. Selections are kept by absolute path so that files from
+// several directories can be added at once.
+type filePicker struct {
+	dir      string
+	entries  []pickerEntry
+	cursor   int
+	selected map[string]bool
+	options  chezmoi.AddOptions
+	err      error
+}
+
+// destDirMsg carries the destination directory resolved for Add Files
+type destDirMsg struct {
+	op      int
+	destDir string
+	err     error
+}
+
+// openAddFiles shows the Add Files screen, starting in the destination
+// directory, which is resolved in the background the first time
+func (m *Model) openAddFiles() tea.Cmd {
+	m.screen = screenAdd
+	if m.destDir != "" {
+		m.picker = newFilePicker(m.destDir)
+		return nil
+	}
+
+	ctx, op := m.startOperation()
+	m.picker = filePicker{selected: make(map[string]bool)}
+	integ := m.integration
+	return func() tea.Msg {
+		destDir, err := integ.DestDir(ctx)
+		return destDirMsg{op: op, destDir: destDir, err: err}
+	}
+}
+
+// openPicker shows the destination directory resolved by openAddFiles
+func (m *Model) openPicker(destDir string, err error) {
+	if err != nil {
+		m.picker.err = err
+		return
+	}
+	m.destDir = destDir
+	m.picker = newFilePicker(destDir)
+}
+
+// newFilePicker returns a picker showing dir
+func newFilePicker(dir string) filePicker {
+	p := filePicker{selected: make(map[string]bool)}
+	p.Open(dir)
+	return p
+}
+
+// Open shows the contents of dir, directories first. Hidden files are
+// listed since they are what dotfile managers are for.
+func (p *filePicker) Open(dir string) {
+	p.dir = dir
+	p.cursor = 0
+	p.entries = nil
+
+	dirEntries, err := os.ReadDir(dir)
+	p.err = err
+	for _, entry := range dirEntries {
+		isDir := entry.IsDir()
+		if entry.Type()&os.ModeSymlink != 0 {
+			// Follow symlinks to directories so that they can be browsed
+			if info, err := os.Stat(filepath.Join(dir, entry.Name())); err == nil {
+				isDir = info.IsDir()
+			}
+		}
+		p.entries = append(p.entries, pickerEntry{name: entry.Name(), isDir: isDir})
+	}
+	sort.SliceStable(p.entries, func(i, j int) bool {
+		return p.entries[i].isDir && !p.entries[j].isDir
+	})
+}
+
+// Parent shows the parent directory with the cursor on the directory that
+// was left
+func (p *filePicker) Parent() {
+	child := filepath.Base(p.dir)
+	parent := filepath.Dir(p.dir)
+	if parent == p.dir {
+		return
+	}
+
+	p.Open(parent)
+	for i, entry := range p.entries {
+		if entry.name == child {
+			p.cursor = i
+			break
+		}
+	}
+}
+
+// Enter opens the directory under the cursor, or toggles the selection of
+// the file under it
+func (p *filePicker) Enter() {
+	if p.cursor >= len(p.entries) {
+		return
+	}
+	if entry := p.entries[p.cursor]; entry.isDir {
+		p.Open(filepath.Join(p.dir, entry.name))
+		return
+	}
+	p.Toggle()
+}
+
+// Toggle selects or deselects the entry under the cursor
+func (p *filePicker) Toggle() {
+	if p.cursor >= len(p.entries) {
+		return
+	}
+	path := filepath.Join(p.dir, p.entries[p.cursor].name)
+	if p.selected[path] {
+		delete(p.selected, path)
+	} else {
+		p.selected[path] = true
+	}
+}
+
+// Targets returns the selected paths in sorted order, or the path of the
+// entry under the cursor if nothing is selected
+func (p *filePicker) Targets() []string {
+	var targets []string
+	for path := range p.selected {
+		targets = append(targets, path)
+	}
+	sort.Strings(targets)
+	if len(targets) == 0 && p.cursor < len(p.entries) {
+		targets = append(targets, filepath.Join(p.dir, p.entries[p.cursor].name))
+	}
+	return targets
+}
+
+// handleAddKey handles a key press on the Add Files screen and reports
+// whether it was consumed
+func (m *Model) handleAddKey(msg tea.KeyMsg) (tea.Cmd, bool) {
+	p := &m.picker
+
+	switch msg.String() {
+	case "up", "k":
+		if p.cursor > 0 {
+			p.cursor--
+		}
+	case "down", "j":
+		if p.cursor < len(p.entries)-1 {
+			p.cursor++
+		}
+	case "enter", "right", "l":
+		p.Enter()
+	case "backspace", "-":
+		p.Parent()
+	case " ":
+		p.Toggle()
+	case "t":
+		p.options.Template = !p.options.Template
+	case "e":
+		p.options.Encrypt = !p.options.Encrypt
+	case "x":
+		p.options.Exact = !p.options.Exact
+	case "f":
+		p.options.Follow = !p.options.Follow
+	case "a":
+		return m.addFiles(), true
+	default:
+		return nil, false
+	}
+	return nil, true
+}
+
+// addFiles adds the picked files to the source state, streaming chezmoi's
+// output into the log pane
+func (m *Model) addFiles() tea.Cmd {
+	targets, opts := m.picker.Targets(), m.picker.options
+	if len(targets) == 0 {
+		return nil
+	}
+	m.picker.selected = make(map[string]bool)
+	m.screen = screenLog
+	m.returnScreen = screenAdd
+	m.refreshOnBack = false
+
+	ctx, op := m.startOperation()
+	m.log.Reset("Adding " + targetSummary(targets))
+
+	integ := m.integration
+	return startLogStream(ctx, op, func(ctx context.Context, streams chezmoi.IOStreams) error {
+		return integ.StreamAddFiles(ctx, streams, opts, targets...)
+	})
+}
+
+// addView renders the Add Files screen
+func (m *Model) addView() string {
+	p := &m.picker
+	switch {
+	case m.loading && p.dir == "":
+		return quitTextStyle.Render(m.spinner.View() + " Finding the destination directory... (esc to cancel)")
+	case p.err != nil && p.dir == "":
+		return quitTextStyle.Render(renderError("Finding the destination directory", p.err))
+	}
+
+	var content strings.Builder
+	content.WriteString("Add Files\n")
+	content.WriteString(p.dir + "\n\n")
+
+	start, end := visibleRange(p.cursor, len(p.entries), m.maxFileDisplay)
+	switch {
+	case p.err != nil:
+		content.WriteString(logErrorStyle.Render(fmt.Sprintf("Cannot read directory: %v", p.err)) + "\n")
+	case len(p.entries) == 0:
+		content.WriteString("  (empty directory)\n")
+	}
+	if start > 0 {
+		content.WriteString(fmt.Sprintf("  ... %d more above\n", start))
+	}
+	for i := start; i < end; i++ {
+		entry := p.entries[i]
+		cursor := " "
+		if p.cursor == i {
+			cursor = "→"
+		}
+		mark := " "
+		if p.selected[filepath.Join(p.dir, entry.name)] {
+			mark = "✓"
+		}
+		name := entry.name
+		if entry.isDir {
+			name += "/"
+		}
+		content.WriteString(fmt.Sprintf("%s %s %s\n", cursor, mark, name))
+	}
+	if end < len(p.entries) {
+		content.WriteString(fmt.Sprintf("  ... and %d more\n", len(p.entries)-end))
+	}
+
+	content.WriteString(fmt.Sprintf("\n%d selected\n", len(p.selected)))
+	content.WriteString(fmt.Sprintf("Options: %s template (t) · %s encrypt (e) · %s exact (x) · %s follow symlinks (f)\n",
+		checkbox(p.options.Template), checkbox(p.options.Encrypt), checkbox(p.options.Exact), checkbox(p.options.Follow)))
+	content.WriteString(addHelp + "\n")
+
+	line := 3 + p.cursor - start
+	if start > 0 {
+		line++
+	}
+	return m.scrollView(content.String(), line)
+}
+
+// checkbox renders an option state
+func checkbox(on bool) string {
+	if on {
+		return "[x]"
+	}
+	return "[ ]"
+}
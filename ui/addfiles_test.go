@@ -0,0 +1,130 @@
+package ui
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"chezmoi-tui/internal/chezmoi/chezmoitest"
+)
+
+// newAddModel returns a model showing the Add Files screen in a home
+// directory containing .bashrc, .gitconfig and .config/nvim/init.lua
+func newAddModel(t *testing.T, fake *chezmoitest.Fake) (*Model, string) {
+	t.Helper()
+
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	for _, path := range []string{".bashrc", ".gitconfig", ".config/nvim/init.lua"} {
+		path = filepath.Join(home, path)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	m := newTestModel(t, fake)
+	selectMenu(t, m, "Add Files")
+	runCmd(m, press(m, "enter"))
+	if m.screen != screenAdd {
+		t.Fatal("Expected the Add Files screen")
+	}
+	return m, home
+}
+
+func TestAddFilesPicker(t *testing.T) {
+	m, home := newAddModel(t, chezmoitest.NewFake())
+
+	// Directories are listed first
+	view := m.View()
+	if !strings.Contains(view, "→   .config/") || !strings.Contains(view, ".gitconfig") {
+		t.Fatalf("Expected the home directory to be listed, got:\n%s", view)
+	}
+
+	press(m, "enter")
+	if m.picker.dir != filepath.Join(home, ".config") {
+		t.Fatalf("Expected to open .config, got %s", m.picker.dir)
+	}
+	press(m, "backspace")
+	if m.picker.dir != home || m.picker.cursor != 0 {
+		t.Errorf("Expected to return to the home directory with the cursor on .config, got %s at %d", m.picker.dir, m.picker.cursor)
+	}
+}
+
+func TestAddFilesWithOptions(t *testing.T) {
+	fake := chezmoitest.NewFake()
+	fake.On("add").Stdout("added\n")
+	m, home := newAddModel(t, fake)
+
+	press(m, "down")
+	press(m, " ")
+	press(m, "down")
+	press(m, " ")
+	press(m, "t")
+	press(m, "e")
+	if view := m.View(); !strings.Contains(view, "2 selected") || !strings.Contains(view, "[x] template") {
+		t.Errorf("Expected the selection and options to be shown, got:\n%s", m.View())
+	}
+
+	runCmd(m, press(m, "a"))
+	if fake.CallCount("add", "--template", "--encrypt", filepath.Join(home, ".bashrc"), filepath.Join(home, ".gitconfig")) != 1 {
+		t.Errorf("Expected the selected files to be added with the options, got %+v", fake.Calls())
+	}
+	if m.screen != screenLog || !strings.Contains(m.View(), "Done.") {
+		t.Errorf("Expected the add output in the log pane, got:\n%s", m.View())
+	}
+
+	press(m, "h")
+	if m.screen != screenAdd || len(m.picker.selected) != 0 {
+		t.Error("Expected to return to the picker with the selection cleared")
+	}
+}
+
+func TestAddFilesDestDir(t *testing.T) {
+	dest := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dest, ".profile"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	fake := chezmoitest.NewFake()
+	fake.On("dump-config").Stdout(`{"destDir":"` + dest + `"}`)
+	m := newTestModel(t, fake)
+
+	// The destination directory is resolved in the background
+	selectMenu(t, m, "Add Files")
+	cmd := press(m, "enter")
+	if view := m.View(); !strings.Contains(view, "Finding the destination directory") {
+		t.Fatalf("Expected the destination directory to be resolved, got:\n%s", view)
+	}
+	runCmd(m, cmd)
+	if m.picker.dir != dest || !strings.Contains(m.View(), ".profile") {
+		t.Errorf("Expected chezmoi's destDir to be listed, got %s:\n%s", m.picker.dir, m.View())
+	}
+
+	// and only once
+	press(m, "h")
+	runCmd(m, press(m, "enter"))
+	if fake.CallCount("dump-config") != 1 || m.picker.dir != dest {
+		t.Errorf("Expected the destDir to be kept, got %+v", fake.Calls())
+	}
+}
+
+func TestAddFilesDestDirCancelled(t *testing.T) {
+	fake := chezmoitest.NewFake()
+	fake.On("dump-config").Delay(time.Minute)
+	m := newTestModel(t, fake)
+
+	selectMenu(t, m, "Add Files")
+	cmd := press(m, "enter")
+	press(m, "esc")
+	runCmd(m, cmd)
+	if m.loading || m.destDir != "" {
+		t.Errorf("Expected the lookup to be cancelled, got %q", m.destDir)
+	}
+	if view := m.View(); !strings.Contains(view, "Finding the destination directory was cancelled.") {
+		t.Errorf("Expected the cancellation to be shown, got:\n%s", view)
+	}
+}
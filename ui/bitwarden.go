@@ -0,0 +1,635 @@
+package ui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/atotto/clipboard"
+	"github.com/charmbracelet/bubbles/cursor"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/sahilm/fuzzy"
+
+	"chezmoi-tui/internal/bitwarden"
+)
+
+// vaultHelp lists the key bindings of the vault item list
+const vaultHelp = "↑/↓ move · enter open · / search · r reload · s sync · L lock · h back"
+
+// vaultItemHelp lists the key bindings of the item detail
+const vaultItemHelp = "↑/↓ move · c copy · v reveal · space select · t add to template · h back"
+
+// clipboardTimeout is how long a copied value is left in the clipboard
+const clipboardTimeout = 30 * time.Second
+
+// sessionCheckDelay is added to the idle time left when checking whether the
+// Bitwarden session expired, since it only does once the timeout is exceeded
+const sessionCheckDelay = 100 * time.Millisecond
+
+// The system clipboard, replaced in tests
+var (
+	writeClipboard = clipboard.WriteAll
+	readClipboard  = clipboard.ReadAll
+)
+
+// vaultView is the state of the Bitwarden manager
+type vaultView struct {
+	// status is nil until the vault has been checked
+	status *bitwarden.Status
+	err    error
+	items  []bitwarden.Item
+	listed []listedItem
+	cursor int
+	// query fuzzy matches the item names
+	query textinput.Model
+	// password is the master password prompt of a locked vault
+	password textinput.Model
+
+	// item is the item shown in detail, nil while the list is shown.
+	// marked holds the selectors of the fields selected for a template.
+	item        *bitwarden.Item
+	fields      []bitwarden.ItemField
+	fieldCursor int
+	marked      map[string]bool
+	reveal      bool
+
+	// message reports the result of the last action, actionErr its failure
+	message   string
+	actionErr error
+}
+
+// listedItem is a vault item listed by the search, with the indexes of the
+// characters of its name matched by the query
+type listedItem struct {
+	bitwarden.Item
+	matched []int
+}
+
+// newVaultView returns the state of a vault that has not been checked yet
+func newVaultView() vaultView {
+	query := textinput.New()
+	query.Prompt = "/"
+	query.Placeholder = "search items"
+	query.Cursor.SetMode(cursor.CursorStatic)
+
+	password := textinput.New()
+	password.Prompt = "Master password: "
+	password.EchoMode = textinput.EchoPassword
+	password.EchoCharacter = '•'
+	password.Cursor.SetMode(cursor.CursorStatic)
+	return vaultView{query: query, password: password, marked: make(map[string]bool)}
+}
+
+// inputFocused reports whether keys go to the password prompt or the search
+func (v *vaultView) inputFocused() bool {
+	return v.password.Focused() || v.query.Focused()
+}
+
+// vaultLoadedMsg carries the state of the vault after an action on it. If
+// the action failed, actionErr is set and the vault was not reloaded.
+type vaultLoadedMsg struct {
+	op        int
+	status    *bitwarden.Status
+	items     []bitwarden.Item
+	err       error
+	message   string
+	actionErr error
+}
+
+// clipboardClearMsg clears a copied value from the clipboard unless it has
+// been replaced in the meantime
+type clipboardClearMsg struct {
+	value string
+}
+
+// sessionCheckedMsg reports whether the cached Bitwarden session expired
+type sessionCheckedMsg struct {
+	seq     int
+	expired bool
+	err     error
+}
+
+// loadVault opens the Bitwarden manager, checking the vault and listing its
+// items if it is unlocked
+func (m *Model) loadVault() tea.Cmd {
+	m.screen = screenBitwarden
+	m.vault.item = nil
+	if m.bitwarden == nil {
+		return nil
+	}
+	return m.vaultAction("", nil)
+}
+
+// vaultAction runs action in the background, unless it is nil, and then
+// reloads the vault. message reports that the action succeeded.
+func (m *Model) vaultAction(message string, action func(ctx context.Context, client *bitwarden.Client) error) tea.Cmd {
+	ctx, op := m.startOperation()
+	m.vault.message, m.vault.actionErr = "", nil
+
+	client := m.bitwarden
+	return func() tea.Msg {
+		if action != nil {
+			if err := action(ctx, client); err != nil {
+				return vaultLoadedMsg{op: op, actionErr: err}
+			}
+		}
+		msg := vaultLoadedMsg{op: op, message: message}
+		msg.status, msg.err = client.Status(ctx)
+		if msg.err == nil && msg.status.Err() == nil {
+			msg.items, msg.err = client.Items(ctx, bitwarden.ItemFilter{})
+		}
+		return msg
+	}
+}
+
+// setVault records the state of the vault. The item shown in detail is
+// refreshed, and the password prompt is focused while the vault is locked.
+func (m *Model) setVault(msg vaultLoadedMsg) tea.Cmd {
+	v := &m.vault
+	if msg.actionErr != nil {
+		v.actionErr = msg.actionErr
+		if v.status != nil && v.status.Status == bitwarden.StatusLocked {
+			// Let a mistyped password be entered again
+			return v.password.Focus()
+		}
+		return nil
+	}
+
+	v.status, v.items, v.err, v.message = msg.status, msg.items, msg.err, msg.message
+	m.filterVault()
+	if v.item != nil {
+		m.showVaultItem(findItem(v.items, v.item.ID))
+	}
+	check := m.scheduleSessionCheck()
+	if v.err == nil && v.status.Status == bitwarden.StatusLocked {
+		return tea.Batch(v.password.Focus(), check)
+	}
+	return check
+}
+
+// scheduleSessionCheck checks the cached Bitwarden session once its idle
+// timeout runs out, so that the vault is locked while the TUI runs rather
+// than by the next command. It supersedes any check scheduled before.
+func (m *Model) scheduleSessionCheck() tea.Cmd {
+	m.sessionSeq++
+	if m.bitwarden == nil || m.bitwarden.Session() == nil {
+		return nil
+	}
+	session := m.bitwarden.Session()
+	remaining, ok := session.Remaining()
+	if !ok {
+		return nil
+	}
+
+	seq := m.sessionSeq
+	return tea.Tick(remaining+sessionCheckDelay, func(time.Time) tea.Msg {
+		key, err := session.Peek()
+		return sessionCheckedMsg{seq: seq, expired: key == "", err: err}
+	})
+}
+
+// handleSessionChecked hides the items of an expired session, reloading the
+// vault if it is shown, or waits for the rest of the timeout if the session
+// was used in the meantime
+func (m *Model) handleSessionChecked(msg sessionCheckedMsg) tea.Cmd {
+	if msg.seq != m.sessionSeq {
+		return nil
+	}
+	if !msg.expired {
+		return m.scheduleSessionCheck()
+	}
+
+	v := &m.vault
+	if v.status == nil || v.status.Status != bitwarden.StatusUnlocked {
+		return nil
+	}
+	v.status, v.items, v.listed, v.item, v.fields = nil, nil, nil, nil, nil
+	if m.screen != screenBitwarden || m.loading {
+		return nil
+	}
+	message := fmt.Sprintf("Vault locked after %v without use.", m.bitwarden.Session().Timeout())
+	cmd := m.vaultAction(message, nil)
+	v.actionErr = msg.err
+	return cmd
+}
+
+// findItem returns the item with the given ID, or nil
+func findItem(items []bitwarden.Item, id string) *bitwarden.Item {
+	for i := range items {
+		if items[i].ID == id {
+			return &items[i]
+		}
+	}
+	return nil
+}
+
+// filterVault recomputes the listed items. Fuzzy matches of the query are
+// listed best first, otherwise the order of bw list is kept.
+func (m *Model) filterVault() {
+	v := &m.vault
+	v.listed = v.listed[:0]
+	if query := v.query.Value(); query != "" {
+		names := make([]string, len(v.items))
+		for i, item := range v.items {
+			names[i] = item.Name
+		}
+		for _, match := range fuzzy.Find(query, names) {
+			v.listed = append(v.listed, listedItem{Item: v.items[match.Index], matched: match.MatchedIndexes})
+		}
+	} else {
+		for _, item := range v.items {
+			v.listed = append(v.listed, listedItem{Item: item})
+		}
+	}
+
+	if v.cursor >= len(v.listed) {
+		v.cursor = max(len(v.listed)-1, 0)
+	}
+}
+
+// showVaultItem shows item in detail, or the list if item is nil. The
+// selection and the revealed values are kept while the same item is shown.
+func (m *Model) showVaultItem(item *bitwarden.Item) {
+	v := &m.vault
+	if item == nil || v.item == nil || v.item.ID != item.ID {
+		v.fieldCursor = 0
+		v.marked = make(map[string]bool)
+		v.reveal = false
+	}
+	v.item = item
+	v.fields = nil
+	if item != nil {
+		v.fields = bitwarden.ItemFields(item)
+	}
+	if v.fieldCursor >= len(v.fields) {
+		v.fieldCursor = max(len(v.fields)-1, 0)
+	}
+}
+
+// handleVaultInput handles a key press while the password or the search
+// query is typed
+func (m *Model) handleVaultInput(msg tea.KeyMsg) tea.Cmd {
+	v := &m.vault
+	var cmd tea.Cmd
+
+	if v.password.Focused() {
+		switch msg.String() {
+		case "enter":
+			password := v.password.Value()
+			if password == "" {
+				return nil
+			}
+			v.password.SetValue("")
+			v.password.Blur()
+			return m.vaultAction("Vault unlocked.", func(ctx context.Context, client *bitwarden.Client) error {
+				_, err := client.UnlockWithPassword(ctx, password)
+				return err
+			})
+		case "esc":
+			v.password.SetValue("")
+			v.password.Blur()
+			return m.back()
+		}
+		v.password, cmd = v.password.Update(msg)
+		return cmd
+	}
+
+	switch msg.String() {
+	case "enter":
+		v.query.Blur()
+		return nil
+	case "esc":
+		v.query.Blur()
+		v.query.SetValue("")
+		m.filterVault()
+		return nil
+	case "up", "down":
+		// Move through the matches without leaving the query
+		_, _ = m.handleVaultKey(msg)
+		return nil
+	}
+	v.query, cmd = v.query.Update(msg)
+	v.cursor = 0
+	m.filterVault()
+	return cmd
+}
+
+// handleVaultKey handles the keys of the Bitwarden manager and reports
+// whether the key was consumed
+func (m *Model) handleVaultKey(msg tea.KeyMsg) (tea.Cmd, bool) {
+	v := &m.vault
+	if m.bitwarden == nil || m.loading {
+		return nil, false
+	}
+	if msg.String() == "r" {
+		return m.vaultAction("", nil), true
+	}
+	if v.status == nil || v.status.Err() != nil {
+		return nil, false
+	}
+	if v.item != nil {
+		return m.handleVaultItemKey(msg)
+	}
+
+	switch msg.String() {
+	case "up", "k":
+		if v.cursor > 0 {
+			v.cursor--
+		}
+	case "down", "j":
+		if v.cursor < len(v.listed)-1 {
+			v.cursor++
+		}
+	case "enter", "l", "right":
+		if v.cursor < len(v.listed) {
+			item := v.listed[v.cursor].Item
+			m.showVaultItem(&item)
+		}
+	case "/":
+		v.cursor = 0
+		return v.query.Focus(), true
+	case "esc":
+		if v.query.Value() == "" {
+			return nil, false
+		}
+		v.query.SetValue("")
+		m.filterVault()
+	case "s":
+		return m.vaultAction("Vault synced.", func(ctx context.Context, client *bitwarden.Client) error {
+			return client.Sync(ctx)
+		}), true
+	case "L":
+		return m.vaultAction("Vault locked.", func(ctx context.Context, client *bitwarden.Client) error {
+			return client.Lock(ctx)
+		}), true
+	default:
+		return nil, false
+	}
+	return nil, true
+}
+
+// handleVaultItemKey handles the keys of the item detail
+func (m *Model) handleVaultItemKey(msg tea.KeyMsg) (tea.Cmd, bool) {
+	v := &m.vault
+	switch msg.String() {
+	case "up", "k":
+		if v.fieldCursor > 0 {
+			v.fieldCursor--
+		}
+	case "down", "j":
+		if v.fieldCursor < len(v.fields)-1 {
+			v.fieldCursor++
+		}
+	case " ":
+		if v.fieldCursor < len(v.fields) {
+			selector := v.fields[v.fieldCursor].Selector
+			if v.marked[selector] {
+				delete(v.marked, selector)
+			} else {
+				v.marked[selector] = true
+			}
+		}
+	case "v":
+		v.reveal = !v.reveal
+	case "c", "y":
+		return m.copyField(), true
+	case "t":
+		m.addToTemplate()
+	default:
+		return nil, false
+	}
+	return nil, true
+}
+
+// copyField copies the value of the field under the cursor to the clipboard
+// and returns the command clearing it again after clipboardTimeout
+func (m *Model) copyField() tea.Cmd {
+	v := &m.vault
+	if v.fieldCursor >= len(v.fields) {
+		return nil
+	}
+	field := v.fields[v.fieldCursor]
+	v.message, v.actionErr = "", nil
+	if err := writeClipboard(field.Value); err != nil {
+		v.actionErr = fmt.Errorf("failed to copy %s: %w", field.Selector, err)
+		return nil
+	}
+	v.message = fmt.Sprintf("Copied %s, the clipboard is cleared in %v.", field.Selector, clipboardTimeout)
+
+	value := field.Value
+	m.copied = value
+	return tea.Tick(clipboardTimeout, func(time.Time) tea.Msg {
+		return clipboardClearMsg{value: value}
+	})
+}
+
+// clearClipboard empties the clipboard if it still holds value
+func clearClipboard(value string) {
+	if current, err := readClipboard(); err == nil && current == value {
+		_ = writeClipboard("")
+	}
+}
+
+// clearCopied clears the last copied value from the clipboard before it
+// times out, for when the TUI exits
+func (m *Model) clearCopied() {
+	if m.copied != "" {
+		clearClipboard(m.copied)
+		m.copied = ""
+	}
+}
+
+// addToTemplate appends the assignments of the selected fields of the item,
+// or of all its fields if none are selected, to bitwarden.template_path.
+// The variables are named after the item and the fields.
+func (m *Model) addToTemplate() {
+	v := &m.vault
+	v.message, v.actionErr = "", nil
+	if m.templatePath == "" {
+		v.actionErr = errors.New("bitwarden.template_path is not set")
+		return
+	}
+
+	var vars []bitwarden.TemplateVariable
+	for _, field := range v.fields {
+		if len(v.marked) == 0 || v.marked[field.Selector] {
+			vars = append(vars, bitwarden.TemplateVariable{Name: bitwarden.DefaultVariable(v.item.Name, field.Selector), Field: field})
+		}
+	}
+	if err := bitwarden.WriteTemplate(m.templatePath, v.item, vars, bitwarden.FormatShell, bitwarden.TemplateAppend); err != nil {
+		v.actionErr = err
+		return
+	}
+	v.message = fmt.Sprintf("Added %d variable(s) to %s.", len(vars), m.templatePath)
+	v.marked = make(map[string]bool)
+}
+
+// bitwardenView renders the Bitwarden manager
+func (m *Model) bitwardenView() string {
+	v := &m.vault
+	switch {
+	case m.bitwarden == nil:
+		return quitTextStyle.Render(renderError("Opening the Bitwarden manager", bitwarden.ErrNotInstalled))
+	case m.loading && v.status == nil:
+		return quitTextStyle.Render(m.spinner.View() + " Checking the vault... (esc to cancel)")
+	case v.err != nil:
+		return quitTextStyle.Render(renderError("Loading the vault", v.err))
+	case v.status == nil:
+		return quitTextStyle.Render("The vault has not been checked. Press 'r' to reload or 'h' to go back.")
+	}
+	if v.item != nil {
+		return m.vaultItemView()
+	}
+
+	var content strings.Builder
+	content.WriteString("Bitwarden Vault")
+	if m.loading {
+		content.WriteString("  " + m.spinner.View() + " working")
+	}
+	content.WriteString("\n" + describeVault(v.status) + "\n\n")
+
+	switch v.status.Status {
+	case bitwarden.StatusUnauthenticated:
+		content.WriteString("You are not logged in. Run 'bw login' in a terminal, then press 'r' to reload.\n")
+		content.WriteString(m.vaultMessage())
+		content.WriteString("\nr reload · h back\n")
+		return quitTextStyle.Render(content.String())
+	case bitwarden.StatusLocked:
+		content.WriteString("The vault is locked.\n\n" + v.password.View() + "\n")
+		content.WriteString(m.vaultMessage())
+		content.WriteString("\nenter unlock · esc back\n")
+		return quitTextStyle.Render(content.String())
+	}
+
+	start, end := visibleRange(v.cursor, len(v.listed), m.maxFileDisplay)
+	line := 4 + v.cursor - start
+	switch {
+	case v.query.Focused():
+		content.WriteString(v.query.View() + "\n\n")
+		line += 2
+	case v.query.Value() != "":
+		content.WriteString(fmt.Sprintf("Search: %q (esc to clear)\n\n", v.query.Value()))
+		line += 2
+	}
+	if len(v.listed) == 0 {
+		content.WriteString("  No items found.\n")
+	}
+	if start > 0 {
+		content.WriteString(fmt.Sprintf("  ... %d more above\n", start))
+		line++
+	}
+
+	for i := start; i < end; i++ {
+		item := v.listed[i]
+		marker := " "
+		if v.cursor == i {
+			marker = "→"
+		}
+		username := ""
+		if item.Login != nil {
+			username = item.Login.Username
+		}
+		padding := strings.Repeat(" ", max(32-len([]rune(item.Name)), 1))
+		content.WriteString(fmt.Sprintf("%s %s%s%-9s %s\n", marker, renderPath(item.Name, item.matched), padding, item.Type, username))
+	}
+	if end < len(v.listed) {
+		content.WriteString(fmt.Sprintf("  ... and %d more\n", len(v.listed)-end))
+	}
+
+	if v.query.Value() != "" {
+		content.WriteString(fmt.Sprintf("\n%d of %d items shown\n", len(v.listed), len(v.items)))
+	} else {
+		content.WriteString(fmt.Sprintf("\n%d items\n", len(v.items)))
+	}
+	content.WriteString(m.vaultMessage())
+	content.WriteString(vaultHelp + "\n")
+
+	return m.scrollView(content.String(), line)
+}
+
+// vaultItemView renders the fields of the item shown in detail. Values other
+// than the username are masked until they are revealed.
+func (m *Model) vaultItemView() string {
+	v := &m.vault
+	item := v.item
+
+	var content strings.Builder
+	content.WriteString(fmt.Sprintf("%s (%s)\n", item.Name, item.Type))
+	line := 3 + v.fieldCursor
+	if item.Login != nil {
+		for _, uri := range item.Login.URIs {
+			content.WriteString(uri.URI + "\n")
+			line++
+		}
+	}
+	content.WriteString("ID " + item.ID + "\n\n")
+
+	if len(v.fields) == 0 {
+		content.WriteString("  This item has no fields to copy.\n")
+	}
+	width := 0
+	for _, field := range v.fields {
+		width = max(width, len(field.Selector))
+	}
+	for i, field := range v.fields {
+		marker := " "
+		if v.fieldCursor == i {
+			marker = "→"
+		}
+		mark := " "
+		if v.marked[field.Selector] {
+			mark = "✓"
+		}
+		value := "••••••••"
+		if v.reveal || field.Selector == "login.username" {
+			value = field.Value
+			if first, _, multiline := strings.Cut(value, "\n"); multiline {
+				value = first + " …"
+			}
+		}
+		content.WriteString(fmt.Sprintf("%s %s %-*s  %s\n", marker, mark, width, field.Selector, value))
+	}
+
+	content.WriteString("\n")
+	content.WriteString(m.vaultMessage())
+	if m.templatePath != "" {
+		content.WriteString("Templates are added to " + m.templatePath + "\n")
+	}
+	content.WriteString(vaultItemHelp + "\n")
+
+	return m.scrollView(content.String(), line)
+}
+
+// vaultMessage renders the result of the last action, if any
+func (m *Model) vaultMessage() string {
+	v := &m.vault
+	switch {
+	case v.actionErr != nil:
+		text := fmt.Sprintf("Error: %v", v.actionErr)
+		if hint := bitwarden.Remediation(v.actionErr); hint != "" && !errors.Is(v.actionErr, bitwarden.ErrLocked) {
+			text += "\nHint: " + hint
+		}
+		return logErrorStyle.Render(text) + "\n"
+	case v.message != "":
+		return logOKStyle.Render(v.message) + "\n"
+	}
+	return ""
+}
+
+// describeVault summarizes the state of the vault in one line
+func describeVault(status *bitwarden.Status) string {
+	parts := []string{"Status: " + status.Status}
+	if status.UserEmail != "" {
+		parts = append(parts, status.UserEmail)
+	}
+	if status.ServerURL != "" {
+		parts = append(parts, status.ServerURL)
+	}
+	if !status.LastSync.IsZero() {
+		parts = append(parts, "last sync "+status.LastSync.Local().Format("2006-01-02 15:04"))
+	}
+	return strings.Join(parts, " · ")
+}
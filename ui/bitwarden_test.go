@@ -0,0 +1,255 @@
+package ui
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"chezmoi-tui/internal/bitwarden"
+	"chezmoi-tui/internal/chezmoi/chezmoitest"
+)
+
+// fakeVault is a fake bw with two items, unlocked by the master password
+// hunter2 and readable when BW_SESSION is "key"
+const fakeVault = `#!/bin/sh
+if [ "$1" = "unlock" ]; then
+	eval password=\"\$$4\"
+	[ "$password" = "hunter2" ] || { echo "Invalid master password." >&2; exit 1; }
+	echo "key"; exit 0
+fi
+if [ "$BW_SESSION" != "key" ]; then
+	[ "$1" = "status" ] && { echo '{"status":"locked"}'; exit 0; }
+	echo "Vault is locked." >&2; exit 1
+fi
+case "$1 $2" in
+"status ") echo '{"userEmail":"jane@example.com","status":"unlocked"}' ;;
+"list items") echo '[{"id":"i1","type":1,"name":"GitHub","login":{"username":"octocat","password":"s3cret","uris":[{"uri":"https://github.com"}]},"fields":[{"name":"token","value":"ghp_123","type":1}]},{"id":"i2","type":2,"name":"Notes","notes":"remember"}]' ;;
+"lock ") echo "Your vault is locked." ;;
+*) echo "unexpected" >&2; exit 1 ;;
+esac
+`
+
+// newVaultModel returns a model whose Bitwarden manager runs a fake bw and
+// shows the vault. The session expires after timeout, or never if it is
+// zero.
+func newVaultModel(t *testing.T, timeout time.Duration) *Model {
+	t.Helper()
+	t.Setenv(bitwarden.SessionEnv, "")
+
+	dir := t.TempDir()
+	bw := filepath.Join(dir, "bw")
+	if err := os.WriteFile(bw, []byte(fakeVault), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	session := bitwarden.NewSession(filepath.Join(dir, "cache", "bw-session"), timeout, nil)
+	client, err := bitwarden.New(bitwarden.WithBinaryPath(bw), bitwarden.WithSession(session))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := newTestModel(t, chezmoitest.NewFake())
+	m.bitwarden = client
+	m.templatePath = filepath.Join(dir, "source", "dot_secrets.tmpl")
+	return m
+}
+
+// stubClipboard replaces the system clipboard with a string holding
+// "previous" for the duration of the test
+func stubClipboard(t *testing.T) *string {
+	t.Helper()
+
+	clipboard := "previous"
+	write, read := writeClipboard, readClipboard
+	writeClipboard = func(text string) error { clipboard = text; return nil }
+	readClipboard = func() (string, error) { return clipboard, nil }
+	t.Cleanup(func() { writeClipboard, readClipboard = write, read })
+	return &clipboard
+}
+
+// openVault opens the Bitwarden manager from the main menu
+func openVault(t *testing.T, m *Model) {
+	t.Helper()
+
+	selectMenu(t, m, "Bitwarden Manager")
+	runCmd(m, press(m, "enter"))
+	if m.screen != screenBitwarden {
+		t.Fatalf("Expected the Bitwarden manager, got %v", m.screen)
+	}
+}
+
+func TestVaultUnlockAndLock(t *testing.T) {
+	m := newVaultModel(t, 0)
+	openVault(t, m)
+
+	if view := m.View(); !strings.Contains(view, "The vault is locked") || !m.vault.password.Focused() {
+		t.Fatalf("Expected the password prompt, got:\n%s", view)
+	}
+	press(m, "wrong")
+	if view := m.View(); strings.Contains(view, "wrong") || !strings.Contains(view, "•••••") {
+		t.Errorf("Expected the password to be masked, got:\n%s", view)
+	}
+	runCmd(m, press(m, "enter"))
+	if view := m.View(); !strings.Contains(view, "Invalid master password") || !m.vault.password.Focused() {
+		t.Fatalf("Expected the password to be asked again, got:\n%s", view)
+	}
+
+	press(m, "hunter2")
+	runCmd(m, press(m, "enter"))
+	view := m.View()
+	for _, expected := range []string{"Vault unlocked.", "Status: unlocked · jane@example.com", "GitHub", "octocat", "Notes", "2 items"} {
+		if !strings.Contains(view, expected) {
+			t.Errorf("Expected the item list to contain %q, got:\n%s", expected, view)
+		}
+	}
+	if key, _ := m.bitwarden.Session().Peek(); key != "key" {
+		t.Errorf("Expected the session key to be cached, got %q", key)
+	}
+
+	runCmd(m, press(m, "L"))
+	if view := m.View(); !strings.Contains(view, "Vault locked.") || strings.Contains(view, "GitHub") || !m.vault.password.Focused() {
+		t.Errorf("Expected the items to be hidden by locking, got:\n%s", view)
+	}
+	press(m, "esc")
+	if m.screen != screenMenu {
+		t.Errorf("Expected esc to leave the password prompt, got %v", m.screen)
+	}
+}
+
+func TestVaultItem(t *testing.T) {
+	m := newVaultModel(t, 0)
+	if err := m.bitwarden.Session().Save("key"); err != nil {
+		t.Fatal(err)
+	}
+	openVault(t, m)
+
+	press(m, "/")
+	press(m, "nts")
+	press(m, "enter")
+	if len(m.vault.listed) != 1 || m.vault.listed[0].Name != "Notes" {
+		t.Fatalf("Expected the search to list Notes, got %+v", m.vault.listed)
+	}
+	press(m, "esc")
+	press(m, "enter")
+	if m.vault.item == nil || m.vault.item.Name != "GitHub" {
+		t.Fatalf("Expected the GitHub item to be shown, got %+v", m.vault.item)
+	}
+
+	view := m.View()
+	if !strings.Contains(view, "login.username  octocat") || strings.Contains(view, "s3cret") || strings.Contains(view, "ghp_123") {
+		t.Errorf("Expected the secrets to be masked, got:\n%s", view)
+	}
+	press(m, "v")
+	if view := m.View(); !strings.Contains(view, "login.password  s3cret") {
+		t.Errorf("Expected the secrets to be revealed, got:\n%s", view)
+	}
+
+	clipboard := stubClipboard(t)
+	press(m, "down")
+	if cmd := press(m, "c"); cmd == nil || *clipboard != "s3cret" {
+		t.Fatalf("Expected the password to be copied and cleared later, got %q", *clipboard)
+	}
+	if view := m.View(); !strings.Contains(view, "Copied login.password, the clipboard is cleared in 30s.") {
+		t.Errorf("Expected the copy to be reported, got:\n%s", view)
+	}
+	m.Update(clipboardClearMsg{value: "s3cret"})
+	if *clipboard != "" {
+		t.Errorf("Expected the clipboard to be cleared, got %q", *clipboard)
+	}
+	*clipboard = "copied elsewhere"
+	m.Update(clipboardClearMsg{value: "s3cret"})
+	if *clipboard != "copied elsewhere" {
+		t.Errorf("Expected a replaced clipboard to be kept, got %q", *clipboard)
+	}
+
+	press(m, " ")
+	press(m, "down")
+	press(m, " ")
+	press(m, "t")
+	data, err := os.ReadFile(m.templatePath)
+	if err != nil {
+		t.Fatalf("Expected the template to be written: %v", err)
+	}
+	if s := string(data); !strings.Contains(s, "export GITHUB_PASSWORD=") || !strings.Contains(s, "export GITHUB_TOKEN=") || strings.Contains(s, "USERNAME") {
+		t.Errorf("Expected the selected fields to be assigned, got:\n%s", s)
+	}
+	if view := m.View(); !strings.Contains(view, "Added 2 variable(s)") {
+		t.Errorf("Expected the template to be reported, got:\n%s", view)
+	}
+	press(m, "t")
+	if view := m.View(); !strings.Contains(view, "the template already sets GITHUB_PASSWORD") {
+		t.Errorf("Expected assigned variables to be refused, got:\n%s", view)
+	}
+
+	press(m, "h")
+	if m.screen != screenBitwarden || m.vault.item != nil {
+		t.Errorf("Expected to return to the item list, got %v", m.screen)
+	}
+	press(m, "h")
+	if m.screen != screenMenu {
+		t.Errorf("Expected to return to the menu, got %v", m.screen)
+	}
+}
+
+func TestVaultClipboardClearedOnQuit(t *testing.T) {
+	m := newVaultModel(t, 0)
+	if err := m.bitwarden.Session().Save("key"); err != nil {
+		t.Fatal(err)
+	}
+	openVault(t, m)
+	clipboard := stubClipboard(t)
+
+	press(m, "enter")
+	press(m, "down")
+	press(m, "c")
+	if *clipboard != "s3cret" {
+		t.Fatalf("Expected the password to be copied, got %q", *clipboard)
+	}
+
+	// Quit before the clipboard timeout; RunTUI clears the clipboard after
+	// the program exits
+	press(m, "h")
+	press(m, "h")
+	if cmd := press(m, "q"); cmd == nil {
+		t.Fatal("Expected q to quit")
+	}
+	m.clearCopied()
+	if *clipboard != "" {
+		t.Errorf("Expected the clipboard to be cleared on quit, got %q", *clipboard)
+	}
+}
+
+func TestVaultSessionExpires(t *testing.T) {
+	m := newVaultModel(t, 300*time.Millisecond)
+	if err := m.bitwarden.Session().Save("key"); err != nil {
+		t.Fatal(err)
+	}
+	if m.Init() == nil {
+		t.Error("Expected the cached session to be checked at startup")
+	}
+
+	// Opening the vault uses the session and schedules its check, which
+	// runCmd waits for
+	start := time.Now()
+	openVault(t, m)
+	if elapsed := time.Since(start); elapsed < 300*time.Millisecond {
+		t.Errorf("Expected the vault to be locked after the idle timeout, took %v", elapsed)
+	}
+	view := m.View()
+	if !strings.Contains(view, "Vault locked after 300ms without use.") || strings.Contains(view, "GitHub") || !m.vault.password.Focused() {
+		t.Errorf("Expected the expired session to lock the vault, got:\n%s", view)
+	}
+	if _, err := os.Stat(m.bitwarden.Session().Path()); !os.IsNotExist(err) {
+		t.Errorf("Expected the session cache to be removed, got %v", err)
+	}
+}
+
+func TestVaultNotInstalled(t *testing.T) {
+	m := newTestModel(t, chezmoitest.NewFake())
+	openVault(t, m)
+
+	if view := m.View(); !strings.Contains(view, "Bitwarden CLI (bw) not found") || !strings.Contains(view, "Install the Bitwarden CLI") {
+		t.Errorf("Expected the missing bw to be explained, got:\n%s", view)
+	}
+}
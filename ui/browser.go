@@ -0,0 +1,321 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"chezmoi-tui/internal/chezmoi"
+	"chezmoi-tui/internal/integration"
+)
+
+// fileAction is an action of the file browser on the selected targets whose
+// output is streamed into the log pane
+type fileAction struct {
+	// title is shown above the output
+	title string
+	// confirm is the prompt shown before running the action, formatted with
+	// the number of targets, or empty to run it immediately
+	confirm string
+	// run runs the action on absolute target paths
+	run func(*integration.ChezmoiIntegration, context.Context, chezmoi.IOStreams, ...string) error
+	// force, if set, replaces run when the targets have local changes that
+	// the action overwrites. They are listed in the prompt.
+	force func(*integration.ChezmoiIntegration, context.Context, chezmoi.IOStreams, ...string) error
+	// refresh reloads the status when returning to the file browser
+	refresh bool
+}
+
+// fileActions maps the file browser keys to their actions
+var fileActions = map[string]fileAction{
+	"a": {
+		title:   "Applying",
+		confirm: "Apply %d file(s) to your destination directory? (y/n)",
+		run:     applyFiles(false),
+		force:   applyFiles(true),
+		refresh: true,
+	},
+	"r": {
+		title:   "Re-adding",
+		run:     (*integration.ChezmoiIntegration).StreamReAddFiles,
+		refresh: true,
+	},
+	"x": {
+		title:   "Forgetting",
+		confirm: "Stop managing %d file(s)? Their source state will be removed. (y/n)",
+		run:     (*integration.ChezmoiIntegration).StreamForgetFiles,
+		refresh: true,
+	},
+}
+
+// applyFiles returns a file action that applies the targets, overwriting
+// local changes if overwrite is set
+func applyFiles(overwrite bool) func(*integration.ChezmoiIntegration, context.Context, chezmoi.IOStreams, ...string) error {
+	opts := applyOptions(overwrite)
+	return func(integ *integration.ChezmoiIntegration, ctx context.Context, streams chezmoi.IOStreams, targets ...string) error {
+		return integ.StreamApplyFiles(ctx, streams, opts, targets...)
+	}
+}
+
+// browserHelp lists the file browser key bindings
+const browserHelp = "↑/↓ move · space select · d diff · a apply · r re-add · e edit source · x forget · o open in $EDITOR · i inspect · h back\n" +
+	"/ filter · M/A/D/S modified/added/deleted/scripts · T/E/P template/encrypted/private · . this directory · esc clear filters · t tree view"
+
+// editorFinishedMsg reports that an editor started by the file browser exited
+type editorFinishedMsg struct {
+	err error
+}
+
+// handleBrowserKey handles a key press on the status screen and reports
+// whether it was consumed
+func (m *Model) handleBrowserKey(msg tea.KeyMsg) (tea.Cmd, bool) {
+	key := msg.String()
+	if key == "t" && !m.loading {
+		return m.toggleTree(), true
+	}
+	if m.treeMode {
+		return m.handleTreeKey(msg)
+	}
+
+	switch key {
+	case "up", "k":
+		if m.fileCursor > 0 {
+			m.fileCursor--
+		}
+		return nil, true
+	case "down", "j":
+		if m.fileCursor < len(m.listed)-1 {
+			m.fileCursor++
+		}
+		return nil, true
+	}
+
+	// Filters and actions need a loaded list of files
+	if m.loading || len(m.fileStatus) == 0 {
+		return nil, false
+	}
+	if cmd, ok := m.handleFilterKey(msg); ok {
+		return cmd, true
+	}
+	if len(m.listed) == 0 {
+		return nil, false
+	}
+
+	switch key {
+	case " ":
+		path := m.listed[m.fileCursor].Path
+		if m.selected[path] {
+			delete(m.selected, path)
+		} else {
+			m.selected[path] = true
+		}
+		return nil, true
+	}
+	return m.handleFileAction(key)
+}
+
+// handleFileAction starts the file action bound to key on the targets and
+// reports whether key is bound to one
+func (m *Model) handleFileAction(key string) (tea.Cmd, bool) {
+	switch key {
+	case "d":
+		// The selection is kept so that the diffed files can be applied next
+		targets := m.targets()
+		m.returnScreen = screenStatus
+		m.refreshOnBack = false
+		return m.loadDiff("Diff "+targetSummary(targets), targets...), true
+	case "e":
+		return m.editSource(), true
+	case "o":
+		return m.openInEditor(), true
+	case "i":
+		return m.inspectTarget(), true
+	}
+
+	action, ok := fileActions[key]
+	if !ok {
+		return nil, false
+	}
+	m.pending = &action
+	m.pendingTargets = m.targets()
+	m.pendingLocal = nil
+	if action.force != nil {
+		m.pendingLocal = localChanges(m.targetEntries(m.pendingTargets))
+	}
+	m.screen = screenLog
+	if action.confirm == "" {
+		return m.runPendingAction(), true
+	}
+	return nil, true
+}
+
+// handleActionConfirmation answers the prompt of a pending file action
+func (m *Model) handleActionConfirmation(msg tea.KeyMsg) tea.Cmd {
+	switch msg.String() {
+	case "y", "Y":
+		return m.runPendingAction()
+	case "n", "N", "esc":
+		m.pending, m.pendingLocal = nil, nil
+		m.screen = screenStatus
+	}
+	return nil
+}
+
+// confirmView renders the prompt of the pending file action
+func (m *Model) confirmView() string {
+	var content strings.Builder
+	m.writeLocalChanges(&content, m.pendingLocal)
+	if content.Len() > 0 {
+		content.WriteString("\n")
+	}
+	content.WriteString(fmt.Sprintf(m.pending.confirm, len(m.pendingTargets)))
+	return quitTextStyle.Render(strings.TrimPrefix(content.String(), "\n"))
+}
+
+// runPendingAction starts the pending file action, streaming its output
+// into the log pane
+func (m *Model) runPendingAction() tea.Cmd {
+	action, targets := *m.pending, m.pendingTargets
+	run := action.run
+	if len(m.pendingLocal) > 0 {
+		run = action.force
+	}
+	m.pending, m.pendingLocal = nil, nil
+	m.refreshOnBack = action.refresh
+	m.returnScreen = screenStatus
+	m.selected = make(map[string]bool)
+
+	ctx, op := m.startOperation()
+	m.log.Reset(action.title + " " + targetSummary(targets))
+
+	integ := m.integration
+	return startLogStream(ctx, op, func(ctx context.Context, streams chezmoi.IOStreams) error {
+		return run(integ, ctx, streams, targets...)
+	})
+}
+
+// editSource opens the source files of the targets with chezmoi edit,
+// handing the terminal over to the editor
+func (m *Model) editSource() tea.Cmd {
+	integ, targets := m.integration, m.targets()
+	m.selected = make(map[string]bool)
+
+	cmd := &chezmoiExec{run: func(streams chezmoi.IOStreams) error {
+		return integ.EditSourceFiles(context.Background(), streams, targets...)
+	}}
+	return tea.Exec(cmd, func(err error) tea.Msg {
+		return editorFinishedMsg{err: err}
+	})
+}
+
+// openInEditor opens the destination files of the targets in $VISUAL or
+// $EDITOR, falling back to vi
+func (m *Model) openInEditor() tea.Cmd {
+	cmd := editorCommand(m.targets())
+	m.selected = make(map[string]bool)
+	return tea.ExecProcess(cmd, func(err error) tea.Msg {
+		return editorFinishedMsg{err: err}
+	})
+}
+
+// editorCommand returns the command that opens files in $VISUAL or $EDITOR,
+// which may include arguments such as "code --wait"
+func editorCommand(files []string) *exec.Cmd {
+	editor := strings.Fields(os.Getenv("VISUAL"))
+	if len(editor) == 0 {
+		editor = strings.Fields(os.Getenv("EDITOR"))
+	}
+	if len(editor) == 0 {
+		editor = []string{"vi"}
+	}
+	return exec.Command(editor[0], append(editor[1:], files...)...)
+}
+
+// targetEntries returns the status entries of the targets, including the
+// entries inside target directories
+func (m *Model) targetEntries(targets []string) []chezmoi.StatusEntry {
+	var entries []chezmoi.StatusEntry
+	for _, entry := range m.fileStatus {
+		path := m.targetPath(entry.Path)
+		for _, target := range targets {
+			if path == target || strings.HasPrefix(path, target+string(filepath.Separator)) {
+				entries = append(entries, entry)
+				break
+			}
+		}
+	}
+	return entries
+}
+
+// targets returns the absolute paths of the selected files that are listed,
+// in list order, or of the file under the cursor if none are selected. In
+// the tree view it returns the file or directory under the cursor.
+func (m *Model) targets() []string {
+	if m.treeMode {
+		if m.treeCursor < len(m.treeRows) {
+			return []string{m.targetPath(m.treeRows[m.treeCursor].node.path)}
+		}
+		return nil
+	}
+
+	var targets []string
+	for _, entry := range m.listed {
+		if m.selected[entry.Path] {
+			targets = append(targets, m.targetPath(entry.Path))
+		}
+	}
+	if len(targets) == 0 && m.fileCursor < len(m.listed) {
+		targets = append(targets, m.targetPath(m.listed[m.fileCursor].Path))
+	}
+	return targets
+}
+
+// targetPath returns the absolute destination path of a target path listed
+// by chezmoi, in the destination directory resolved with the status
+func (m *Model) targetPath(path string) string {
+	if filepath.IsAbs(path) || m.destDir == "" {
+		return path
+	}
+	return filepath.Join(m.destDir, path)
+}
+
+// targetSummary describes the targets of an action in its title
+func targetSummary(targets []string) string {
+	if len(targets) == 1 {
+		return targets[0]
+	}
+	return fmt.Sprintf("%d files", len(targets))
+}
+
+// chezmoiExec adapts an interactive chezmoi command to tea.ExecCommand so
+// that it can take over the terminal from the TUI
+type chezmoiExec struct {
+	run     func(chezmoi.IOStreams) error
+	streams chezmoi.IOStreams
+}
+
+// Run implements tea.ExecCommand
+func (c *chezmoiExec) Run() error {
+	return c.run(c.streams)
+}
+
+// SetStdin implements tea.ExecCommand
+func (c *chezmoiExec) SetStdin(r io.Reader) {
+	c.streams.In = r
+}
+
+// SetStdout implements tea.ExecCommand
+func (c *chezmoiExec) SetStdout(w io.Writer) {
+	c.streams.Out = w
+}
+
+// SetStderr implements tea.ExecCommand
+func (c *chezmoiExec) SetStderr(w io.Writer) {
+	c.streams.Err = w
+}
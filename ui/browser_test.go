@@ -0,0 +1,189 @@
+package ui
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"chezmoi-tui/internal/chezmoi/chezmoitest"
+)
+
+// browserStatus is the status of .bashrc, .vimrc and .zshrc
+const browserStatus = " M .bashrc\n M .vimrc\n M .zshrc\n"
+
+// newStatusModel returns a model showing status, the output of chezmoi
+// status, in a destination directory of /home/user
+func newStatusModel(t *testing.T, fake *chezmoitest.Fake, status string) *Model {
+	t.Helper()
+
+	t.Setenv("HOME", "/home/user")
+	fake.On("status").Stdout(status)
+	m := newTestModel(t, fake)
+
+	selectMenu(t, m, "View Status")
+	runCmd(m, press(m, "enter"))
+	if expected := strings.Count(status, "\n"); len(m.fileStatus) != expected {
+		t.Fatalf("Expected %d files, got %+v", expected, m.fileStatus)
+	}
+	return m
+}
+
+func TestBrowserDestDir(t *testing.T) {
+	fake := chezmoitest.NewFake()
+	fake.On("dump-config").Stdout(`{"destDir":"/srv/dotfiles"}`)
+	fake.On("diff").Stdout("--- a/.bashrc\n+++ b/.bashrc\n")
+	m := newStatusModel(t, fake, browserStatus)
+
+	runCmd(m, press(m, "d"))
+	if fake.CallCount("diff", "--use-builtin-diff", "--no-pager", "--color=false", "/srv/dotfiles/.bashrc") != 1 {
+		t.Errorf("Expected the target in chezmoi's destDir, got %+v", fake.Calls())
+	}
+}
+
+func TestBrowserNavigation(t *testing.T) {
+	m := newStatusModel(t, chezmoitest.NewFake(), browserStatus)
+
+	press(m, "down")
+	press(m, "j")
+	press(m, "down")
+	if m.fileCursor != 2 {
+		t.Errorf("Expected the cursor to stop at the last file, got %d", m.fileCursor)
+	}
+	press(m, "up")
+	if m.fileCursor != 1 {
+		t.Errorf("Expected the cursor to move up, got %d", m.fileCursor)
+	}
+	if view := m.View(); !strings.Contains(view, "→   [ M] .vimrc") {
+		t.Errorf("Expected the cursor on .vimrc, got:\n%s", view)
+	}
+}
+
+func TestBrowserDiffCursorFile(t *testing.T) {
+	fake := chezmoitest.NewFake()
+	fake.On("diff").Stdout("--- a/.vimrc\n+++ b/.vimrc\n")
+	m := newStatusModel(t, fake, browserStatus)
+
+	press(m, "down")
+	runCmd(m, press(m, "d"))
+
+	if fake.CallCount("diff", "--use-builtin-diff", "--no-pager", "--color=false", "/home/user/.vimrc") != 1 {
+		t.Errorf("Expected a diff of the file under the cursor, got %+v", fake.Calls())
+	}
+	if m.screen != screenDiff || !strings.Contains(m.View(), "Diff /home/user/.vimrc") {
+		t.Errorf("Expected the diff viewer, got:\n%s", m.View())
+	}
+
+	// Returning from a diff does not reload the status
+	runCmd(m, press(m, "h"))
+	if m.screen != screenStatus || fake.CallCount("status") != 1 {
+		t.Errorf("Expected to return to the file browser without a reload, got %d status calls", fake.CallCount("status"))
+	}
+}
+
+func TestBrowserApplySelection(t *testing.T) {
+	fake := chezmoitest.NewFake()
+	fake.On("apply")
+	m := newStatusModel(t, fake, browserStatus)
+
+	press(m, " ")
+	press(m, "down")
+	press(m, "down")
+	press(m, " ")
+	if len(m.selected) != 2 {
+		t.Fatalf("Expected two selected files, got %v", m.selected)
+	}
+
+	press(m, "a")
+	if m.pending == nil || !strings.Contains(m.View(), "Apply 2 file(s)") {
+		t.Fatalf("Expected apply to ask for confirmation, got:\n%s", m.View())
+	}
+	runCmd(m, press(m, "y"))
+
+	// Without a terminal, a prompt from chezmoi must fail instead of hang
+	if fake.CallCount("apply", "--no-tty", "/home/user/.bashrc", "/home/user/.zshrc") != 1 {
+		t.Errorf("Expected apply of the selected files, got %+v", fake.Calls())
+	}
+	if len(m.selected) != 0 {
+		t.Error("Expected the selection to be cleared")
+	}
+
+	// Returning to the file browser reloads the status
+	runCmd(m, press(m, "h"))
+	if fake.CallCount("status") != 2 {
+		t.Errorf("Expected the status to be reloaded, got %d status calls", fake.CallCount("status"))
+	}
+}
+
+func TestBrowserApplyLocalChanges(t *testing.T) {
+	fake := chezmoitest.NewFake()
+	fake.On("apply")
+	m := newStatusModel(t, fake, "MM .bashrc\nA  .gitconfig\n M .zshrc\n")
+
+	press(m, " ")
+	press(m, "down")
+	press(m, " ")
+	press(m, "down")
+	press(m, " ")
+	press(m, "a")
+	_, local, _ := strings.Cut(m.View(), "1 file(s) changed since chezmoi last wrote them")
+	if !strings.Contains(local, ".bashrc") || strings.Contains(local, ".zshrc") {
+		t.Errorf("Expected the local changes of .bashrc to be listed, got:\n%s", m.View())
+	}
+	runCmd(m, press(m, "y"))
+
+	expected := []string{"apply", "--force", "/home/user/.bashrc", "/home/user/.gitconfig", "/home/user/.zshrc"}
+	if calls := fake.Calls(); !reflect.DeepEqual(calls[len(calls)-1].Args, expected) {
+		t.Errorf("Expected args %v, got %v", expected, calls[len(calls)-1].Args)
+	}
+}
+
+func TestBrowserForgetDeclined(t *testing.T) {
+	fake := chezmoitest.NewFake()
+	m := newStatusModel(t, fake, browserStatus)
+
+	press(m, "x")
+	press(m, "n")
+	if m.screen != screenStatus || m.pending != nil {
+		t.Error("Expected to return to the file browser")
+	}
+	if fake.CallCount("forget") != 0 {
+		t.Error("Expected forget not to run")
+	}
+}
+
+func TestBrowserEditAndReload(t *testing.T) {
+	fake := chezmoitest.NewFake()
+	m := newStatusModel(t, fake, browserStatus)
+
+	if cmd := press(m, "e"); cmd == nil {
+		t.Fatal("Expected edit to hand the terminal to chezmoi edit")
+	}
+
+	// chezmoi edit is run by Bubble Tea; simulate it exiting
+	runCmd(m, func() tea.Msg { return editorFinishedMsg{} })
+	if fake.CallCount("status") != 2 {
+		t.Errorf("Expected the status to be reloaded after editing, got %d status calls", fake.CallCount("status"))
+	}
+}
+
+func TestBrowserOpenInEditor(t *testing.T) {
+	t.Setenv("VISUAL", "")
+	t.Setenv("EDITOR", "code --wait")
+	cmd := editorCommand([]string{"/home/user/.bashrc"})
+	if expected := []string{"code", "--wait", "/home/user/.bashrc"}; !reflect.DeepEqual(cmd.Args, expected) {
+		t.Errorf("Expected the editor arguments to be split, got %q", cmd.Args)
+	}
+
+	t.Setenv("VISUAL", "nvim")
+	if cmd := editorCommand([]string{"a", "b"}); !reflect.DeepEqual(cmd.Args, []string{"nvim", "a", "b"}) {
+		t.Errorf("Expected $VISUAL to take precedence, got %q", cmd.Args)
+	}
+
+	t.Setenv("VISUAL", " ")
+	t.Setenv("EDITOR", "")
+	if cmd := editorCommand([]string{"a"}); !reflect.DeepEqual(cmd.Args, []string{"vi", "a"}) {
+		t.Errorf("Expected vi as the fallback, got %q", cmd.Args)
+	}
+}
@@ -0,0 +1,154 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"chezmoi-tui/internal/chezmoi"
+	"chezmoi-tui/internal/integration"
+)
+
+// attributeDescriptions explain the source state attributes, by the names
+// of chezmoi.SourceAttributes.Names
+var attributeDescriptions = map[string]string{
+	"create":     "only created if it does not exist",
+	"modify":     "script that modifies the existing file",
+	"remove":     "removed from the destination",
+	"run":        "script that is run by chezmoi apply",
+	"once":       "run once per content",
+	"onchange":   "run when its contents change",
+	"before":     "run before files are updated",
+	"after":      "run after files are updated",
+	"symlink":    "symbolic link to the contents",
+	"external":   "names below are not decoded",
+	"exact":      "entries not in the source state are removed",
+	"encrypted":  "encrypted in the source state",
+	"private":    "not accessible by group or others",
+	"readonly":   "not writable",
+	"empty":      "kept even if empty",
+	"executable": "executable",
+	"dot":        "name starts with a dot",
+	"template":   "contents are a template",
+}
+
+// detailLoadedMsg carries the details of an inspected target
+type detailLoadedMsg struct {
+	op      int
+	details *integration.TargetDetails
+	err     error
+}
+
+// inspectTarget starts loading the source details of the file or directory
+// under the cursor
+func (m *Model) inspectTarget() tea.Cmd {
+	var target string
+	switch {
+	case m.treeMode && m.treeCursor < len(m.treeRows):
+		target = m.treeRows[m.treeCursor].node.path
+	case !m.treeMode && m.fileCursor < len(m.listed):
+		target = m.listed[m.fileCursor].Path
+	default:
+		return nil
+	}
+	target = m.targetPath(target)
+
+	ctx, op := m.startOperation()
+	m.screen = screenDetail
+	m.returnScreen = screenStatus
+	m.refreshOnBack = false
+	m.details, m.detailErr = nil, nil
+
+	integ := m.integration
+	return func() tea.Msg {
+		details, err := integ.InspectTarget(ctx, target)
+		return detailLoadedMsg{op: op, details: details, err: err}
+	}
+}
+
+// detailView renders the source details of the inspected target
+func (m *Model) detailView() string {
+	switch {
+	case m.loading:
+		return quitTextStyle.Render(m.spinner.View() + " Inspecting source... (esc to cancel)")
+	case m.detailErr != nil:
+		return quitTextStyle.Render(renderError("Inspecting source", m.detailErr))
+	case m.details == nil:
+		return quitTextStyle.Render("Nothing to inspect. Press 'h' to go back.")
+	}
+
+	d := m.details
+	attrs := d.Attributes
+	var content strings.Builder
+	field := func(name, value string) {
+		content.WriteString(fmt.Sprintf("%-13s %s\n", name, value))
+	}
+
+	content.WriteString("Source of " + d.Target + "\n\n")
+	field("Source", d.SourceRelative)
+	field("", d.SourcePath)
+	field("Type", attrs.Type.String())
+	if attrs.Template {
+		field("Template", "yes, rendered by chezmoi apply")
+	} else {
+		field("Template", "no")
+	}
+
+	names := attrs.Names()
+	if len(names) == 0 {
+		field("Attributes", "none")
+	}
+	for i, name := range names {
+		label := ""
+		if i == 0 {
+			label = "Attributes"
+		}
+		field(label, fmt.Sprintf("%-11s %s", name, attributeDescriptions[name]))
+	}
+
+	if perm := attrs.Perm(); perm != 0 {
+		field("Permissions", fmt.Sprintf("%v %04o in the target state, before the umask", perm, perm))
+	} else {
+		field("Permissions", "none in the target state")
+	}
+	if d.Dest != nil {
+		field("", fmt.Sprintf("%v %04o in the destination", d.Dest.Mode(), d.Dest.Mode().Perm()))
+	} else {
+		field("", "not in the destination")
+	}
+
+	switch {
+	case d.CommitErr != nil:
+		field("Last commit", logStderrStyle.Render("unavailable: "+commitError(d.CommitErr)))
+	case d.Commit == nil:
+		field("Last commit", "not committed")
+	default:
+		c := d.Commit
+		hash := c.Hash
+		if len(hash) > 8 {
+			hash = hash[:8]
+		}
+		field("Last commit", fmt.Sprintf("%s %s %s", hash, c.Date.Format("2006-01-02"), c.Author))
+		field("", c.Subject)
+	}
+
+	content.WriteString("\nPress 'h' to go back.\n")
+	m.viewport.SetContent(content.String())
+	return m.viewport.View()
+}
+
+// commitError describes why the last commit could not be looked up by what
+// git printed, e.g. that the source directory is not a git repository,
+// rather than by the whole git command line
+func commitError(err error) string {
+	var execErr *chezmoi.ExecError
+	if errors.As(err, &execErr) {
+		if stderr := strings.TrimSpace(execErr.Stderr); stderr != "" {
+			line, _, _ := strings.Cut(stderr, "\n")
+			return line
+		}
+	}
+	return err.Error()
+}
@@ -0,0 +1,53 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+
+	"chezmoi-tui/internal/chezmoi/chezmoitest"
+)
+
+func TestInspectTarget(t *testing.T) {
+	fake := chezmoitest.NewFake()
+	m := newStatusModel(t, fake, browserStatus)
+
+	fake.On("source-path").Stdout("/src\n")
+	fake.On("source-path", "/home/user/.bashrc").Stdout("/src/private_dot_bashrc.tmpl\n")
+	fake.On("git").Stdout("0123abcdef\x00Jane Doe\x002024-03-01T12:30:00Z\x00Add aliases\n")
+	runCmd(m, press(m, "i"))
+	if m.screen != screenDetail {
+		t.Fatalf("Expected the detail screen, got %v", m.screen)
+	}
+
+	view := m.View()
+	for _, expected := range []string{
+		"Source of /home/user/.bashrc",
+		"private_dot_bashrc.tmpl",
+		"Template      yes",
+		"private     not accessible by group or others",
+		"-rw------- 0600 in the target state",
+		"not in the destination",
+		"0123abcd 2024-03-01 Jane Doe",
+		"Add aliases",
+	} {
+		if !strings.Contains(view, expected) {
+			t.Errorf("Expected the details to contain %q, got:\n%s", expected, view)
+		}
+	}
+
+	press(m, "h")
+	if m.screen != screenStatus {
+		t.Errorf("Expected to return to the status screen, got %v", m.screen)
+	}
+}
+
+func TestInspectTargetError(t *testing.T) {
+	fake := chezmoitest.NewFake()
+	m := newStatusModel(t, fake, browserStatus)
+
+	fake.On("source-path").Fail(1, "chezmoi: not managed")
+	runCmd(m, press(m, "i"))
+	if view := m.View(); !strings.Contains(view, "not managed") {
+		t.Errorf("Expected the error to be shown, got:\n%s", view)
+	}
+}
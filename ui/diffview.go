@@ -0,0 +1,155 @@
+package ui
+
+import (
+	"fmt"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"chezmoi-tui/internal/diff"
+	"chezmoi-tui/internal/integration"
+)
+
+// diffLoadedMsg carries the result of an asynchronous diff load
+type diffLoadedMsg struct {
+	op     int
+	output string
+	err    error
+}
+
+// patchPreparedMsg carries the selected hunks applied in memory, ready to
+// be previewed
+type patchPreparedMsg struct {
+	op      int
+	target  integration.PatchTarget
+	patches []integration.FilePatch
+	err     error
+}
+
+// patchWrittenMsg reports the result of writing a previewed patch
+type patchWrittenMsg struct {
+	op  int
+	err error
+}
+
+// loadDiff starts loading the diff of the given targets, or of all targets
+// if none are given, in the background. The load can be cancelled with esc
+// while it is running.
+func (m *Model) loadDiff(title string, targets ...string) tea.Cmd {
+	ctx, op := m.startOperation()
+	m.screen = screenDiff
+	m.diffErr = nil
+	m.diffTargets = targets
+	m.diff.title = title
+	m.diff.selectable = true
+	m.diff.SetDiff("")
+
+	integ := m.integration
+	return func() tea.Msg {
+		output, err := integ.DiffFiles(ctx, targets...)
+		return diffLoadedMsg{op: op, output: output, err: err}
+	}
+}
+
+// handleDiffKey handles the hunk actions of the diff screen and reports
+// whether the key was consumed
+func (m *Model) handleDiffKey(msg tea.KeyMsg) (tea.Cmd, bool) {
+	switch msg.String() {
+	case "a":
+		return m.preparePatch(integration.PatchDestination), true
+	case "r":
+		return m.preparePatch(integration.PatchSource), true
+	}
+	return nil, false
+}
+
+// preparePatch applies the selected hunks, or the current one, to the
+// destination or source files in memory so that the result can be previewed
+func (m *Model) preparePatch(target integration.PatchTarget) tea.Cmd {
+	files := m.diff.SelectedFiles()
+	if len(files) == 0 {
+		return nil
+	}
+
+	ctx, op := m.startOperation()
+	integ := m.integration
+	return func() tea.Msg {
+		patches, err := integ.PreparePatch(ctx, target, files)
+		return patchPreparedMsg{op: op, target: target, patches: patches, err: err}
+	}
+}
+
+// showPatch previews a prepared patch before it is written
+func (m *Model) showPatch(msg patchPreparedMsg) {
+	if msg.err != nil {
+		m.diff.notice = logErrorStyle.Render(fmt.Sprintf("Cannot apply hunks: %v", msg.err))
+		return
+	}
+
+	var files []diff.File
+	hunks := 0
+	for _, patch := range msg.patches {
+		files = append(files, patch.Diff)
+		hunks += len(patch.Diff.Hunks)
+	}
+	m.patches = msg.patches
+	m.patchTarget = msg.target
+	m.patchView.title = fmt.Sprintf("Preview: write %d hunk(s) to the %s", hunks, msg.target)
+	m.patchView.SetDiff(diff.Format(files))
+	m.screen = screenPatch
+}
+
+// handlePatchConfirmation answers the prompt below a patch preview
+func (m *Model) handlePatchConfirmation(msg tea.KeyMsg) tea.Cmd {
+	switch msg.String() {
+	case "y", "Y":
+		patches := m.patches
+		m.patches = nil
+		_, op := m.startOperation()
+		return func() tea.Msg {
+			return patchWrittenMsg{op: op, err: integration.WritePatch(patches)}
+		}
+	case "n", "N", "esc":
+		m.patches = nil
+		m.screen = screenDiff
+		return nil
+	}
+	return m.patchView.Update(msg)
+}
+
+// finishPatch reports the result of writing a patch and reloads the diff
+func (m *Model) finishPatch(err error) tea.Cmd {
+	m.screen = screenDiff
+	if err != nil {
+		m.diff.notice = logErrorStyle.Render(fmt.Sprintf("Failed to write hunks: %v", err))
+		return nil
+	}
+
+	cmd := m.loadDiff(m.diff.title, m.diffTargets...)
+	m.diff.notice = logOKStyle.Render(fmt.Sprintf("Wrote hunks to the %s", m.patchTarget))
+	return cmd
+}
+
+// diffView renders the diff screen
+func (m *Model) diffView() string {
+	switch {
+	case m.loading:
+		return quitTextStyle.Render(m.spinner.View() + " Loading diff... (esc to cancel)")
+	case m.diffErr != nil:
+		return quitTextStyle.Render(renderError("Loading diff", m.diffErr))
+	case m.diff.Empty():
+		message := "No differences, your destination directory matches the target state.\n\nPress 'h' to go back."
+		if m.diff.notice != "" {
+			message = m.diff.notice + "\n\n" + message
+		}
+		return quitTextStyle.Render(message)
+	}
+	return m.diff.View()
+}
+
+// patchPreviewView renders the preview of a patch with its confirmation prompt
+func (m *Model) patchPreviewView() string {
+	if m.loading {
+		return quitTextStyle.Render(m.spinner.View() + " Writing hunks...")
+	}
+	return m.patchView.View() + "\n" + fmt.Sprintf("Write these changes to the %s? (y/n)", m.patchTarget)
+}
@@ -0,0 +1,73 @@
+package ui
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"chezmoi-tui/internal/chezmoi/chezmoitest"
+)
+
+func TestApplyHunkToDestination(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	bashrc := filepath.Join(home, ".bashrc")
+	if err := os.WriteFile(bashrc, []byte("# .bashrc\nexport EDITOR=vi\nexport PAGER=less\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	fake := chezmoitest.NewFake()
+	fake.On("diff").Stdout("diff --git a/.bashrc b/.bashrc\n--- a/.bashrc\n+++ b/.bashrc\n@@ -1,3 +1,3 @@\n # .bashrc\n-export EDITOR=vi\n+export EDITOR=nvim\n export PAGER=less\n")
+	m := newTestModel(t, fake)
+
+	selectMenu(t, m, "Diff Changes")
+	runCmd(m, press(m, "enter"))
+	// Hunks are parsed from the builtin diff, without a pager or colours
+	if calls := fake.Calls(); len(calls) == 0 || strings.Join(calls[len(calls)-1].Args, " ") != "diff --use-builtin-diff --no-pager --color=false" {
+		t.Fatalf("Expected a plain unified diff, got %+v", calls)
+	}
+	press(m, " ")
+	runCmd(m, press(m, "a"))
+
+	if m.screen != screenPatch {
+		t.Fatalf("Expected a preview of the patch, got:\n%s", m.View())
+	}
+	view := m.View()
+	for _, expected := range []string{"write 1 hunk(s) to the destination", "+export EDITOR=nvim", "(y/n)"} {
+		if !strings.Contains(view, expected) {
+			t.Errorf("Expected %q in the preview, got:\n%s", expected, view)
+		}
+	}
+	if data, _ := os.ReadFile(bashrc); strings.Contains(string(data), "nvim") {
+		t.Fatal("Expected nothing to be written before confirmation")
+	}
+
+	runCmd(m, press(m, "y"))
+	if data, _ := os.ReadFile(bashrc); string(data) != "# .bashrc\nexport EDITOR=nvim\nexport PAGER=less\n" {
+		t.Errorf("Expected the hunk to be written, got:\n%s", data)
+	}
+	if m.screen != screenDiff || fake.CallCount("diff") != 2 {
+		t.Errorf("Expected the diff to be reloaded, got %d diff calls", fake.CallCount("diff"))
+	}
+	if !strings.Contains(m.View(), "Wrote hunks to the destination") {
+		t.Errorf("Expected a confirmation, got:\n%s", m.View())
+	}
+}
+
+func TestMergeTemplateHunkRejected(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	fake := chezmoitest.NewFake()
+	fake.On("diff").Stdout("--- a/.bashrc\n+++ b/.bashrc\n@@ -1 +1 @@\n-a\n+b\n")
+	fake.On("source-path").Stdout("/src/dot_bashrc.tmpl\n")
+	m := newTestModel(t, fake)
+
+	selectMenu(t, m, "Diff Changes")
+	runCmd(m, press(m, "enter"))
+	runCmd(m, press(m, "r"))
+
+	if m.screen != screenDiff || !strings.Contains(m.View(), "templates cannot be patched") {
+		t.Errorf("Expected templates to be rejected, got:\n%s", m.View())
+	}
+}
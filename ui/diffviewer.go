@@ -0,0 +1,456 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+
+	"chezmoi-tui/internal/diff"
+)
+
+var (
+	diffHeaderStyle     = lipgloss.NewStyle().Bold(true)
+	diffHunkStyle       = lipgloss.NewStyle().Foreground(lipgloss.Color("#0366d6"))
+	diffAddStyle        = lipgloss.NewStyle().Foreground(lipgloss.Color("#28a745"))
+	diffRemoveStyle     = lipgloss.NewStyle().Foreground(lipgloss.Color("#dc3545"))
+	diffAddWordStyle    = diffAddStyle.Reverse(true)
+	diffRemoveWordStyle = diffRemoveStyle.Reverse(true)
+	diffMutedStyle      = lipgloss.NewStyle().Faint(true)
+)
+
+// collapsedContext is the number of unchanged lines kept next to changes
+// when context is collapsed
+const collapsedContext = 1
+
+// diffViewerHelp lists the diff viewer key bindings
+const diffViewerHelp = "n/N next/prev hunk · s side-by-side · c collapse context · ↑/↓ scroll · h back"
+
+// diffSelectHelp lists the key bindings of a viewer whose hunks can be
+// selected
+const diffSelectHelp = "space select hunk · a apply to destination · r merge into source · "
+
+// diffViewer shows a unified diff with coloured additions and deletions,
+// either inline or side by side
+type diffViewer struct {
+	title      string
+	files      []diff.File
+	raw        string
+	parseErr   error
+	viewport   viewport.Model
+	width      int
+	sideBySide bool
+	collapse   bool
+
+	// selectable enables selecting hunks with space, and selected holds the
+	// indexes of the selected hunks
+	selectable bool
+	selected   map[int]bool
+
+	// notice is shown in the footer until the next key press
+	notice string
+
+	// hunks are the rendered line of every hunk header, in order, and
+	// hunkRefs where each is in files
+	hunks    []int
+	hunkRefs []hunkRef
+}
+
+// hunkRef locates a hunk in the files of a diff
+type hunkRef struct {
+	file, hunk int
+}
+
+// newDiffViewer creates an empty diff viewer of the given size
+func newDiffViewer(width, height int) diffViewer {
+	d := diffViewer{viewport: viewport.New(width, height)}
+	d.SetSize(width, height)
+	return d
+}
+
+// SetDiff parses and shows a unified diff. A diff that cannot be parsed is
+// shown as plain text and the parse error returned.
+func (d *diffViewer) SetDiff(text string) error {
+	d.raw = text
+	d.files, d.parseErr = diff.Parse(text)
+	d.selected = make(map[int]bool)
+	d.render()
+	d.viewport.GotoTop()
+	return d.parseErr
+}
+
+// Empty reports whether the diff has no changes
+func (d *diffViewer) Empty() bool {
+	return strings.TrimSpace(d.raw) == ""
+}
+
+// SetSize resizes the viewer
+func (d *diffViewer) SetSize(width, height int) {
+	d.width = width
+	d.viewport.Width = width
+	d.viewport.Height = max(height-2, 1) // title and footer
+	d.render()
+}
+
+// Update handles resizing, the viewer key bindings and scrolling
+func (d *diffViewer) Update(msg tea.Msg) tea.Cmd {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		d.SetSize(msg.Width, msg.Height)
+		return nil
+	case tea.KeyMsg:
+		d.notice = ""
+		switch msg.String() {
+		case " ":
+			if current := d.currentHunk(); d.selectable && current >= 0 {
+				if d.selected[current] {
+					delete(d.selected, current)
+				} else {
+					d.selected[current] = true
+				}
+				d.rerender()
+				return nil
+			}
+		case "n", "]":
+			d.nextHunk()
+			return nil
+		case "N", "p", "[":
+			d.previousHunk()
+			return nil
+		case "s":
+			d.sideBySide = !d.sideBySide
+			d.rerender()
+			return nil
+		case "c":
+			d.collapse = !d.collapse
+			d.rerender()
+			return nil
+		}
+	}
+
+	var cmd tea.Cmd
+	d.viewport, cmd = d.viewport.Update(msg)
+	return cmd
+}
+
+// View renders the viewer
+func (d *diffViewer) View() string {
+	mode := "inline"
+	if d.sideBySide {
+		mode = "side-by-side"
+	}
+
+	status := mode
+	if current := d.currentHunk(); current >= 0 {
+		ref := d.hunkRefs[current]
+		status = fmt.Sprintf("hunk %d/%d · %s · %s", current+1, len(d.hunks), d.files[ref.file].Path(), mode)
+	}
+	if len(d.selected) > 0 {
+		status += fmt.Sprintf(" · %d selected", len(d.selected))
+	}
+	if d.parseErr != nil {
+		status = logErrorStyle.Render(fmt.Sprintf("Cannot parse diff: %v", d.parseErr))
+	}
+	if d.notice != "" {
+		status = d.notice + " · " + status
+	}
+
+	help := diffViewerHelp
+	if d.selectable {
+		help = diffSelectHelp + help
+	}
+	footer := ansi.Truncate(status+" · "+help, d.width, "…")
+	return logTitleStyle.Render(d.title) + "\n" + d.viewport.View() + "\n" + footer
+}
+
+// SelectedFiles returns the files of the diff reduced to the selected
+// hunks, or to the current hunk if none are selected
+func (d *diffViewer) SelectedFiles() []diff.File {
+	selected := d.selected
+	if current := d.currentHunk(); len(selected) == 0 && current >= 0 {
+		selected = map[int]bool{current: true}
+	}
+
+	var files []diff.File
+	last := -1
+	for i, ref := range d.hunkRefs {
+		if !selected[i] {
+			continue
+		}
+		if ref.file != last {
+			file := d.files[ref.file]
+			file.Hunks = nil
+			files = append(files, file)
+			last = ref.file
+		}
+		files[len(files)-1].Hunks = append(files[len(files)-1].Hunks, d.files[ref.file].Hunks[ref.hunk])
+	}
+	return files
+}
+
+// nextHunk scrolls to the hunk after the current one
+func (d *diffViewer) nextHunk() {
+	if next := d.currentHunk() + 1; next > 0 && next < len(d.hunks) {
+		d.viewport.SetYOffset(d.hunks[next])
+	}
+}
+
+// previousHunk scrolls to the start of the current hunk, or to the hunk
+// before it if the current hunk starts at the top of the viewport
+func (d *diffViewer) previousHunk() {
+	current := d.currentHunk()
+	if current < 0 {
+		return
+	}
+	if d.hunks[current] >= d.viewport.YOffset && current > 0 {
+		current--
+	}
+	d.viewport.SetYOffset(d.hunks[current])
+}
+
+// currentHunk returns the index of the hunk at the top of the viewport, or
+// -1 if there are no hunks
+func (d *diffViewer) currentHunk() int {
+	current := -1
+	for i, line := range d.hunks {
+		if line > d.viewport.YOffset {
+			break
+		}
+		current = i
+	}
+	if current < 0 && len(d.hunks) > 0 {
+		current = 0
+	}
+	return current
+}
+
+// rerender renders the diff again after a mode change, keeping the current
+// hunk at the top of the viewport
+func (d *diffViewer) rerender() {
+	current := d.currentHunk()
+	d.render()
+	if current >= 0 && current < len(d.hunks) {
+		d.viewport.SetYOffset(d.hunks[current])
+	}
+}
+
+// render lays out the diff for the current width and mode
+func (d *diffViewer) render() {
+	d.hunks, d.hunkRefs = nil, nil
+	if d.parseErr != nil {
+		d.viewport.SetContent(expandTabs(d.raw))
+		return
+	}
+
+	var lines []string
+	for i := range d.files {
+		file := &d.files[i]
+		lines = append(lines, diffHeaderStyle.Render(ansi.Truncate(fileTitle(file), d.width, "…")))
+		if file.Binary {
+			lines = append(lines, diffMutedStyle.Render("  Binary file differs"))
+		}
+
+		for j := range file.Hunks {
+			hunk := &file.Hunks[j]
+			header := hunk.Header()
+			if d.selectable {
+				mark := "[ ] "
+				if d.selected[len(d.hunks)] {
+					mark = "[✓] "
+				}
+				header = mark + header
+			}
+			d.hunks = append(d.hunks, len(lines))
+			d.hunkRefs = append(d.hunkRefs, hunkRef{file: i, hunk: j})
+			lines = append(lines, diffHunkStyle.Render(ansi.Truncate(header, d.width, "…")))
+
+			for _, row := range d.collapseRows(hunk.Rows()) {
+				if row.Old == nil && row.New == nil {
+					lines = append(lines, row.skipped)
+					continue
+				}
+				if d.sideBySide {
+					lines = append(lines, d.renderSideBySide(row.Row))
+				} else {
+					lines = append(lines, d.renderInline(row.Row)...)
+				}
+			}
+		}
+		lines = append(lines, "")
+	}
+	d.viewport.SetContent(strings.Join(lines, "\n"))
+}
+
+// displayRow is a row of a hunk, or a marker for collapsed context lines
+// when both sides are nil
+type displayRow struct {
+	diff.Row
+	skipped string
+}
+
+// collapseRows replaces the unchanged lines of a hunk that are not next to
+// a change with a marker if context is collapsed
+func (d *diffViewer) collapseRows(rows []diff.Row) []displayRow {
+	display := make([]displayRow, 0, len(rows))
+	for i := 0; i < len(rows); {
+		if !d.collapse || !isContextRow(rows[i]) {
+			display = append(display, displayRow{Row: rows[i]})
+			i++
+			continue
+		}
+
+		// Find the run of context rows and hide all but its ends next to changes
+		j := i
+		for j < len(rows) && isContextRow(rows[j]) {
+			j++
+		}
+		hideStart, hideEnd := i+collapsedContext, j-collapsedContext
+		if i == 0 {
+			hideStart = i
+		}
+		if j == len(rows) {
+			hideEnd = j
+		}
+		if hideEnd-hideStart < 2 {
+			hideStart, hideEnd = j, j
+		}
+
+		for k := i; k < j; k++ {
+			if k == hideStart && hideEnd > hideStart {
+				marker := fmt.Sprintf("  ⋯ %d unchanged lines", hideEnd-hideStart)
+				display = append(display, displayRow{skipped: diffMutedStyle.Render(marker)})
+				k = hideEnd - 1
+				continue
+			}
+			display = append(display, displayRow{Row: rows[k]})
+		}
+		i = j
+	}
+	return display
+}
+
+// isContextRow reports whether row is an unchanged line
+func isContextRow(row diff.Row) bool {
+	return row.Old != nil && row.Old == row.New
+}
+
+// renderInline renders a row as one line per version, each with the old and
+// new line numbers
+func (d *diffViewer) renderInline(row diff.Row) []string {
+	if isContextRow(row) {
+		return []string{d.fit(lineNumbers(row.Old) + " " + expandTabs(row.Old.Text))}
+	}
+
+	oldText, newText := d.words(row)
+	var lines []string
+	if row.Old != nil {
+		lines = append(lines, d.fit(lineNumbers(row.Old)+diffRemoveStyle.Render("-")+oldText))
+	}
+	if row.New != nil {
+		lines = append(lines, d.fit(lineNumbers(row.New)+diffAddStyle.Render("+")+newText))
+	}
+	return lines
+}
+
+// renderSideBySide renders a row with the old version on the left and the
+// new version on the right
+func (d *diffViewer) renderSideBySide(row diff.Row) string {
+	column := max((d.width-3)/2, 10)
+
+	if isContextRow(row) {
+		text := expandTabs(row.Old.Text)
+		return d.column(sideNumber(row.Old.OldNumber)+" "+text, column) + " │ " + d.column(sideNumber(row.New.NewNumber)+" "+text, column)
+	}
+
+	oldText, newText := d.words(row)
+	left, right := strings.Repeat(" ", column), ""
+	if row.Old != nil {
+		left = d.column(sideNumber(row.Old.OldNumber)+diffRemoveStyle.Render("-")+oldText, column)
+	}
+	if row.New != nil {
+		right = d.column(sideNumber(row.New.NewNumber)+diffAddStyle.Render("+")+newText, column)
+	}
+	return left + " │ " + right
+}
+
+// words renders the text of a changed row, highlighting the changed words
+// when a removed line is paired with an added one
+func (d *diffViewer) words(row diff.Row) (string, string) {
+	if row.Old == nil || row.New == nil {
+		var oldText, newText string
+		if row.Old != nil {
+			oldText = diffRemoveStyle.Render(expandTabs(row.Old.Text))
+		}
+		if row.New != nil {
+			newText = diffAddStyle.Render(expandTabs(row.New.Text))
+		}
+		return oldText, newText
+	}
+
+	oldSegs, newSegs := diff.Words(row.Old.Text, row.New.Text)
+	return renderSegments(oldSegs, diffRemoveStyle, diffRemoveWordStyle), renderSegments(newSegs, diffAddStyle, diffAddWordStyle)
+}
+
+// renderSegments styles the segments of a line, highlighting changed ones
+func renderSegments(segs []diff.Segment, style, changed lipgloss.Style) string {
+	var b strings.Builder
+	for _, seg := range segs {
+		if seg.Changed {
+			b.WriteString(changed.Render(expandTabs(seg.Text)))
+		} else {
+			b.WriteString(style.Render(expandTabs(seg.Text)))
+		}
+	}
+	return b.String()
+}
+
+// fit truncates a rendered line to the width of the viewer
+func (d *diffViewer) fit(line string) string {
+	return ansi.Truncate(line, d.width, "…")
+}
+
+// column truncates or pads a rendered line to width
+func (d *diffViewer) column(line string, width int) string {
+	line = ansi.Truncate(line, width, "…")
+	return line + strings.Repeat(" ", max(width-ansi.StringWidth(line), 0))
+}
+
+// lineNumbers renders the old and new line numbers of an inline line
+func lineNumbers(line *diff.Line) string {
+	return diffMutedStyle.Render(fmt.Sprintf("%4s %4s ", number(line.OldNumber), number(line.NewNumber)))
+}
+
+// sideNumber renders the line number of one side of a side-by-side line
+func sideNumber(n int) string {
+	return diffMutedStyle.Render(fmt.Sprintf("%4s", number(n)))
+}
+
+// number formats a line number, leaving missing ones blank
+func number(n int) string {
+	if n == 0 {
+		return ""
+	}
+	return fmt.Sprint(n)
+}
+
+// fileTitle describes a file of the diff
+func fileTitle(file *diff.File) string {
+	switch {
+	case file.IsNew():
+		return file.Path() + " (new file)"
+	case file.IsDeleted():
+		return file.Path() + " (deleted)"
+	case file.OldPath != "" && file.NewPath != "" && file.OldPath != file.NewPath:
+		return file.OldPath + " → " + file.NewPath
+	default:
+		return file.Path()
+	}
+}
+
+// expandTabs replaces tabs with spaces so that widths can be measured
+func expandTabs(s string) string {
+	return strings.ReplaceAll(s, "\t", "    ")
+}
@@ -0,0 +1,129 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// testDiff has two hunks, the second separated from its change by a long
+// run of context lines
+const testDiff = `diff --git a/.bashrc b/.bashrc
+--- a/.bashrc
++++ b/.bashrc
+@@ -1,3 +1,3 @@
+ # .bashrc
+-export EDITOR=vi
++export EDITOR=nvim
+ export PAGER=less
+@@ -20,7 +20,7 @@ prompt()
+ one
+ two
+ three
+ four
+ five
+-PS1="$ "
++PS1="> "
+ six
+`
+
+// newTestDiffViewer returns a viewer of testDiff sized by a window resize
+func newTestDiffViewer(t *testing.T) *diffViewer {
+	t.Helper()
+
+	d := newDiffViewer(10, 10)
+	d.Update(tea.WindowSizeMsg{Width: 100, Height: 40})
+	if err := d.SetDiff(testDiff); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	return &d
+}
+
+func TestDiffViewerInline(t *testing.T) {
+	d := newTestDiffViewer(t)
+
+	view := d.View()
+	for _, expected := range []string{".bashrc", "@@ -1,3 +1,3 @@", "   2      -export EDITOR=vi", "        2 +export EDITOR=nvim", "hunk 1/2 · .bashrc · inline"} {
+		if !strings.Contains(view, expected) {
+			t.Errorf("Expected %q in:\n%s", expected, view)
+		}
+	}
+	if d.viewport.Width != 100 || d.viewport.Height != 38 {
+		t.Errorf("Expected the viewer to be sized to the window, got %dx%d", d.viewport.Width, d.viewport.Height)
+	}
+}
+
+func TestDiffViewerSideBySide(t *testing.T) {
+	d := newTestDiffViewer(t)
+	d.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("s")})
+
+	var row string
+	for _, line := range strings.Split(d.View(), "\n") {
+		if strings.Contains(line, "EDITOR=vi") {
+			row = line
+		}
+	}
+	if !strings.Contains(row, "│") || !strings.Contains(row, "EDITOR=nvim") {
+		t.Errorf("Expected the changed lines side by side, got %q", row)
+	}
+}
+
+func TestDiffViewerHunkNavigation(t *testing.T) {
+	d := newTestDiffViewer(t)
+	d.Update(tea.WindowSizeMsg{Width: 100, Height: 8})
+
+	d.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("n")})
+	if d.viewport.YOffset != d.hunks[1] || !strings.Contains(d.View(), "hunk 2/2") {
+		t.Errorf("Expected to scroll to the second hunk, got offset %d", d.viewport.YOffset)
+	}
+	d.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("N")})
+	if d.viewport.YOffset != d.hunks[0] {
+		t.Errorf("Expected to scroll back to the first hunk, got offset %d", d.viewport.YOffset)
+	}
+}
+
+func TestDiffViewerCollapse(t *testing.T) {
+	d := newTestDiffViewer(t)
+	d.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("c")})
+
+	view := d.View()
+	if !strings.Contains(view, "⋯ 4 unchanged lines") || strings.Contains(view, "three") || !strings.Contains(view, "five") {
+		t.Errorf("Expected the leading context of the second hunk to be collapsed, got:\n%s", view)
+	}
+	if !strings.Contains(view, "export PAGER=less") {
+		t.Errorf("Expected short context runs to be kept, got:\n%s", view)
+	}
+}
+
+func TestDiffViewerUnparsable(t *testing.T) {
+	d := newDiffViewer(80, 20)
+	if err := d.SetDiff("--- a/x\n+++ b/x\n@@ -1,5 +1,5 @@\n a\n"); err == nil {
+		t.Fatal("Expected a parse error")
+	}
+	if view := d.View(); !strings.Contains(view, "Cannot parse diff") || !strings.Contains(view, "@@ -1,5 +1,5 @@") {
+		t.Errorf("Expected the raw diff and the error, got:\n%s", view)
+	}
+}
+
+func TestDiffViewerSelectHunks(t *testing.T) {
+	d := newTestDiffViewer(t)
+	d.selectable = true
+	d.Update(tea.WindowSizeMsg{Width: 100, Height: 8})
+
+	// Without a selection the current hunk is used
+	if files := d.SelectedFiles(); len(files) != 1 || len(files[0].Hunks) != 1 || files[0].Hunks[0].OldStart != 1 {
+		t.Errorf("Expected the current hunk, got %+v", files)
+	}
+
+	d.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("n")})
+	d.Update(tea.KeyMsg{Type: tea.KeySpace})
+	if !strings.Contains(d.View(), "[✓] @@ -20,7 +20,7 @@") {
+		t.Errorf("Expected the second hunk to be marked, got:\n%s", d.View())
+	}
+	d.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("N")})
+	files := d.SelectedFiles()
+	if len(files) != 1 || len(files[0].Hunks) != 1 || files[0].Hunks[0].OldStart != 20 {
+		t.Errorf("Expected only the selected hunk, got %+v", files)
+	}
+}
@@ -0,0 +1,311 @@
+package ui
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/cursor"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/sahilm/fuzzy"
+
+	"chezmoi-tui/internal/chezmoi"
+)
+
+// filterMatchStyle highlights the characters matched by the fuzzy filter
+var filterMatchStyle = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#1793d1"))
+
+// quickFilter is a filter of the status screen toggled by a single key
+type quickFilter struct {
+	key  string
+	name string
+	// match reports whether an entry passes the filter. source is the source
+	// path of the entry relative to the source directory.
+	match func(entry chezmoi.StatusEntry, source string) bool
+}
+
+// statusFilters are the quick filters on the status type of an entry. An
+// entry passes if it matches any of the enabled ones.
+var statusFilters = []quickFilter{
+	{key: "M", name: "modified", match: func(e chezmoi.StatusEntry, _ string) bool { return e.IsModified() }},
+	{key: "A", name: "added", match: func(e chezmoi.StatusEntry, _ string) bool { return e.IsAdded() }},
+	{key: "D", name: "deleted", match: func(e chezmoi.StatusEntry, _ string) bool { return e.IsDeleted() }},
+	{key: "S", name: "scripts", match: func(e chezmoi.StatusEntry, _ string) bool { return e.IsScript() }},
+}
+
+// attributeFilters are the quick filters on the source state attributes of
+// an entry. An entry passes if it has all of the enabled ones.
+var attributeFilters = []quickFilter{
+	{key: "T", name: "template", match: hasSourceAttribute("template")},
+	{key: "E", name: "encrypted", match: hasSourceAttribute("encrypted")},
+	{key: "P", name: "private", match: hasSourceAttribute("private")},
+}
+
+// hasSourceAttribute returns a filter matching source files with the given
+// attribute, named as by chezmoi.SourceAttributes.Names
+func hasSourceAttribute(attribute string) func(chezmoi.StatusEntry, string) bool {
+	return func(_ chezmoi.StatusEntry, source string) bool {
+		if source == "" {
+			return false
+		}
+		return chezmoi.ParseSourceName(filepath.Base(source), false).Has(attribute)
+	}
+}
+
+// statusFilter narrows the file list of the status screen
+type statusFilter struct {
+	// query fuzzy matches paths while the filter is typed or kept
+	query textinput.Model
+	// enabled holds the keys of the enabled quick filters
+	enabled map[string]bool
+	// dir limits the list to the entries below a directory, relative to the
+	// destination directory
+	dir string
+}
+
+// newStatusFilter returns a filter that lets every entry pass
+func newStatusFilter() statusFilter {
+	query := textinput.New()
+	query.Prompt = "/"
+	query.Placeholder = "fuzzy filter"
+	query.Cursor.SetMode(cursor.CursorStatic)
+	return statusFilter{query: query, enabled: make(map[string]bool)}
+}
+
+// Active reports whether the filter hides any entries
+func (f *statusFilter) Active() bool {
+	return f.query.Value() != "" || len(f.enabled) > 0 || f.dir != ""
+}
+
+// NeedsSources reports whether the filter needs the source paths of entries
+func (f *statusFilter) NeedsSources() bool {
+	for _, filter := range attributeFilters {
+		if f.enabled[filter.key] {
+			return true
+		}
+	}
+	return false
+}
+
+// Describe summarizes the enabled filters
+func (f *statusFilter) Describe() string {
+	var parts []string
+	var kinds []string
+	for _, filter := range statusFilters {
+		if f.enabled[filter.key] {
+			kinds = append(kinds, filter.name)
+		}
+	}
+	if len(kinds) > 0 {
+		parts = append(parts, strings.Join(kinds, " or "))
+	}
+	for _, filter := range attributeFilters {
+		if f.enabled[filter.key] {
+			parts = append(parts, filter.name)
+		}
+	}
+	if f.dir != "" {
+		parts = append(parts, "in "+f.dir+"/")
+	}
+	if query := f.query.Value(); query != "" && !f.query.Focused() {
+		parts = append(parts, fmt.Sprintf("matching %q", query))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// match reports whether an entry passes the quick filters and the directory
+// filter
+func (f *statusFilter) match(entry chezmoi.StatusEntry, source string) bool {
+	if f.dir != "" && !strings.HasPrefix(entry.Path, f.dir+"/") {
+		return false
+	}
+
+	anyKind, kindMatched := false, false
+	for _, filter := range statusFilters {
+		if f.enabled[filter.key] {
+			anyKind = true
+			kindMatched = kindMatched || filter.match(entry, source)
+		}
+	}
+	if anyKind && !kindMatched {
+		return false
+	}
+
+	for _, filter := range attributeFilters {
+		if f.enabled[filter.key] && !filter.match(entry, source) {
+			return false
+		}
+	}
+	return true
+}
+
+// listedEntry is an entry of the filtered file list
+type listedEntry struct {
+	chezmoi.StatusEntry
+	// matched holds the byte offsets of the path matched by the fuzzy query
+	matched []int
+}
+
+// sourcesLoadedMsg carries the source paths of the managed targets
+type sourcesLoadedMsg struct {
+	op      int
+	entries []chezmoi.ManagedEntry
+	err     error
+}
+
+// applyFilter recomputes the listed entries from the file status. Fuzzy
+// matches are listed best first, otherwise the order of chezmoi status is
+// kept.
+func (m *Model) applyFilter() {
+	var candidates []chezmoi.StatusEntry
+	for _, entry := range m.fileStatus {
+		if m.filter.match(entry, m.sources[entry.Path]) {
+			candidates = append(candidates, entry)
+		}
+	}
+
+	m.listed = m.listed[:0]
+	if query := m.filter.query.Value(); query != "" {
+		paths := make([]string, len(candidates))
+		for i, entry := range candidates {
+			paths[i] = entry.Path
+		}
+		for _, match := range fuzzy.Find(query, paths) {
+			m.listed = append(m.listed, listedEntry{StatusEntry: candidates[match.Index], matched: match.MatchedIndexes})
+		}
+	} else {
+		for _, entry := range candidates {
+			m.listed = append(m.listed, listedEntry{StatusEntry: entry})
+		}
+	}
+
+	if m.fileCursor >= len(m.listed) {
+		m.fileCursor = max(len(m.listed)-1, 0)
+	}
+}
+
+// handleFilterKey handles the keys changing the filter of the status screen
+// and reports whether the key was consumed
+func (m *Model) handleFilterKey(msg tea.KeyMsg) (tea.Cmd, bool) {
+	key := msg.String()
+	f := &m.filter
+
+	switch key {
+	case "/":
+		m.fileCursor = 0
+		return f.query.Focus(), true
+	case "esc":
+		if !f.Active() {
+			return nil, false
+		}
+		m.filter = newStatusFilter()
+		m.applyFilter()
+		return nil, true
+	case ".":
+		// Limit the list to the directory of the file under the cursor, or
+		// show all directories again
+		switch {
+		case f.dir != "":
+			f.dir = ""
+		case m.fileCursor < len(m.listed):
+			dir := filepath.ToSlash(filepath.Dir(m.listed[m.fileCursor].Path))
+			if dir == "." {
+				return nil, true
+			}
+			f.dir = dir
+		}
+		m.applyFilter()
+		return nil, true
+	}
+
+	for _, filter := range append(statusFilters, attributeFilters...) {
+		if filter.key != key {
+			continue
+		}
+		if f.enabled[key] {
+			delete(f.enabled, key)
+		} else {
+			f.enabled[key] = true
+		}
+		m.fileCursor = 0
+		m.applyFilter()
+		if f.NeedsSources() && (m.sources == nil || m.sourcesStale) {
+			return m.loadSources(), true
+		}
+		return nil, true
+	}
+	return nil, false
+}
+
+// handleFilterInput handles a key press while the fuzzy query is typed.
+// enter keeps the query and returns to the list, esc clears it.
+func (m *Model) handleFilterInput(msg tea.KeyMsg) tea.Cmd {
+	f := &m.filter
+
+	switch msg.String() {
+	case "enter":
+		f.query.Blur()
+		return nil
+	case "esc":
+		f.query.Blur()
+		f.query.SetValue("")
+		m.applyFilter()
+		return nil
+	case "up", "down":
+		// Move through the matches without leaving the query
+		_, _ = m.handleBrowserKey(msg)
+		return nil
+	}
+
+	var cmd tea.Cmd
+	f.query, cmd = f.query.Update(msg)
+	m.fileCursor = 0
+	m.applyFilter()
+	return cmd
+}
+
+// loadSources loads the source paths of the managed targets, which the
+// attribute filters need
+func (m *Model) loadSources() tea.Cmd {
+	ctx, op := m.startOperation()
+	m.sourcesStale = false
+	integ := m.integration
+	return func() tea.Msg {
+		entries, err := integ.GetManagedEntries(ctx)
+		return sourcesLoadedMsg{op: op, entries: entries, err: err}
+	}
+}
+
+// setSources records the source paths of the managed targets
+func (m *Model) setSources(entries []chezmoi.ManagedEntry, err error) {
+	m.sourcesErr = err
+	m.sources = make(map[string]string, len(entries))
+	for _, entry := range entries {
+		m.sources[entry.Path] = entry.SourceRelative
+	}
+	m.applyFilter()
+}
+
+// renderPath renders a path with the characters matched by the fuzzy query
+// highlighted
+func renderPath(path string, matched []int) string {
+	if len(matched) == 0 {
+		return path
+	}
+
+	isMatched := make(map[int]bool, len(matched))
+	for _, i := range matched {
+		isMatched[i] = true
+	}
+	var b strings.Builder
+	for i, r := range path {
+		if isMatched[i] {
+			b.WriteString(filterMatchStyle.Render(string(r)))
+		} else {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,146 @@
+package ui
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"chezmoi-tui/internal/chezmoi/chezmoitest"
+)
+
+// listedPaths returns the paths listed on the status screen
+func listedPaths(m *Model) []string {
+	var paths []string
+	for _, entry := range m.listed {
+		paths = append(paths, entry.Path)
+	}
+	return paths
+}
+
+// filterStatus is the status of files of several types and directories
+const filterStatus = " M .bashrc\nA  .gitconfig\n D .config/nvim/init.lua\n M .config/git/config\n R .chezmoiscripts/install.sh\n"
+
+func TestFuzzyFilter(t *testing.T) {
+	m := newStatusModel(t, chezmoitest.NewFake(), browserStatus)
+
+	press(m, "/")
+	for _, key := range []string{"v", "r", "c"} {
+		press(m, key)
+	}
+	if paths := listedPaths(m); !reflect.DeepEqual(paths, []string{".vimrc"}) {
+		t.Errorf("Expected only .vimrc to match, got %v", paths)
+	}
+	if view := m.View(); !strings.Contains(view, "/vrc") {
+		t.Errorf("Expected the query to be shown, got:\n%s", view)
+	}
+
+	// enter keeps the query and returns to the list
+	press(m, "enter")
+	view := m.View()
+	if !strings.Contains(view, `Filter: matching "vrc"`) || !strings.Contains(view, "1 of 3 files shown") {
+		t.Errorf("Expected the kept filter to be described, got:\n%s", view)
+	}
+	if targets := m.targets(); !reflect.DeepEqual(targets, []string{"/home/user/.vimrc"}) {
+		t.Errorf("Expected actions to run on the listed file, got %v", targets)
+	}
+
+	press(m, "esc")
+	if len(m.listed) != 3 || m.filter.Active() {
+		t.Errorf("Expected esc to clear the filter, got %v", listedPaths(m))
+	}
+}
+
+func TestFuzzyFilterTypesKeys(t *testing.T) {
+	m := newStatusModel(t, chezmoitest.NewFake(), browserStatus)
+
+	press(m, "/")
+	press(m, "h")
+	if m.screen != screenStatus || m.filter.query.Value() != "h" {
+		t.Errorf("Expected h to be typed into the query, got screen %v and query %q", m.screen, m.filter.query.Value())
+	}
+	press(m, "esc")
+	if m.filter.query.Focused() || m.filter.Active() || len(m.listed) != 3 {
+		t.Error("Expected esc to discard the query")
+	}
+}
+
+func TestQuickFilters(t *testing.T) {
+	m := newStatusModel(t, chezmoitest.NewFake(), filterStatus)
+
+	press(m, "M")
+	if paths := listedPaths(m); !reflect.DeepEqual(paths, []string{".bashrc", ".config/git/config"}) {
+		t.Errorf("Expected the modified files, got %v", paths)
+	}
+	press(m, "A")
+	if paths := listedPaths(m); !reflect.DeepEqual(paths, []string{".bashrc", ".gitconfig", ".config/git/config"}) {
+		t.Errorf("Expected the modified or added files, got %v", paths)
+	}
+	if view := m.View(); !strings.Contains(view, "Filter: modified or added") {
+		t.Errorf("Expected the filter to be described, got:\n%s", view)
+	}
+	press(m, "M")
+	press(m, "A")
+	press(m, "S")
+	if paths := listedPaths(m); !reflect.DeepEqual(paths, []string{".chezmoiscripts/install.sh"}) {
+		t.Errorf("Expected the scripts, got %v", paths)
+	}
+	press(m, "S")
+	press(m, "D")
+	if paths := listedPaths(m); !reflect.DeepEqual(paths, []string{".config/nvim/init.lua"}) {
+		t.Errorf("Expected the deleted files, got %v", paths)
+	}
+}
+
+func TestDirectoryFilter(t *testing.T) {
+	m := newStatusModel(t, chezmoitest.NewFake(), filterStatus)
+
+	// Files in the destination directory itself do not narrow the list
+	press(m, ".")
+	if len(m.listed) != 5 {
+		t.Errorf("Expected all files to stay listed, got %v", listedPaths(m))
+	}
+
+	press(m, "down")
+	press(m, "down")
+	press(m, ".")
+	if paths := listedPaths(m); !reflect.DeepEqual(paths, []string{".config/nvim/init.lua"}) {
+		t.Errorf("Expected the files in .config/nvim, got %v", paths)
+	}
+	if view := m.View(); !strings.Contains(view, "in .config/nvim/") {
+		t.Errorf("Expected the directory to be shown, got:\n%s", view)
+	}
+
+	press(m, ".")
+	if len(m.listed) != 5 {
+		t.Errorf("Expected all directories to be listed again, got %v", listedPaths(m))
+	}
+}
+
+func TestAttributeFilters(t *testing.T) {
+	fake := chezmoitest.NewFake()
+	fake.On("managed").Stdout(`{
+		".bashrc": {"sourceRelative": "dot_bashrc.tmpl"},
+		".gitconfig": {"sourceRelative": "encrypted_private_dot_gitconfig.age"},
+		".config/git/config": {"sourceRelative": "dot_config/git/private_config.tmpl"}
+	}`)
+	m := newStatusModel(t, fake, filterStatus)
+
+	// The source paths are loaded when an attribute filter is first used
+	runCmd(m, press(m, "T"))
+	if paths := listedPaths(m); !reflect.DeepEqual(paths, []string{".bashrc", ".config/git/config"}) {
+		t.Errorf("Expected the templates, got %v", paths)
+	}
+	press(m, "P")
+	if paths := listedPaths(m); !reflect.DeepEqual(paths, []string{".config/git/config"}) {
+		t.Errorf("Expected the private templates, got %v", paths)
+	}
+	press(m, "T")
+	press(m, "P")
+	press(m, "E")
+	if paths := listedPaths(m); !reflect.DeepEqual(paths, []string{".gitconfig"}) {
+		t.Errorf("Expected the encrypted files, got %v", paths)
+	}
+	if fake.CallCount("managed") != 1 {
+		t.Errorf("Expected the source paths to be loaded once, got %d", fake.CallCount("managed"))
+	}
+}
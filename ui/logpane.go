@@ -0,0 +1,155 @@
+package ui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"chezmoi-tui/internal/chezmoi"
+)
+
+var (
+	logTitleStyle  = lipgloss.NewStyle().Bold(true).MarginLeft(2)
+	logStderrStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#ffc107"))
+	logErrorStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("#dc3545"))
+	logOKStyle     = lipgloss.NewStyle().Foreground(lipgloss.Color("#28a745"))
+)
+
+// logStream connects a running command to the log pane. op is the
+// operation the command belongs to.
+type logStream struct {
+	op    int
+	lines <-chan chezmoi.OutputLine
+	done  <-chan error
+}
+
+// logLineMsg delivers one line of streamed output
+type logLineMsg struct {
+	line   chezmoi.OutputLine
+	stream logStream
+}
+
+// logDoneMsg signals that the streamed command has exited
+type logDoneMsg struct {
+	op  int
+	err error
+}
+
+// startLogStream runs fn in the background and returns a command that
+// delivers its output to the log pane line by line
+func startLogStream(ctx context.Context, op int, fn func(context.Context, chezmoi.IOStreams) error) tea.Cmd {
+	lines := make(chan chezmoi.OutputLine, 64)
+	done := make(chan error, 1)
+
+	go func() {
+		stdout := chezmoi.NewLineWriter(lines, false)
+		stderr := chezmoi.NewLineWriter(lines, true)
+		err := fn(ctx, chezmoi.IOStreams{Out: stdout, Err: stderr})
+		stdout.Flush()
+		stderr.Flush()
+		close(lines)
+		done <- err
+	}()
+
+	return waitForLog(logStream{op: op, lines: lines, done: done})
+}
+
+// waitForLog waits for the next line of a stream, or its result once the
+// command has exited
+func waitForLog(stream logStream) tea.Cmd {
+	return func() tea.Msg {
+		line, ok := <-stream.lines
+		if !ok {
+			return logDoneMsg{op: stream.op, err: <-stream.done}
+		}
+		return logLineMsg{line: line, stream: stream}
+	}
+}
+
+// logPane shows the output of a streaming command as it arrives
+type logPane struct {
+	title    string
+	lines    []string
+	viewport viewport.Model
+	running  bool
+	err      error
+	// spinner is the current frame of the spinner shown while running
+	spinner string
+}
+
+// newLogPane creates an empty log pane of the given size
+func newLogPane(title string, width, height int) logPane {
+	return logPane{
+		title:    title,
+		viewport: viewport.New(width, height),
+	}
+}
+
+// SetSize resizes the pane
+func (l *logPane) SetSize(width, height int) {
+	l.viewport.Width = width
+	l.viewport.Height = height - 2 // title and footer
+}
+
+// Reset clears the pane for a new command with the given title
+func (l *logPane) Reset(title string) {
+	l.title = title
+	l.lines = nil
+	l.err = nil
+	l.running = true
+	l.viewport.SetContent("")
+}
+
+// AppendLine adds a line of output, following the tail of the log unless
+// the user has scrolled up
+func (l *logPane) AppendLine(line chezmoi.OutputLine) {
+	text := line.Text
+	if line.Stderr {
+		text = logStderrStyle.Render(text)
+	}
+	follow := l.viewport.AtBottom()
+	l.lines = append(l.lines, text)
+	l.viewport.SetContent(strings.Join(l.lines, "\n"))
+	if follow {
+		l.viewport.GotoBottom()
+	}
+}
+
+// Finish records the result of the command
+func (l *logPane) Finish(err error) {
+	l.running = false
+	l.err = err
+}
+
+// Update scrolls the pane
+func (l *logPane) Update(msg tea.Msg) tea.Cmd {
+	var cmd tea.Cmd
+	l.viewport, cmd = l.viewport.Update(msg)
+	return cmd
+}
+
+// View renders the pane
+func (l *logPane) View() string {
+	var footer string
+	switch {
+	case l.running:
+		footer = strings.TrimSpace(l.spinner+" Running...") + " (esc to cancel)"
+	case errors.Is(l.err, context.Canceled):
+		footer = logErrorStyle.Render("Cancelled.") + " Press 'h' to go back."
+	case l.err != nil:
+		footer = logErrorStyle.Render(fmt.Sprintf("Failed: %v", l.err))
+		if hint := chezmoi.Remediation(l.err); hint != "" {
+			footer += "\nHint: " + hint
+		}
+		footer += "\nPress 'h' to go back."
+	default:
+		footer = logOKStyle.Render("Done.") + " Press 'h' to go back."
+	}
+
+	return logTitleStyle.Render(l.title) + "\n" + l.viewport.View() + "\n" + footer
+}
@@ -0,0 +1,302 @@
+package ui
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"chezmoi-tui/internal/chezmoi"
+)
+
+// treeHelp lists the tree view key bindings
+const treeHelp = "↑/↓ move · enter/space expand or collapse · d diff · a apply · r re-add · x forget · e edit source · i inspect · t list view · h back"
+
+// treeNode is a file or directory of the tree of managed files
+type treeNode struct {
+	name string
+	// path is the target path relative to the destination directory
+	path     string
+	dir      bool
+	children []*treeNode
+	// entry is the status of a file, or nil if it is up to date
+	entry *chezmoi.StatusEntry
+	// counts summarizes the status of the entries below a directory
+	counts chezmoi.StatusCounts
+}
+
+// treeRow is a node shown by the tree view
+type treeRow struct {
+	node  *treeNode
+	depth int
+}
+
+// managedLoadedMsg carries the managed targets the tree view is built from
+type managedLoadedMsg struct {
+	op    int
+	paths []string
+	err   error
+}
+
+// buildTree builds the tree of the managed targets and of the entries with
+// a status, which includes scripts, and aggregates the status of every
+// directory
+func buildTree(managed []string, entries []chezmoi.StatusEntry) *treeNode {
+	root := &treeNode{dir: true}
+	nodes := map[string]*treeNode{"": root}
+
+	var insert func(path string) *treeNode
+	insert = func(path string) *treeNode {
+		if node, ok := nodes[path]; ok {
+			return node
+		}
+		parentPath, name := "", path
+		if i := strings.LastIndex(path, "/"); i >= 0 {
+			parentPath, name = path[:i], path[i+1:]
+		}
+		parent := insert(parentPath)
+		parent.dir = true
+		node := &treeNode{name: name, path: path}
+		parent.children = append(parent.children, node)
+		nodes[path] = node
+		return node
+	}
+
+	for _, path := range managed {
+		insert(path)
+	}
+	below := make(map[*treeNode][]chezmoi.StatusEntry)
+	for _, entry := range entries {
+		node := insert(entry.Path)
+		node.entry = &entry
+		for path := entry.Path; path != ""; {
+			i := strings.LastIndex(path, "/")
+			path = path[:max(i, 0)]
+			below[nodes[path]] = append(below[nodes[path]], entry)
+		}
+	}
+
+	for _, node := range nodes {
+		node.counts = chezmoi.CountStatus(below[node])
+		sort.Slice(node.children, func(i, j int) bool {
+			a, b := node.children[i], node.children[j]
+			if a.dir != b.dir {
+				return a.dir
+			}
+			return a.name < b.name
+		})
+	}
+	return root
+}
+
+// rebuildTree rebuilds the tree from the managed targets and the status,
+// keeping the cursor on the same path
+func (m *Model) rebuildTree() {
+	if m.managed == nil {
+		m.tree, m.treeRows = nil, nil
+		return
+	}
+
+	var current string
+	if m.treeCursor < len(m.treeRows) {
+		current = m.treeRows[m.treeCursor].node.path
+	}
+	m.tree = buildTree(m.managed, m.fileStatus)
+	m.flattenTree()
+	for i, row := range m.treeRows {
+		if row.node.path == current {
+			m.treeCursor = i
+			break
+		}
+	}
+}
+
+// flattenTree lists the nodes of the expanded directories
+func (m *Model) flattenTree() {
+	m.treeRows = m.treeRows[:0]
+	var walk func(node *treeNode, depth int)
+	walk = func(node *treeNode, depth int) {
+		for _, child := range node.children {
+			m.treeRows = append(m.treeRows, treeRow{node: child, depth: depth})
+			if child.dir && m.expanded[child.path] {
+				walk(child, depth+1)
+			}
+		}
+	}
+	if m.tree != nil {
+		walk(m.tree, 0)
+	}
+	if m.treeCursor >= len(m.treeRows) {
+		m.treeCursor = max(len(m.treeRows)-1, 0)
+	}
+}
+
+// toggleTree switches the status screen between the list of changed files
+// and the tree of managed files, loading the managed files the first time
+func (m *Model) toggleTree() tea.Cmd {
+	m.treeMode = !m.treeMode
+	if m.treeMode && (m.managed == nil || m.managedStale) {
+		return m.loadManaged()
+	}
+	return nil
+}
+
+// loadManaged loads the managed targets the tree view is built from
+func (m *Model) loadManaged() tea.Cmd {
+	ctx, op := m.startOperation()
+	m.managedStale = false
+	integ := m.integration
+	return func() tea.Msg {
+		paths, err := integ.GetManagedFiles(ctx)
+		return managedLoadedMsg{op: op, paths: paths, err: err}
+	}
+}
+
+// setManaged records the managed targets and rebuilds the tree
+func (m *Model) setManaged(paths []string, err error) {
+	m.treeErr = err
+	m.managed = paths
+	if m.managed == nil {
+		m.managed = []string{}
+	}
+	m.rebuildTree()
+}
+
+// handleTreeKey handles a key press in the tree view and reports whether it
+// was consumed. Actions apply to the file or the whole directory under the
+// cursor.
+func (m *Model) handleTreeKey(msg tea.KeyMsg) (tea.Cmd, bool) {
+	key := msg.String()
+
+	switch key {
+	case "up", "k":
+		if m.treeCursor > 0 {
+			m.treeCursor--
+		}
+		return nil, true
+	case "down", "j":
+		if m.treeCursor < len(m.treeRows)-1 {
+			m.treeCursor++
+		}
+		return nil, true
+	}
+
+	if m.loading || len(m.treeRows) == 0 {
+		return nil, false
+	}
+
+	switch key {
+	case "enter", "l", "right", " ":
+		if node := m.treeRows[m.treeCursor].node; node.dir {
+			if m.expanded[node.path] {
+				delete(m.expanded, node.path)
+			} else {
+				m.expanded[node.path] = true
+			}
+			m.flattenTree()
+		}
+		return nil, true
+	case "o":
+		// Opening a directory in an editor is rarely what was meant
+		if m.treeRows[m.treeCursor].node.dir {
+			return nil, true
+		}
+	}
+	return m.handleFileAction(key)
+}
+
+// describeCounts summarizes the changes below a directory, e.g.
+// "3 modified, 1 added"
+func describeCounts(counts chezmoi.StatusCounts) string {
+	var parts []string
+	for _, part := range []struct {
+		n    int
+		name string
+	}{
+		{counts.Modified, "modified"},
+		{counts.Added, "added"},
+		{counts.Deleted, "deleted"},
+		{counts.Scripts, "scripts"},
+	} {
+		if part.n > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", part.n, part.name))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// treeView renders the tree of managed files
+func (m *Model) treeView() string {
+	if m.managed == nil && m.loading {
+		return quitTextStyle.Render(m.spinner.View() + " Loading managed files... (esc to cancel)")
+	}
+	if m.treeErr != nil {
+		return quitTextStyle.Render(renderError("Loading managed files", m.treeErr) + "Press 't' for the list of changed files.\n")
+	}
+
+	start, end := visibleRange(m.treeCursor, len(m.treeRows), m.maxFileDisplay)
+
+	var content strings.Builder
+	content.WriteString("Chezmoi Managed Files")
+	if m.loading {
+		content.WriteString("  " + m.spinner.View() + " refreshing")
+	}
+	content.WriteString("\n\n~\n")
+	if len(m.treeRows) == 0 {
+		content.WriteString("  No managed files.\n")
+	}
+	if start > 0 {
+		content.WriteString(fmt.Sprintf("  ... %d more above\n", start))
+	}
+
+	for i := start; i < end; i++ {
+		row := m.treeRows[i]
+		node := row.node
+		cursor := " "
+		if m.treeCursor == i {
+			cursor = "→"
+		}
+
+		var line string
+		switch {
+		case node.dir:
+			marker := "▸"
+			if m.expanded[node.path] {
+				marker = "▾"
+			}
+			line = marker + " " + node.name + "/"
+			if summary := describeCounts(node.counts); summary != "" {
+				line += " (" + summary + ")"
+			}
+		case node.entry != nil:
+			line = fmt.Sprintf("[%s%s] %s", node.entry.DestStatus.Symbol(), node.entry.TargetStatus.Symbol(), node.name)
+		default:
+			line = "     " + node.name
+		}
+		content.WriteString(fmt.Sprintf("%s %s%s\n", cursor, strings.Repeat("  ", row.depth), line))
+	}
+	if end < len(m.treeRows) {
+		content.WriteString(fmt.Sprintf("  ... and %d more\n", len(m.treeRows)-end))
+	}
+
+	content.WriteString(fmt.Sprintf("\n%d managed, %s\n", len(m.managed), summarizeChanges(m.tree)))
+	content.WriteString(treeHelp + "\n")
+
+	line := 3 + m.treeCursor - start
+	if start > 0 {
+		line++
+	}
+	return m.scrollView(content.String(), line)
+}
+
+// summarizeChanges describes the changes in the whole tree
+func summarizeChanges(root *treeNode) string {
+	if root == nil {
+		return "no changes"
+	}
+	if summary := describeCounts(root.counts); summary != "" {
+		return summary
+	}
+	return "no changes"
+}
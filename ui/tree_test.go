@@ -0,0 +1,114 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+
+	"chezmoi-tui/internal/chezmoi"
+	"chezmoi-tui/internal/chezmoi/chezmoitest"
+)
+
+// newTreeModel returns a model showing the tree of a few managed files
+func newTreeModel(t *testing.T, fake *chezmoitest.Fake) *Model {
+	t.Helper()
+
+	fake.On("managed").Stdout(".bashrc\x00.config\x00.config/nvim\x00.config/nvim/init.lua\x00.config/nvim/lua/plugins.lua\x00.config/git/config\x00")
+	m := newStatusModel(t, fake, browserStatus)
+	fake.On("status").Stdout(" M .bashrc\n M .config/nvim/init.lua\n M .config/nvim/lua/plugins.lua\nA  .config/git/config\n")
+	runCmd(m, m.loadStatus())
+
+	runCmd(m, press(m, "t"))
+	if !m.treeMode || m.tree == nil {
+		t.Fatal("Expected the tree view")
+	}
+	return m
+}
+
+func TestBuildTree(t *testing.T) {
+	entries := []chezmoi.StatusEntry{
+		{DestStatus: ' ', TargetStatus: 'M', Path: ".config/nvim/init.lua"},
+		{DestStatus: 'A', TargetStatus: ' ', Path: ".config/git/config"},
+		{DestStatus: ' ', TargetStatus: 'R', Path: ".chezmoiscripts/install.sh"},
+	}
+	root := buildTree([]string{".zshrc", ".config", ".config/nvim/init.lua", ".config/git/config"}, entries)
+
+	var names []string
+	for _, child := range root.children {
+		names = append(names, child.name)
+	}
+	if strings.Join(names, " ") != ".chezmoiscripts .config .zshrc" {
+		t.Errorf("Expected directories before files, got %v", names)
+	}
+
+	config := root.children[1]
+	if !config.dir || config.counts.Modified != 1 || config.counts.Added != 1 {
+		t.Errorf("Expected .config to aggregate its changes, got %+v", config.counts)
+	}
+	if got := describeCounts(root.counts); got != "1 modified, 1 added, 1 scripts" {
+		t.Errorf("Unexpected summary %q", got)
+	}
+	if root.children[2].entry != nil {
+		t.Error("Expected .zshrc to be up to date")
+	}
+}
+
+func TestTreeView(t *testing.T) {
+	m := newTreeModel(t, chezmoitest.NewFake())
+
+	view := m.View()
+	for _, expected := range []string{"▸ .config/ (2 modified, 1 added)", "[ M] .bashrc", "1 added"} {
+		if !strings.Contains(view, expected) {
+			t.Errorf("Expected the tree to contain %q, got:\n%s", expected, view)
+		}
+	}
+
+	// Expand .config and then .config/nvim
+	press(m, "enter")
+	press(m, "down")
+	press(m, "down")
+	press(m, "l")
+	view = m.View()
+	for _, expected := range []string{"▾ .config/", "  ▸ git/ (1 added)", "  ▾ nvim/ (2 modified)", "      [ M] init.lua", "    ▸ lua/ (1 modified)"} {
+		if !strings.Contains(view, expected) {
+			t.Errorf("Expected the tree to contain %q, got:\n%s", expected, view)
+		}
+	}
+
+	// Collapsing .config hides everything below it
+	m.treeCursor = 0
+	press(m, " ")
+	if len(m.treeRows) != 2 {
+		t.Errorf("Expected two rows after collapsing, got %d", len(m.treeRows))
+	}
+
+	press(m, "t")
+	if m.treeMode || !strings.Contains(m.View(), "Chezmoi File Status") {
+		t.Error("Expected t to return to the list")
+	}
+}
+
+func TestTreeSubtreeActions(t *testing.T) {
+	fake := chezmoitest.NewFake()
+	m := newTreeModel(t, fake)
+
+	// The cursor is on .config
+	fake.On("diff", "--use-builtin-diff", "--no-pager", "--color=false", "/home/user/.config").Stdout("--- a/.config/git/config\n+++ b/.config/git/config\n@@ -0,0 +1 @@\n+[user]\n")
+	runCmd(m, press(m, "d"))
+	if m.screen != screenDiff || m.diff.title != "Diff /home/user/.config" {
+		t.Fatalf("Expected the diff of .config, got screen %v titled %q", m.screen, m.diff.title)
+	}
+	press(m, "h")
+	if m.screen != screenStatus || !m.treeMode {
+		t.Fatal("Expected to return to the tree")
+	}
+
+	fake.On("apply", "--no-tty", "/home/user/.config")
+	press(m, "a")
+	if m.pending == nil || len(m.pendingTargets) != 1 || m.pendingTargets[0] != "/home/user/.config" {
+		t.Fatalf("Expected to apply .config, got %v", m.pendingTargets)
+	}
+	runCmd(m, press(m, "y"))
+	if fake.CallCount("apply", "--no-tty", "/home/user/.config") != 1 {
+		t.Error("Expected .config to be applied")
+	}
+}
@@ -1,18 +1,28 @@
 package ui
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/charmbracelet/bubbles/help"
 	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/bubbles/spinner"
 	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
 
+	"chezmoi-tui/internal/bitwarden"
+	"chezmoi-tui/internal/chezmoi"
+	"chezmoi-tui/internal/config"
+	"chezmoi-tui/internal/fsutil"
 	"chezmoi-tui/internal/integration"
+	"chezmoi-tui/internal/watch"
 )
 
 var (
@@ -24,22 +34,6 @@ var (
 	quitTextStyle     = lipgloss.NewStyle().Margin(1, 0, 2, 4)
 )
 
-type StatusType int
-
-const (
-	StatusUpToDate StatusType = iota
-	StatusModified
-	StatusUnmanaged
-	StatusIgnored
-)
-
-type FileStatus struct {
-	Name         string
-	Type         StatusType
-	DestStatus   string
-	TargetStatus string
-}
-
 // item implements list.Item
 type item struct {
 	title, desc string
@@ -49,87 +43,278 @@ func (i item) Title() string       { return i.title }
 func (i item) Description() string { return i.desc }
 func (i item) FilterValue() string { return i.title }
 
+// screen identifies what the TUI is currently showing
+type screen int
+
+const (
+	screenMenu screen = iota
+	screenStatus
+	screenStats
+	screenBitwarden
+	screenApply
+	screenLog
+	screenAdd
+	screenDiff
+	screenPatch
+	screenDetail
+)
+
 // Model represents the state of the TUI
 type Model struct {
-	// Integration layer
+	// Integration layer. destDir is chezmoi's destination directory, which
+	// is resolved in the background when the status or Add Files is opened.
 	integration *integration.ChezmoiIntegration
-	
+	destDir     string
+
 	// Main menu state
-	choice    int
-	choices   []string
-	cursor    int
-	quitting  bool
-	
+	choice   int
+	choices  []string
+	cursor   int
+	quitting bool
+	screen   screen
+
 	// Status list view
 	statusList list.Model
-	
-	// File status view
-	fileCursor int
-	fileStatus []FileStatus
-	showFiles  bool
-	help       help.Model
-	viewport   viewport.Model
+
+	// File status view. fileCursor indexes the entries listed by the filter.
+	fileCursor   int
+	fileStatus   []chezmoi.StatusEntry
+	statusErr    error
+	filter       statusFilter
+	listed       []listedEntry
+	sources      map[string]string
+	sourcesStale bool
+	sourcesErr   error
+
+	// Tree view of the managed files
+	treeMode     bool
+	tree         *treeNode
+	treeRows     []treeRow
+	treeCursor   int
+	expanded     map[string]bool
+	managed      []string
+	managedStale bool
+	treeErr      error
+	help         help.Model
+	viewport     viewport.Model
+
+	// File browser actions
+	selected       map[string]bool
+	pending        *fileAction
+	pendingTargets []string
+	pendingLocal   []chezmoi.StatusEntry
+	refreshOnBack  bool
+	editorErr      error
+
+	// returnScreen is the screen that going back from the log pane or the
+	// diff viewer returns to
+	returnScreen screen
+
+	// Add Files view
+	picker filePicker
+
+	// Apply view
+	confirmApply    bool
+	applyPreview    []chezmoi.StatusEntry
+	applyPreviewErr error
+	log             logPane
+
+	// Diff view
+	diff        diffViewer
+	diffErr     error
+	diffTargets []string
+
+	// Preview of the hunks selected in the diff view
+	patchView   diffViewer
+	patches     []integration.FilePatch
+	patchTarget integration.PatchTarget
+
+	// Source details of the inspected target
+	details   *integration.TargetDetails
+	detailErr error
+
+	// In-flight operation state. op identifies the latest operation so that
+	// the results of superseded ones are dropped.
+	loading  bool
+	cancel   context.CancelFunc
+	op       int
+	spinner  spinner.Model
+	spinning bool
+
+	// Stats view
+	statsErr error
+
+	// Bitwarden manager. bitwarden is nil if bw is not installed.
+	// templatePath is the source file that fields are added to. copied is
+	// the last value copied to the clipboard until it is cleared.
+	bitwarden    *bitwarden.Client
+	vault        vaultView
+	templatePath string
+	copied       string
+	// sessionSeq identifies the latest scheduled check of the session
+	sessionSeq int
+
+	// Watching the files that decide the status. pendingChange holds the
+	// changes that have not been refreshed yet.
+	watchEnabled  bool
+	watchStarted  bool
+	watcher       *watch.Watcher
+	watchErr      error
+	pendingChange *watch.Change
+
+	// Settings from the configuration file
+	refreshInterval time.Duration
+	refreshSeq      int
+	maxFileDisplay  int
+}
+
+// statusLoadedMsg carries the result of an asynchronous status load
+type statusLoadedMsg struct {
+	op      int
+	destDir string
+	entries []chezmoi.StatusEntry
+	err     error
+}
+
+// applyPreviewMsg carries the status of the files that apply would change
+type applyPreviewMsg struct {
+	op      int
+	entries []chezmoi.StatusEntry
+	err     error
 }
 
-// RunTUI starts the terminal user interface
-func RunTUI() error {
+// statsLoadedMsg carries the rendered statistics
+type statsLoadedMsg struct {
+	op      int
+	content string
+	err     error
+}
+
+// statusRefreshMsg triggers a periodic reload of the status screen. Ticks
+// from superseded refresh schedules are ignored by their sequence number.
+type statusRefreshMsg struct {
+	seq int
+}
+
+// RunTUI starts the terminal user interface. bw is the client of the
+// Bitwarden manager, nil if bw is not installed.
+func RunTUI(cfg *config.Config, bw *bitwarden.Client, opts ...chezmoi.Option) error {
 	// Initialize integration layer
-	integ, err := integration.New()
+	integ, err := integration.New(opts...)
 	if err != nil {
 		return fmt.Errorf("failed to initialize chezmoi integration: %w", err)
 	}
-	
+
 	model := initialModel(integ)
+	model.bitwarden = bw
+	model.configure(cfg)
 	p := tea.NewProgram(&model, tea.WithAltScreen())
 	_, err = p.Run()
+	model.stopWatch()
+	model.clearCopied()
 	return err
 }
 
 // initialModel returns the initial state of the UI
 func initialModel(integ *integration.ChezmoiIntegration) Model {
 	choices := []string{"View Status", "Add Files", "Apply Changes", "Diff Changes", "Show Stats", "Bitwarden Manager", "Exit"}
-	
+
 	// Create items for the list
 	var items []list.Item
 	for _, choice := range choices {
 		items = append(items, item{title: choice, desc: getDescription(choice)})
 	}
 
-	// Create a custom delegate for our file list
-	delegate := list.NewDefaultDelegate()
-	delegate.Styles.NormalTitle = itemStyle
-	delegate.Styles.SelectedTitle = selectedItemStyle
-	
 	// Create the status list
-	statusList := list.New(items, delegate, 0, 0)
+	statusList := list.New(items, newMenuDelegate(), 0, 0)
 	statusList.Title = "Chezmoi TUI - Enhanced dotfile management"
 	statusList.SetShowStatusBar(false)
 	statusList.SetFilteringEnabled(false)
 	statusList.Styles.Title = titleStyle
-	
+
 	// Set key bindings
 	statusList.KeyMap.Quit = key.NewBinding(
 		key.WithKeys("q", "ctrl+c"),
 		key.WithHelp("q", "quit"),
 	)
-	
+
 	return Model{
 		choices:     choices,
 		integration: integ,
-		fileStatus:  []FileStatus{},
+		fileStatus:  []chezmoi.StatusEntry{},
+		filter:      newStatusFilter(),
+		expanded:    make(map[string]bool),
+		selected:    make(map[string]bool),
+		vault:       newVaultView(),
 		statusList:  statusList,
 		help:        help.New(),
+		spinner:     spinner.New(spinner.WithSpinner(spinner.MiniDot)),
 		viewport:    viewport.New(78, 20), // width and height
+		log:         newLogPane("Applying changes", 78, 20),
+		diff:        newDiffViewer(78, 20),
+		patchView:   newDiffViewer(78, 19),
+	}
+}
+
+// configure applies the configuration file settings to the model
+func (m *Model) configure(cfg *config.Config) {
+	applyTheme(cfg.Theme)
+	if !cfg.CLI.Color {
+		lipgloss.SetColorProfile(termenv.Ascii)
+	}
+
+	m.statusList.SetDelegate(newMenuDelegate())
+	m.statusList.Styles.Title = titleStyle
+	m.statusList.SetShowHelp(cfg.TUI.ShowHelp)
+	m.refreshInterval = cfg.RefreshInterval()
+	m.watchEnabled = cfg.TUI.Watch
+	m.maxFileDisplay = cfg.TUI.MaxFileDisplay
+	if cfg.Bitwarden.TemplatePath != "" {
+		m.templatePath = fsutil.ExpandHome(cfg.Bitwarden.TemplatePath)
 	}
 }
 
+// applyTheme sets the package styles from the theme colours
+func applyTheme(theme config.ThemeConfig) {
+	titleStyle = lipgloss.NewStyle().MarginLeft(2).Foreground(lipgloss.Color(theme.SecondaryColor))
+	selectedItemStyle = itemStyle.Copy().Foreground(lipgloss.Color(theme.PrimaryColor))
+	filterMatchStyle = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color(theme.PrimaryColor))
+	logStderrStyle = lipgloss.NewStyle().Foreground(lipgloss.Color(theme.WarningColor))
+	logErrorStyle = lipgloss.NewStyle().Foreground(lipgloss.Color(theme.DangerColor))
+	logOKStyle = lipgloss.NewStyle().Foreground(lipgloss.Color(theme.SuccessColor))
+	diffHunkStyle = lipgloss.NewStyle().Foreground(lipgloss.Color(theme.SecondaryColor))
+	diffAddStyle = lipgloss.NewStyle().Foreground(lipgloss.Color(theme.SuccessColor))
+	diffRemoveStyle = lipgloss.NewStyle().Foreground(lipgloss.Color(theme.DangerColor))
+	diffAddWordStyle = diffAddStyle.Reverse(true)
+	diffRemoveWordStyle = diffRemoveStyle.Reverse(true)
+}
+
+// newMenuDelegate returns the delegate rendering the main menu entries
+func newMenuDelegate() list.DefaultDelegate {
+	delegate := list.NewDefaultDelegate()
+	delegate.Styles.NormalTitle = itemStyle
+	delegate.Styles.SelectedTitle = selectedItemStyle
+	return delegate
+}
+
 // Init is the initial command for the TUI
 func (m *Model) Init() tea.Cmd {
-	return nil
+	return m.scheduleSessionCheck()
 }
 
-// Update handles messages and updates the model
+// Update handles messages and updates the model. The spinner is started
+// whenever a message starts an operation.
 func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	cmd := m.update(msg)
+	if m.loading && !m.spinning {
+		m.spinning = true
+		cmd = tea.Batch(cmd, m.spinner.Tick)
+	}
+	return m, cmd
+}
+
+// update handles a message and returns the command to run next
+func (m *Model) update(msg tea.Msg) tea.Cmd {
 	var (
 		cmd  tea.Cmd
 		cmds []tea.Cmd
@@ -141,252 +326,748 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.statusList.SetSize(msg.Width-h, msg.Height-v)
 		m.viewport.Width = msg.Width
 		m.viewport.Height = msg.Height - 15 // Leave space for header and footer
+		m.log.SetSize(msg.Width, msg.Height-4)
+		m.diff.Update(msg)
+		m.patchView.Update(tea.WindowSizeMsg{Width: msg.Width, Height: msg.Height - 1}) // confirmation prompt
+
+	case spinner.TickMsg:
+		if !m.loading {
+			m.spinning = false
+			return nil
+		}
+		m.spinner, cmd = m.spinner.Update(msg)
+		m.log.spinner = m.spinner.View()
+		return cmd
+
+	case statusLoadedMsg:
+		if !m.finishOperation(msg.op) {
+			return nil
+		}
+		if msg.destDir != "" {
+			m.destDir = msg.destDir
+		}
+		m.setFileStatus(msg.entries, msg.err)
+		if msg.err != nil {
+			return m.scheduleRefresh()
+		}
+		return tea.Batch(m.scheduleRefresh(), m.startWatch(), m.continueStatusLoad())
+
+	case sourcesLoadedMsg:
+		if m.finishOperation(msg.op) {
+			m.setSources(msg.entries, msg.err)
+			return m.continueStatusLoad()
+		}
+		return nil
+
+	case managedLoadedMsg:
+		if m.finishOperation(msg.op) {
+			m.setManaged(msg.paths, msg.err)
+			return m.continueStatusLoad()
+		}
+		return nil
+
+	case statusUpdatedMsg:
+		if !m.finishOperation(msg.op) {
+			return nil
+		}
+		if msg.err != nil {
+			m.setFileStatus(nil, msg.err)
+			return nil
+		}
+		m.updateFileStatus(msg.targets, msg.entries)
+		return m.refreshChanged()
+
+	case watchStartedMsg:
+		return m.setWatcher(msg)
+
+	case fileChangeMsg:
+		return m.handleFileChange(msg.change)
+
+	case watchStoppedMsg:
+		return m.handleWatchStopped(msg.watcher)
+
+	case applyPreviewMsg:
+		if m.finishOperation(msg.op) {
+			m.applyPreview, m.applyPreviewErr = msg.entries, msg.err
+		}
+		return nil
+
+	case statsLoadedMsg:
+		if m.finishOperation(msg.op) {
+			m.statsErr = msg.err
+			m.viewport.SetContent(msg.content)
+			m.viewport.GotoTop()
+		}
+		return nil
+
+	case diffLoadedMsg:
+		if m.finishOperation(msg.op) {
+			m.diffErr = msg.err
+			if msg.err == nil {
+				m.diff.SetDiff(msg.output)
+			}
+		}
+		return nil
+
+	case destDirMsg:
+		if m.finishOperation(msg.op) {
+			m.openPicker(msg.destDir, msg.err)
+		}
+		return nil
+
+	case detailLoadedMsg:
+		if m.finishOperation(msg.op) {
+			m.details, m.detailErr = msg.details, msg.err
+		}
+		return nil
+
+	case patchPreparedMsg:
+		if m.finishOperation(msg.op) {
+			m.showPatch(msg)
+		}
+		return nil
+
+	case patchWrittenMsg:
+		if m.finishOperation(msg.op) {
+			return m.finishPatch(msg.err)
+		}
+		return nil
+
+	case vaultLoadedMsg:
+		if m.finishOperation(msg.op) {
+			return m.setVault(msg)
+		}
+		return nil
+
+	case sessionCheckedMsg:
+		return m.handleSessionChecked(msg)
+
+	case clipboardClearMsg:
+		clearClipboard(msg.value)
+		if m.copied == msg.value {
+			m.copied = ""
+		}
+		return nil
+
+	case statusRefreshMsg:
+		if msg.seq == m.refreshSeq && m.screen == screenStatus && !m.loading {
+			return m.loadStatus()
+		}
+		return nil
+
+	case logLineMsg:
+		// Keep draining superseded streams so that their commands can exit
+		if msg.stream.op == m.op {
+			m.log.AppendLine(msg.line)
+		}
+		return waitForLog(msg.stream)
+
+	case logDoneMsg:
+		if m.finishOperation(msg.op) {
+			m.log.Finish(msg.err)
+		}
+		return nil
+
+	case editorFinishedMsg:
+		m.editorErr = msg.err
+		return m.loadStatus()
 
 	case tea.KeyMsg:
+		// Keys go to the fuzzy filter while it is typed
+		if m.screen == screenStatus && m.filter.query.Focused() && msg.String() != "ctrl+c" {
+			return m.handleFilterInput(msg)
+		}
+		if m.screen == screenBitwarden && m.vault.inputFocused() && msg.String() != "ctrl+c" {
+			return m.handleVaultInput(msg)
+		}
+
+		// Cancel the in-flight operation instead of navigating
+		if m.loading && msg.String() == "esc" {
+			m.cancel()
+			return nil
+		}
+
 		// Don't forward quit or back commands to the list when showing files
-		if m.showFiles && (msg.String() == "h" || msg.String() == "left") {
-			m.showFiles = false
-			return m, nil
+		if m.screen != screenMenu && (msg.String() == "h" || msg.String() == "left") {
+			return m.back()
+		}
+
+		if m.confirmApply {
+			return m.handleApplyConfirmation(msg)
+		}
+		if m.pending != nil {
+			return m.handleActionConfirmation(msg)
+		}
+		if m.screen == screenStatus {
+			if cmd, ok := m.handleBrowserKey(msg); ok {
+				return cmd
+			}
+		}
+		if m.screen == screenAdd {
+			if cmd, ok := m.handleAddKey(msg); ok {
+				return cmd
+			}
+		}
+		if m.screen == screenBitwarden {
+			if cmd, ok := m.handleVaultKey(msg); ok {
+				return cmd
+			}
+		}
+		if m.screen == screenDiff && !m.loading {
+			if cmd, ok := m.handleDiffKey(msg); ok {
+				return cmd
+			}
+		}
+		if m.screen == screenPatch && !m.loading && msg.String() != "q" && msg.String() != "ctrl+c" {
+			return m.handlePatchConfirmation(msg)
 		}
 
 		switch msg.String() {
 		case "ctrl+c", "q":
+			if m.cancel != nil {
+				m.cancel()
+			}
 			m.quitting = true
-			return m, tea.Quit
+			return tea.Quit
 
 		case "enter":
-			if !m.showFiles {
+			if m.screen == screenMenu {
 				m.choice = m.statusList.Index()
 				selectedItem := m.statusList.SelectedItem()
 				if selectedItem != nil {
 					item := selectedItem.(item)
 					if item.title == "Exit" {
 						m.quitting = true
-						return m, tea.Quit
+						return tea.Quit
 					} else if item.title == "View Status" {
-						// Load file status using the integration layer
-						statusData, err := m.integration.GetStatus()
-						if err != nil {
-							// Handle error - for now just show an error message
-							m.fileStatus = []FileStatus{
-								{Name: fmt.Sprintf("Error loading status: %v", err), Type: StatusIgnored},
-							}
-						} else {
-							// Convert the status data to our internal format
-							m.fileStatus = make([]FileStatus, len(statusData))
-							for i, entry := range statusData {
-								destStatus := entry["dest_status"]
-								targetStatus := entry["target_status"]
-								filename := entry["filename"]
-								
-								statusType := getStatusType(destStatus, targetStatus)
-								
-								m.fileStatus[i] = FileStatus{
-									Name:         filename,
-									Type:         statusType,
-									DestStatus:   destStatus,
-									TargetStatus: targetStatus,
-								}
-							}
-						}
-						m.showFiles = true
+						return m.loadStatus()
+					} else if item.title == "Add Files" {
+						return m.openAddFiles()
+					} else if item.title == "Apply Changes" {
+						m.screen = screenApply
+						m.confirmApply = true
+						return m.loadApplyPreview()
+					} else if item.title == "Diff Changes" {
+						m.returnScreen = screenMenu
+						return m.loadDiff("Diff Changes")
 					} else if item.title == "Show Stats" {
-						// Show statistics about the dotfiles
-						statsContent, err := generateStatsContent(m.integration)
-						if err != nil {
-							statsContent = fmt.Sprintf("Error loading stats: %v", err)
-						}
-						m.viewport.SetContent(statsContent)
-						m.showFiles = true // Reuse the file view for stats display
+						return m.loadStats()
 					} else if item.title == "Bitwarden Manager" {
-						// Show Bitwarden manager information
-						bwContent := generateBitwardenContent()
-						m.viewport.SetContent(bwContent)
-						m.showFiles = true
+						return m.loadVault()
 					}
 				}
 			}
 		case "l", "right":
-			if !m.showFiles {
+			if m.screen == screenMenu {
 				// Check if "View Status" is selected
 				selectedItem := m.statusList.SelectedItem()
 				if selectedItem != nil {
 					item := selectedItem.(item)
 					if item.title == "View Status" {
-						// Load file status using the integration layer
-						statusData, err := m.integration.GetStatus()
-						if err != nil {
-							// Handle error - for now just show an error message
-							m.fileStatus = []FileStatus{
-								{Name: fmt.Sprintf("Error loading status: %v", err), Type: StatusIgnored},
-							}
-						} else {
-							// Convert the status data to our internal format
-							m.fileStatus = make([]FileStatus, len(statusData))
-							for i, entry := range statusData {
-								destStatus := entry["dest_status"]
-								targetStatus := entry["target_status"]
-								filename := entry["filename"]
-								
-								statusType := getStatusType(destStatus, targetStatus)
-								
-								m.fileStatus[i] = FileStatus{
-									Name:         filename,
-									Type:         statusType,
-									DestStatus:   destStatus,
-									TargetStatus: targetStatus,
-								}
-							}
-						}
-						m.showFiles = true
+						return m.loadStatus()
 					}
 				}
 			}
 		}
 	}
 
-	// Update the status list unless we're showing files
-	if !m.showFiles {
+	// Update the status list unless we're showing another screen
+	if m.screen == screenMenu {
 		m.statusList, cmd = m.statusList.Update(msg)
 		cmds = append(cmds, cmd)
 	}
 
-	// Update the viewport if needed
-	m.viewport, cmd = m.viewport.Update(msg)
-	cmds = append(cmds, cmd)
+	// Scroll whichever viewport is visible
+	switch m.screen {
+	case screenApply, screenLog:
+		cmds = append(cmds, m.log.Update(msg))
+	case screenDiff:
+		cmds = append(cmds, m.diff.Update(msg))
+	default:
+		m.viewport, cmd = m.viewport.Update(msg)
+		cmds = append(cmds, cmd)
+	}
+
+	return tea.Batch(cmds...)
+}
+
+// back leaves the current screen, cancelling any operation in flight. The
+// output of a file action returns to the screen it was started from,
+// everything else to the main menu.
+func (m *Model) back() tea.Cmd {
+	if m.loading {
+		// A patch that is being written cannot be cancelled
+		if m.screen == screenPatch {
+			return nil
+		}
+		m.abandonOperation()
+	}
+	m.confirmApply = false
+	m.pending, m.pendingLocal = nil, nil
 
-	return m, tea.Batch(cmds...)
+	if m.screen == screenPatch {
+		m.patches = nil
+		m.screen = screenDiff
+		return nil
+	}
+	if m.screen == screenBitwarden && m.vault.item != nil {
+		m.showVaultItem(nil)
+		return nil
+	}
+	if m.screen == screenLog || m.screen == screenDiff || m.screen == screenDetail {
+		m.screen = m.returnScreen
+		if m.refreshOnBack && m.screen == screenStatus {
+			m.refreshOnBack = false
+			return m.loadStatus()
+		}
+		return m.refreshChanged()
+	}
+	m.screen = screenMenu
+	return nil
 }
 
-// getStatusType determines the status type based on chezmoi status codes
-func getStatusType(destStatus, targetStatus string) StatusType {
-	// This is a simplification - in real world you'd have more complex logic
-	if strings.Contains(destStatus, "M") || strings.Contains(targetStatus, "M") {
-		return StatusModified
+// loadStatus starts loading the file status in the background. The load can
+// be cancelled with esc while it is running.
+func (m *Model) loadStatus() tea.Cmd {
+	ctx, op := m.startOperation()
+	m.screen = screenStatus
+	m.pendingChange = nil
+	// Files may have been added or forgotten
+	m.sourcesStale, m.managedStale = true, true
+
+	integ := m.integration
+	return func() tea.Msg {
+		destDir, err := integ.DestDir(ctx)
+		if err != nil {
+			return statusLoadedMsg{op: op, err: err}
+		}
+		entries, err := integ.GetStatus(ctx)
+		return statusLoadedMsg{op: op, destDir: destDir, entries: entries, err: err}
 	}
-	if strings.Contains(destStatus, "A") || strings.Contains(targetStatus, "A") {
-		return StatusUnmanaged
+}
+
+// loadStats starts generating the statistics in the background
+func (m *Model) loadStats() tea.Cmd {
+	ctx, op := m.startOperation()
+	m.screen = screenStats
+	m.statsErr = nil
+
+	integ := m.integration
+	return func() tea.Msg {
+		content, err := generateStatsContent(ctx, integ)
+		return statsLoadedMsg{op: op, content: content, err: err}
 	}
-	if strings.Contains(destStatus, "D") || strings.Contains(targetStatus, "D") {
-		return StatusUnmanaged // deleted
+}
+
+// continueStatusLoad reloads the data besides the status that the status
+// screen needs, one operation at a time, and then refreshes the files that
+// changed in the meantime
+func (m *Model) continueStatusLoad() tea.Cmd {
+	switch {
+	case m.filter.NeedsSources() && (m.sources == nil || m.sourcesStale):
+		return m.loadSources()
+	case m.treeMode && (m.managed == nil || m.managedStale):
+		return m.loadManaged()
 	}
-	return StatusUpToDate
+	return m.refreshChanged()
 }
 
-// View renders the UI
-func (m *Model) View() string {
-	if m.quitting {
-		return quitTextStyle.Render("Bye!")
+// scheduleRefresh reloads the status screen after the configured refresh
+// interval, superseding any refresh scheduled before. The status is not
+// polled while files are watched.
+func (m *Model) scheduleRefresh() tea.Cmd {
+	m.refreshSeq++
+	if m.refreshInterval <= 0 || m.screen != screenStatus || m.watcher != nil {
+		return nil
 	}
 
-	if m.showFiles {
-		// File status view
-		if len(m.fileStatus) == 0 {
-			return "No files to display. Press 'h' to go back.\n"
+	seq := m.refreshSeq
+	return tea.Tick(m.refreshInterval, func(time.Time) tea.Msg {
+		return statusRefreshMsg{seq: seq}
+	})
+}
+
+// loadApplyPreview starts loading the status of the files that apply would
+// change, which is shown above the apply prompt
+func (m *Model) loadApplyPreview() tea.Cmd {
+	ctx, op := m.startOperation()
+	m.applyPreview, m.applyPreviewErr = nil, nil
+
+	integ := m.integration
+	return func() tea.Msg {
+		entries, err := integ.GetStatus(ctx)
+		return applyPreviewMsg{op: op, entries: entries, err: err}
+	}
+}
+
+// handleApplyConfirmation answers the apply prompt, starting chezmoi apply
+// and streaming its output into the log pane on confirmation. The prompt
+// can only be confirmed once the preview shows something to apply.
+func (m *Model) handleApplyConfirmation(msg tea.KeyMsg) tea.Cmd {
+	switch msg.String() {
+	case "y", "Y":
+		if m.loading || len(m.applyPreview) == 0 {
+			return nil
 		}
+		m.confirmApply = false
+		ctx, op := m.startOperation()
+		m.log.Reset("Applying changes")
 
-		// Create content for the viewport
-		var content strings.Builder
-		content.WriteString("Chezmoi File Status\n\n")
+		integ, opts := m.integration, applyOptions(len(localChanges(m.applyPreview)) > 0)
+		return startLogStream(ctx, op, func(ctx context.Context, streams chezmoi.IOStreams) error {
+			return integ.StreamApplyFiles(ctx, streams, opts)
+		})
+	case "n", "N", "esc":
+		m.confirmApply = false
+		m.screen = screenMenu
+	}
+	return nil
+}
 
-		for i, file := range m.fileStatus {
-			cursor := " "
-			if m.fileCursor == i {
-				cursor = "→"
-			}
-			
-			statusSymbol := " "
-			switch file.Type {
-			case StatusModified:
-				statusSymbol = "M"
-			case StatusUnmanaged:
-				statusSymbol = "A"
-			case StatusIgnored:
-				statusSymbol = "I"
-			default:
-				statusSymbol = " "
-			}
-			
-			content.WriteString(fmt.Sprintf("%s [%s] %s\n", cursor, statusSymbol, file.Name))
+// startOperation marks an operation as in flight, cancelling any operation
+// it supersedes, and returns the context it must run under together with
+// the id its result message must carry
+func (m *Model) startOperation() (context.Context, int) {
+	if m.cancel != nil {
+		m.cancel()
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	m.op++
+	m.loading = true
+	m.cancel = cancel
+	return ctx, m.op
+}
+
+// finishOperation clears the in-flight operation state if op is the latest
+// operation and reports whether it is. Results of operations that were
+// superseded must be dropped.
+func (m *Model) finishOperation(op int) bool {
+	if op != m.op {
+		return false
+	}
+	if m.cancel != nil {
+		m.cancel()
+	}
+	m.loading = false
+	m.cancel = nil
+	return true
+}
+
+// abandonOperation cancels the in-flight operation and drops its result,
+// so that the screen it was started from can be left without waiting for it
+func (m *Model) abandonOperation() {
+	m.finishOperation(m.op)
+	m.op++
+}
+
+// setFileStatus records the result of a status load, keeping the cursor and
+// the selection on files that are still listed
+func (m *Model) setFileStatus(entries []chezmoi.StatusEntry, err error) {
+	m.statusErr = err
+	m.fileStatus = entries
+
+	listed := make(map[string]bool, len(entries))
+	for _, entry := range entries {
+		listed[entry.Path] = true
+	}
+	for path := range m.selected {
+		if !listed[path] {
+			delete(m.selected, path)
 		}
+	}
+	m.applyFilter()
+	m.rebuildTree()
+}
 
-		content.WriteString(fmt.Sprintf("\n%d files total | Use arrow keys to navigate, 'h' to go back, 'q' to quit\n", len(m.fileStatus)))
+// renderError renders a failed operation together with a remediation hint
+// when chezmoi's error output identifies the cause
+func renderError(action string, err error) string {
+	if errors.Is(err, context.Canceled) {
+		return fmt.Sprintf("%s was cancelled.\n\nPress 'h' to go back.\n", action)
+	}
 
-		m.viewport.SetContent(content.String())
-		return m.viewport.View()
-	} else {
+	var content strings.Builder
+	content.WriteString(fmt.Sprintf("Error %s: %v\n", strings.ToLower(action), err))
+	hint := chezmoi.Remediation(err)
+	if hint == "" {
+		hint = bitwarden.Remediation(err)
+	}
+	if hint != "" {
+		content.WriteString(fmt.Sprintf("\nHint: %s\n", hint))
+	}
+	content.WriteString("\nPress 'h' to go back.\n")
+	return content.String()
+}
+
+// View renders the UI
+func (m *Model) View() string {
+	if m.quitting {
+		return quitTextStyle.Render("Bye!")
+	}
+
+	switch m.screen {
+	case screenStatus:
+		return m.statusView()
+	case screenStats:
+		return m.statsView()
+	case screenBitwarden:
+		return m.bitwardenView()
+	case screenApply:
+		if m.confirmApply {
+			return m.applyView()
+		}
+		return m.log.View()
+	case screenAdd:
+		return m.addView()
+	case screenDiff:
+		return m.diffView()
+	case screenPatch:
+		return m.patchPreviewView()
+	case screenDetail:
+		return m.detailView()
+	case screenLog:
+		if m.pending != nil {
+			return m.confirmView()
+		}
+		return m.log.View()
+	default:
 		// Main menu
 		return m.statusList.View()
 	}
 }
 
-func generateStatsContent(integ *integration.ChezmoiIntegration) (string, error) {
-	// Get status information
-	statusData, err := integ.GetStatus()
-	if err != nil {
-		return "", fmt.Errorf("could not get status data: %w", err)
+// statusView renders the file status screen
+func (m *Model) statusView() string {
+	// Keep showing the previous status while a refresh is running
+	if m.loading && len(m.fileStatus) == 0 {
+		return quitTextStyle.Render(m.spinner.View() + " Loading status... (esc to cancel)")
 	}
 
-	// Get all managed files
-	managedOutput, err := integ.GetManagedFiles()
-	if err != nil {
-		return "", fmt.Errorf("could not get managed files: %w", err)
+	if m.statusErr != nil {
+		return quitTextStyle.Render(renderError("Loading status", m.statusErr))
+	}
+	if m.treeMode {
+		return m.treeView()
 	}
 
-	// Get unmanaged files
-	unmanagedOutput, err := integ.GetUnmanagedFiles()
-	if err != nil {
-		// This is okay, sometimes there are no unmanaged files
+	// File status view
+	if len(m.fileStatus) == 0 {
+		return "No files to display. Press 'h' to go back.\n"
 	}
 
-	// Calculate stats
-	var modifiedCount, addedCount, deletedCount, upToDateCount int
-	for _, entry := range statusData {
-		destStatus := entry["dest_status"]
-		targetStatus := entry["target_status"]
-		
-		if strings.Contains(destStatus, "M") || strings.Contains(targetStatus, "M") {
-			modifiedCount++
-		} else if strings.Contains(destStatus, "A") || strings.Contains(targetStatus, "A") {
-			addedCount++
-		} else if strings.Contains(destStatus, "D") || strings.Contains(targetStatus, "D") {
-			deletedCount++
-		} else {
-			upToDateCount++
-		}
+	start, end := visibleRange(m.fileCursor, len(m.listed), m.maxFileDisplay)
+
+	// Create content for the viewport
+	var content strings.Builder
+	content.WriteString("Chezmoi File Status")
+	if m.loading {
+		content.WriteString("  " + m.spinner.View() + " refreshing")
+	}
+	content.WriteString("\n")
+	line := 2 + m.fileCursor - start
+	switch {
+	case m.filter.query.Focused():
+		content.WriteString(m.filter.query.View() + "\n")
+		line++
+	case m.filter.Active():
+		content.WriteString("Filter: " + m.filter.Describe() + " (esc to clear)\n")
+		line++
+	}
+	content.WriteString("\n")
+	if len(m.listed) == 0 {
+		content.WriteString("  No files match the filter.\n")
+	}
+	if start > 0 {
+		content.WriteString(fmt.Sprintf("  ... %d more above\n", start))
+		line++
 	}
 
-	managedFiles := strings.Split(strings.TrimSpace(managedOutput), "\n")
-	var validManagedFiles []string
-	for _, file := range managedFiles {
-		if strings.TrimSpace(file) != "" {
-			validManagedFiles = append(validManagedFiles, file)
+	for i := start; i < end; i++ {
+		file := m.listed[i]
+		cursor := " "
+		if m.fileCursor == i {
+			cursor = "→"
+		}
+		mark := " "
+		if m.selected[file.Path] {
+			mark = "✓"
 		}
+		content.WriteString(fmt.Sprintf("%s %s [%s%s] %s\n", cursor, mark, file.DestStatus.Symbol(), file.TargetStatus.Symbol(), renderPath(file.Path, file.matched)))
+	}
+	if end < len(m.listed) {
+		content.WriteString(fmt.Sprintf("  ... and %d more\n", len(m.listed)-end))
 	}
 
-	unmanagedFiles := strings.Split(strings.TrimSpace(unmanagedOutput), "\n")
-	var validUnmanagedFiles []string
-	for _, file := range unmanagedFiles {
-		if strings.TrimSpace(file) != "" {
-			validUnmanagedFiles = append(validUnmanagedFiles, file)
+	if m.filter.Active() {
+		content.WriteString(fmt.Sprintf("\n%d of %d files shown, %d selected\n", len(m.listed), len(m.fileStatus), len(m.selected)))
+	} else {
+		content.WriteString(fmt.Sprintf("\n%d files total, %d selected\n", len(m.fileStatus), len(m.selected)))
+	}
+	if m.sourcesErr != nil && m.filter.NeedsSources() {
+		content.WriteString(logErrorStyle.Render(fmt.Sprintf("Cannot filter by attributes: %v", m.sourcesErr)) + "\n")
+	}
+	if m.editorErr != nil {
+		content.WriteString(logErrorStyle.Render(fmt.Sprintf("Editor failed: %v", m.editorErr)) + "\n")
+	}
+	if m.watchErr != nil {
+		content.WriteString(logStderrStyle.Render(fmt.Sprintf("Not watching files: %v", m.watchErr)) + "\n")
+	}
+	content.WriteString(browserHelp + "\n")
+
+	return m.scrollView(content.String(), line)
+}
+
+// applyView renders the preview of the changes apply would make together
+// with the confirmation prompt
+func (m *Model) applyView() string {
+	switch {
+	case m.loading:
+		return quitTextStyle.Render(m.spinner.View() + " Loading changes to apply... (esc to cancel)")
+	case m.applyPreviewErr != nil:
+		return quitTextStyle.Render(renderError("Loading changes to apply", m.applyPreviewErr))
+	case len(m.applyPreview) == 0:
+		return quitTextStyle.Render("Nothing to apply, your destination directory matches the target state.\n\nPress 'h' to go back.")
+	}
+
+	var content strings.Builder
+	content.WriteString("Apply Changes\n\n")
+	_, end := visibleRange(0, len(m.applyPreview), m.maxFileDisplay)
+	for _, file := range m.applyPreview[:end] {
+		content.WriteString(fmt.Sprintf("  [%s%s] %s\n", file.DestStatus.Symbol(), file.TargetStatus.Symbol(), file.Path))
+	}
+	if end < len(m.applyPreview) {
+		content.WriteString(fmt.Sprintf("  ... and %d more\n", len(m.applyPreview)-end))
+	}
+	m.writeLocalChanges(&content, localChanges(m.applyPreview))
+	content.WriteString(fmt.Sprintf("\nApply %d change(s) to your destination directory? (y/n)\n", len(m.applyPreview)))
+
+	m.viewport.SetContent(content.String())
+	return m.viewport.View()
+}
+
+// localChanges returns the entries that apply would change although their
+// destination was changed since chezmoi last wrote it
+func localChanges(entries []chezmoi.StatusEntry) []chezmoi.StatusEntry {
+	var local []chezmoi.StatusEntry
+	for _, entry := range entries {
+		if entry.HasLocalChanges() && entry.NeedsApply() {
+			local = append(local, entry)
 		}
 	}
+	return local
+}
+
+// writeLocalChanges lists the local changes an apply prompt overwrites
+func (m *Model) writeLocalChanges(content *strings.Builder, local []chezmoi.StatusEntry) {
+	if len(local) == 0 {
+		return
+	}
+	content.WriteString(fmt.Sprintf("\n%d file(s) changed since chezmoi last wrote them, applying overwrites them:\n", len(local)))
+	_, end := visibleRange(0, len(local), m.maxFileDisplay)
+	for _, file := range local[:end] {
+		content.WriteString(fmt.Sprintf("  %s\n", file.Path))
+	}
+	if end < len(local) {
+		content.WriteString(fmt.Sprintf("  ... and %d more\n", len(local)-end))
+	}
+}
+
+// applyOptions returns the flags of a confirmed apply. chezmoi asks before
+// overwriting local changes, but it runs without a terminal of its own, so
+// the prompt would wait forever. Once the user has confirmed overwriting
+// the listed local changes they are forced; otherwise any prompt fails and
+// its error is shown in the log pane.
+func applyOptions(overwrite bool) chezmoi.ApplyOptions {
+	if overwrite {
+		return chezmoi.ApplyOptions{Force: true}
+	}
+	return chezmoi.ApplyOptions{NoTTY: true}
+}
+
+// visibleRange returns the window of at most limit of total list entries
+// that follows the cursor. A limit of zero shows every entry.
+func visibleRange(cursor, total, limit int) (start, end int) {
+	if limit <= 0 || total <= limit {
+		return 0, total
+	}
+	start = max(cursor-limit+1, 0)
+	return start, start + limit
+}
+
+// scrollView renders content in the viewport, scrolling it so that the
+// cursor on the given line is visible
+func (m *Model) scrollView(content string, line int) string {
+	m.viewport.SetContent(content)
+	if line < m.viewport.YOffset {
+		m.viewport.SetYOffset(line)
+	} else if line >= m.viewport.YOffset+m.viewport.Height {
+		m.viewport.SetYOffset(line - m.viewport.Height + 1)
+	}
+	return m.viewport.View()
+}
+
+// statsView renders the statistics screen
+func (m *Model) statsView() string {
+	switch {
+	case m.loading:
+		return quitTextStyle.Render(m.spinner.View() + " Loading stats... (esc to cancel)")
+	case m.statsErr != nil:
+		return quitTextStyle.Render(renderError("Loading stats", m.statsErr))
+	}
+	return m.viewport.View()
+}
+
+// generateStatsContent renders statistics about the dotfiles. The chezmoi
+// commands it needs run concurrently.
+func generateStatsContent(ctx context.Context, integ *integration.ChezmoiIntegration) (string, error) {
+	var (
+		wg                           sync.WaitGroup
+		statusData                   []chezmoi.StatusEntry
+		managedFiles, unmanagedFiles []string
+		statusErr, managedErr        error
+	)
+	wg.Add(3)
+	go func() {
+		defer wg.Done()
+		statusData, statusErr = integ.GetStatus(ctx)
+	}()
+	go func() {
+		defer wg.Done()
+		managedFiles, managedErr = integ.GetManagedFiles(ctx)
+	}()
+	go func() {
+		defer wg.Done()
+		// This is okay to fail, sometimes there are no unmanaged files
+		unmanagedFiles, _ = integ.GetUnmanagedFiles(ctx)
+	}()
+	wg.Wait()
+
+	if statusErr != nil {
+		return "", fmt.Errorf("could not get status data: %w", statusErr)
+	}
+	if managedErr != nil {
+		return "", fmt.Errorf("could not get managed files: %w", managedErr)
+	}
+
+	// Calculate stats
+	counts := chezmoi.CountStatus(statusData)
 
 	var content strings.Builder
 	content.WriteString("┌─ Chezmoi Dotfiles Statistics ──────────────────────────────────┐\n")
 	content.WriteString(fmt.Sprintf("│ Last Updated: %-47s │\n", time.Now().Format("2006-01-02 15:04:05")))
 	content.WriteString("├─────────────────────────────────────────────────────────────────┤\n")
-	content.WriteString(fmt.Sprintf("│ Total Managed Files:    %3d                                   │\n", len(validManagedFiles)))
-	content.WriteString(fmt.Sprintf("│ Total Unmanaged Files:  %3d                                   │\n", len(validManagedFiles)))
+	content.WriteString(fmt.Sprintf("│ Total Managed Files:    %3d                                   │\n", len(managedFiles)))
+	content.WriteString(fmt.Sprintf("│ Total Unmanaged Files:  %3d                                   │\n", len(unmanagedFiles)))
 	content.WriteString("├─────────────────────────────────────────────────────────────────┤\n")
-	content.WriteString(fmt.Sprintf("│ Up to Date:             %3d (%3d%%)                           │\n", 
-		upToDateCount, calculatePercentage(upToDateCount, len(validManagedFiles))))
-	content.WriteString(fmt.Sprintf("│ Modified:               %3d (%3d%%)                           │\n", 
-		modifiedCount, calculatePercentage(modifiedCount, len(validManagedFiles))))
-	content.WriteString(fmt.Sprintf("│ Added:                  %3d (%3d%%)                           │\n", 
-		addedCount, calculatePercentage(addedCount, len(validManagedFiles))))
-	content.WriteString(fmt.Sprintf("│ Deleted:                %3d (%3d%%)                           │\n", 
-		deletedCount, calculatePercentage(deletedCount, len(validManagedFiles))))
+	content.WriteString(fmt.Sprintf("│ Up to Date:             %3d (%3d%%)                           │\n",
+		counts.Unchanged, calculatePercentage(counts.Unchanged, len(managedFiles))))
+	content.WriteString(fmt.Sprintf("│ Modified:               %3d (%3d%%)                           │\n",
+		counts.Modified, calculatePercentage(counts.Modified, len(managedFiles))))
+	content.WriteString(fmt.Sprintf("│ Added:                  %3d (%3d%%)                           │\n",
+		counts.Added, calculatePercentage(counts.Added, len(managedFiles))))
+	content.WriteString(fmt.Sprintf("│ Deleted:                %3d (%3d%%)                           │\n",
+		counts.Deleted, calculatePercentage(counts.Deleted, len(managedFiles))))
+	content.WriteString(fmt.Sprintf("│ Scripts to Run:         %3d                                   │\n", counts.Scripts))
 	content.WriteString("├─────────────────────────────────────────────────────────────────┤\n")
 	content.WriteString("│ Actions: Use arrow keys to navigate, 'h' to go back, 'q' to quit │\n")
 	content.WriteString("└─────────────────────────────────────────────────────────────────┘\n")
@@ -401,30 +1082,6 @@ func calculatePercentage(part, total int) int {
 	return int(float64(part) / float64(total) * 100)
 }
 
-func generateBitwardenContent() string {
-	return "┌─ Bitwarden Secrets Manager ─────────────────────────────────────┐\n" +
-		"│                                                                 │\n" +
-		"│  Manage your Bitwarden vault from within Chezmoi TUI           │\n" +
-		"│                                                                 │\n" +
-		"│  Features:                                                      │\n" +
-		"│  • View and manage Bitwarden items                              │\n" +
-		"│  • Unlock/Lock your vault                                       │\n" +
-		"│  • Sync with remote server                                      │\n" +
-		"│  • Integration with Chezmoi templates                            │\n" +
-		"│  • Launch dedicated Bitwarden TUI                               │\n" +
-		"│                                                                 │\n" +
-		"│  CLI Commands:                                                   │\n" +
-		"│  • chezmoi-tui bitwarden status  - Show vault status            │\n" +
-		"│  • chezmoi-tui bitwarden unlock  - Unlock vault                 │\n" +
-		"│  • chezmoi-tui bitwarden lock    - Lock vault                   │\n" +
-		"│  • chezmoi-tui bitwarden list    - List items                  │\n" +
-		"│  • chezmoi-tui bitwarden sync    - Sync with server             │\n" +
-		"│  • chezmoi-tui bitwarden tui     - Launch Bitwarden TUI         │\n" +
-		"│                                                                 │\n" +
-		"│  Actions: Use arrow keys to navigate, 'h' to go back, 'q' to quit │\n" +
-		"└─────────────────────────────────────────────────────────────────┘\n"
-}
-
 func getDescription(choice string) string {
 	switch choice {
 	case "View Status":
@@ -444,4 +1101,4 @@ func getDescription(choice string) string {
 	default:
 		return "Select an option"
 	}
-}
\ No newline at end of file
+}
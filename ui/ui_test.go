@@ -0,0 +1,367 @@
+package ui
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"chezmoi-tui/internal/chezmoi"
+	"chezmoi-tui/internal/chezmoi/chezmoitest"
+	"chezmoi-tui/internal/config"
+	"chezmoi-tui/internal/integration"
+)
+
+// newTestModel returns a model backed by a fake chezmoi
+func newTestModel(t *testing.T, fake *chezmoitest.Fake) *Model {
+	t.Helper()
+
+	integ, err := integration.New(chezmoi.WithExecutor(fake))
+	if err != nil {
+		t.Fatalf("Failed to create integration: %v", err)
+	}
+	m := initialModel(integ)
+	m.Update(tea.WindowSizeMsg{Width: 100, Height: 40})
+	return &m
+}
+
+// selectMenu highlights the main menu entry with the given title
+func selectMenu(t *testing.T, m *Model, title string) {
+	t.Helper()
+
+	for i, choice := range m.choices {
+		if choice == title {
+			m.statusList.Select(i)
+			return
+		}
+	}
+	t.Fatalf("Menu entry %q not found", title)
+}
+
+// runCmd executes cmd and feeds the resulting messages back into the model
+// until no further commands are produced
+func runCmd(m *Model, cmd tea.Cmd) {
+	if cmd == nil {
+		return
+	}
+	msg := cmd()
+	if batch, ok := msg.(tea.BatchMsg); ok {
+		for _, cmd := range batch {
+			runCmd(m, cmd)
+		}
+		return
+	}
+	if msg == nil {
+		return
+	}
+	_, next := m.Update(msg)
+	runCmd(m, next)
+}
+
+// work returns the command that does the work of an operation, leaving out
+// the spinner tick that is batched with it
+func work(cmd tea.Cmd) tea.Cmd {
+	return func() tea.Msg {
+		msg := cmd()
+		if batch, ok := msg.(tea.BatchMsg); ok {
+			return batch[0]()
+		}
+		return msg
+	}
+}
+
+// press sends a key press to the model and returns the resulting command
+func press(m *Model, key string) tea.Cmd {
+	var msg tea.KeyMsg
+	switch key {
+	case "enter":
+		msg = tea.KeyMsg{Type: tea.KeyEnter}
+	case "esc":
+		msg = tea.KeyMsg{Type: tea.KeyEsc}
+	case "up":
+		msg = tea.KeyMsg{Type: tea.KeyUp}
+	case "down":
+		msg = tea.KeyMsg{Type: tea.KeyDown}
+	case " ":
+		msg = tea.KeyMsg{Type: tea.KeySpace}
+	default:
+		msg = tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(key)}
+	}
+	_, cmd := m.Update(msg)
+	return cmd
+}
+
+func TestViewStatus(t *testing.T) {
+	fake := chezmoitest.NewFake()
+	fake.On("status").Stdout(" M .bashrc\nA  .gitconfig\n")
+	m := newTestModel(t, fake)
+
+	selectMenu(t, m, "View Status")
+	cmd := press(m, "enter")
+	if !m.loading || m.screen != screenStatus {
+		t.Fatal("Expected the status screen to be loading")
+	}
+
+	runCmd(m, cmd)
+	if m.loading {
+		t.Error("Expected loading to have finished")
+	}
+	if len(m.fileStatus) != 2 || m.fileStatus[0].Path != ".bashrc" {
+		t.Errorf("Unexpected file status: %+v", m.fileStatus)
+	}
+	if view := m.View(); !strings.Contains(view, "[ M] .bashrc") {
+		t.Errorf("Expected view to list .bashrc, got:\n%s", view)
+	}
+}
+
+func TestViewStatusError(t *testing.T) {
+	fake := chezmoitest.NewFake()
+	fake.On("status").Fail(1, "chezmoi: stat /home/user/.local/share/chezmoi: no such file or directory\n")
+	m := newTestModel(t, fake)
+
+	selectMenu(t, m, "View Status")
+	runCmd(m, press(m, "enter"))
+
+	view := m.View()
+	if !strings.Contains(view, "Hint:") || !strings.Contains(view, "init") {
+		t.Errorf("Expected a remediation hint, got:\n%s", view)
+	}
+}
+
+func TestCancelStatus(t *testing.T) {
+	fake := chezmoitest.NewFake()
+	fake.On("status").Delay(time.Minute)
+	m := newTestModel(t, fake)
+
+	selectMenu(t, m, "View Status")
+	cmd := work(press(m, "enter"))
+
+	done := make(chan tea.Msg)
+	go func() { done <- cmd() }()
+	press(m, "esc")
+
+	select {
+	case msg := <-done:
+		m.Update(msg)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the status load to be cancelled")
+	}
+
+	if m.loading {
+		t.Error("Expected loading to have finished")
+	}
+	if view := m.View(); !strings.Contains(view, "cancelled") {
+		t.Errorf("Expected a cancellation message, got:\n%s", view)
+	}
+}
+
+func TestApplyStreamsOutput(t *testing.T) {
+	fake := chezmoitest.NewFake()
+	fake.On("status").Stdout(" M .bashrc\n")
+	fake.On("apply").Stdout("line one\nline two\n")
+	m := newTestModel(t, fake)
+
+	selectMenu(t, m, "Apply Changes")
+	runCmd(m, press(m, "enter"))
+	if !m.confirmApply {
+		t.Fatal("Expected apply to ask for confirmation")
+	}
+	if view := m.View(); !strings.Contains(view, "[ M] .bashrc") || !strings.Contains(view, "Apply 1 change(s)") {
+		t.Errorf("Expected a preview of the changes, got:\n%s", view)
+	}
+	if fake.CallCount("apply") != 0 {
+		t.Fatal("Expected apply not to run before confirmation")
+	}
+
+	runCmd(m, press(m, "y"))
+	if fake.CallCount("apply", "--no-tty") != 1 {
+		t.Errorf("Expected apply to run once without a terminal, got %+v", fake.Calls())
+	}
+	if len(m.log.lines) != 2 || m.log.lines[1] != "line two" {
+		t.Errorf("Expected streamed lines in the log pane, got %q", m.log.lines)
+	}
+	if view := m.View(); !strings.Contains(view, "Done.") {
+		t.Errorf("Expected apply to be done, got:\n%s", view)
+	}
+}
+
+func TestApplyOverwritesLocalChanges(t *testing.T) {
+	fake := chezmoitest.NewFake()
+	fake.On("status").Stdout("MM .bashrc\n M .zshrc\n")
+	fake.On("apply")
+	m := newTestModel(t, fake)
+
+	selectMenu(t, m, "Apply Changes")
+	runCmd(m, press(m, "enter"))
+	_, local, _ := strings.Cut(m.View(), "1 file(s) changed since chezmoi last wrote them")
+	if !strings.Contains(local, ".bashrc") || strings.Contains(local, ".zshrc") {
+		t.Errorf("Expected the local changes to be listed, got:\n%s", m.View())
+	}
+
+	runCmd(m, press(m, "y"))
+	expected := []string{"apply", "--force"}
+	if calls := fake.Calls(); !reflect.DeepEqual(calls[len(calls)-1].Args, expected) {
+		t.Errorf("Expected args %v, got %v", expected, calls[len(calls)-1].Args)
+	}
+}
+
+func TestApplyNothingToApply(t *testing.T) {
+	fake := chezmoitest.NewFake()
+	fake.On("status")
+	m := newTestModel(t, fake)
+
+	selectMenu(t, m, "Apply Changes")
+	runCmd(m, press(m, "enter"))
+	if view := m.View(); !strings.Contains(view, "Nothing to apply") {
+		t.Errorf("Expected an up to date message, got:\n%s", view)
+	}
+
+	runCmd(m, press(m, "y"))
+	if fake.CallCount("apply") != 0 {
+		t.Error("Expected apply not to run without changes")
+	}
+}
+
+func TestDiffChanges(t *testing.T) {
+	fake := chezmoitest.NewFake()
+	fake.On("diff").Stdout("diff --git a/.bashrc b/.bashrc\n--- a/.bashrc\n+++ b/.bashrc\n@@ -1 +1 @@\n-export EDITOR=vi\n+export EDITOR=nvim\n")
+	m := newTestModel(t, fake)
+
+	selectMenu(t, m, "Diff Changes")
+	cmd := press(m, "enter")
+	if !m.loading || m.screen != screenDiff {
+		t.Fatal("Expected the diff screen to be loading")
+	}
+	runCmd(m, cmd)
+
+	view := m.View()
+	for _, expected := range []string{"Diff Changes", "-export EDITOR=vi", "+export EDITOR=nvim"} {
+		if !strings.Contains(view, expected) {
+			t.Errorf("Expected the diff to contain %q, got:\n%s", expected, view)
+		}
+	}
+
+	press(m, "h")
+	if m.screen != screenMenu {
+		t.Error("Expected to return to the main menu")
+	}
+}
+
+func TestDiffChangesEmpty(t *testing.T) {
+	fake := chezmoitest.NewFake()
+	fake.On("diff")
+	m := newTestModel(t, fake)
+
+	selectMenu(t, m, "Diff Changes")
+	runCmd(m, press(m, "enter"))
+	if view := m.View(); !strings.Contains(view, "No differences") {
+		t.Errorf("Expected a no differences message, got:\n%s", view)
+	}
+}
+
+func TestGenerateStatsContent(t *testing.T) {
+	fake := chezmoitest.NewFake()
+	fake.On("status").Stdout(" M .bashrc\n A .vimrc\n")
+	fake.On("managed").Stdout(".bashrc\x00.vimrc\x00.zshrc\x00")
+	fake.On("unmanaged").Stdout("notes.txt\x00")
+	m := newTestModel(t, fake)
+
+	content, err := generateStatsContent(t.Context(), m.integration)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	for _, expected := range []string{"Total Managed Files:      3", "Total Unmanaged Files:    1", "Modified:                 1"} {
+		if !strings.Contains(content, expected) {
+			t.Errorf("Expected stats to contain %q, got:\n%s", expected, content)
+		}
+	}
+}
+
+func TestShowStats(t *testing.T) {
+	fake := chezmoitest.NewFake()
+	fake.On("status").Stdout(" M .bashrc\n")
+	fake.On("managed").Stdout(".bashrc\x00.vimrc\x00")
+	fake.On("unmanaged")
+	m := newTestModel(t, fake)
+
+	selectMenu(t, m, "Show Stats")
+	cmd := press(m, "enter")
+	if !m.loading || m.screen != screenStats {
+		t.Fatal("Expected the stats screen to be loading")
+	}
+	if view := m.View(); !strings.Contains(view, "Loading stats") || !strings.Contains(view, m.spinner.View()) {
+		t.Errorf("Expected a spinner while loading, got:\n%s", view)
+	}
+
+	runCmd(m, cmd)
+	if m.loading || m.spinning {
+		t.Error("Expected loading and the spinner to have stopped")
+	}
+	if view := m.View(); !strings.Contains(view, "Total Managed Files:      2") {
+		t.Errorf("Expected the stats, got:\n%s", view)
+	}
+}
+
+func TestBackDropsStaleResult(t *testing.T) {
+	fake := chezmoitest.NewFake()
+	fake.On("diff").Stdout("--- a/.bashrc\n+++ b/.bashrc\n@@ -1 +1 @@\n-a\n+b\n")
+	fake.On("status").Stdout(" M .bashrc\n")
+	m := newTestModel(t, fake)
+
+	selectMenu(t, m, "Diff Changes")
+	cmd := work(press(m, "enter"))
+
+	// Leaving the screen does not wait for the diff
+	press(m, "h")
+	if m.loading || m.screen != screenMenu {
+		t.Fatal("Expected to return to the main menu without waiting")
+	}
+
+	// Its result arrives while the status is loading and is dropped
+	selectMenu(t, m, "View Status")
+	statusCmd := press(m, "enter")
+	m.Update(cmd())
+	if !m.loading || m.screen != screenStatus || !m.diff.Empty() {
+		t.Error("Expected the stale diff to be ignored")
+	}
+	runCmd(m, statusCmd)
+	if m.loading || len(m.fileStatus) != 1 {
+		t.Errorf("Expected the status to load, got %+v", m.fileStatus)
+	}
+}
+
+func TestConfigureLimitsAndRefresh(t *testing.T) {
+	fake := chezmoitest.NewFake()
+	fake.On("status").Stdout(" M .bashrc\n M .vimrc\n M .zshrc\n")
+	m := newTestModel(t, fake)
+
+	cfg := config.Default()
+	cfg.TUI.MaxFileDisplay = 2
+	m.configure(cfg)
+
+	selectMenu(t, m, "View Status")
+	m.Update(work(press(m, "enter"))())
+
+	view := m.View()
+	if !strings.Contains(view, ".vimrc") || strings.Contains(view, ".zshrc") || !strings.Contains(view, "... and 1 more") {
+		t.Errorf("Expected only two files to be listed, got:\n%s", view)
+	}
+
+	// The load scheduled a refresh; a tick from an older schedule is ignored
+	if _, cmd := m.Update(statusRefreshMsg{seq: m.refreshSeq - 1}); cmd != nil {
+		t.Error("Expected a stale refresh tick to be ignored")
+	}
+	_, cmd := m.Update(statusRefreshMsg{seq: m.refreshSeq})
+	if cmd == nil || !m.loading {
+		t.Fatal("Expected the refresh tick to reload the status")
+	}
+	if view := m.View(); !strings.Contains(view, ".bashrc") {
+		t.Errorf("Expected the previous status to stay visible while refreshing, got:\n%s", view)
+	}
+	m.Update(work(cmd)())
+	if fake.CallCount("status") != 2 {
+		t.Errorf("Expected status to be loaded twice, got %d", fake.CallCount("status"))
+	}
+}
@@ -0,0 +1,167 @@
+package ui
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"chezmoi-tui/internal/chezmoi"
+	"chezmoi-tui/internal/watch"
+)
+
+// watchDebounce is how long a burst of file changes must have ended before
+// the status is refreshed
+const watchDebounce = 300 * time.Millisecond
+
+// watchStartedMsg carries the watcher started by startWatch
+type watchStartedMsg struct {
+	watcher *watch.Watcher
+	err     error
+}
+
+// fileChangeMsg reports watched files that changed
+type fileChangeMsg struct {
+	change watch.Change
+}
+
+// watchStoppedMsg reports that the watcher closed its changes channel
+type watchStoppedMsg struct {
+	watcher *watch.Watcher
+}
+
+// statusUpdatedMsg carries the status of targets that changed
+type statusUpdatedMsg struct {
+	op      int
+	targets []string
+	entries []chezmoi.StatusEntry
+	err     error
+}
+
+// startWatch starts watching the source directory and the managed targets,
+// unless watching is disabled or was started before. The status is polled
+// until the watcher runs, and instead of it if it cannot be started.
+func (m *Model) startWatch() tea.Cmd {
+	if !m.watchEnabled || m.watchStarted {
+		return nil
+	}
+	m.watchStarted = true
+
+	integ := m.integration
+	return func() tea.Msg {
+		sourceDir, targets, err := integ.WatchPaths(context.Background())
+		if err != nil {
+			return watchStartedMsg{err: err}
+		}
+		w, err := watch.New(sourceDir, targets, watchDebounce)
+		return watchStartedMsg{watcher: w, err: err}
+	}
+}
+
+// setWatcher replaces polling with the started watcher
+func (m *Model) setWatcher(msg watchStartedMsg) tea.Cmd {
+	m.watchErr = msg.err
+	if msg.err != nil {
+		return nil
+	}
+	m.watcher = msg.watcher
+	m.refreshSeq++ // supersedes the scheduled poll
+	return waitForChange(m.watcher)
+}
+
+// stopWatch stops the watcher, if it runs
+func (m *Model) stopWatch() {
+	if m.watcher != nil {
+		m.watcher.Close()
+		m.watcher = nil
+	}
+}
+
+// waitForChange waits for the next change reported by w
+func waitForChange(w *watch.Watcher) tea.Cmd {
+	if w == nil {
+		return nil
+	}
+	return func() tea.Msg {
+		change, ok := <-w.Changes()
+		if !ok {
+			return watchStoppedMsg{watcher: w}
+		}
+		return fileChangeMsg{change: change}
+	}
+}
+
+// handleWatchStopped falls back to polling the status when the running
+// watcher stops delivering changes
+func (m *Model) handleWatchStopped(w *watch.Watcher) tea.Cmd {
+	if m.watcher != w {
+		return nil
+	}
+	m.stopWatch()
+	m.watchErr = errors.New("the file watcher stopped")
+	return m.scheduleRefresh()
+}
+
+// handleFileChange records a change reported by the watcher and refreshes
+// the status screen if it is idle
+func (m *Model) handleFileChange(change watch.Change) tea.Cmd {
+	if m.pendingChange == nil {
+		m.pendingChange = &watch.Change{}
+	}
+	m.pendingChange.Source = m.pendingChange.Source || change.Source
+	m.pendingChange.Targets = append(m.pendingChange.Targets, change.Targets...)
+	return tea.Batch(waitForChange(m.watcher), m.refreshChanged())
+}
+
+// refreshChanged refreshes the status after the files recorded by
+// handleFileChange changed. Changes to the source state can affect any
+// target and reload the whole status; otherwise only the changed targets
+// are reloaded. Changes are kept while the status screen is not shown or
+// busy.
+func (m *Model) refreshChanged() tea.Cmd {
+	if m.pendingChange == nil || m.screen != screenStatus || m.loading {
+		return nil
+	}
+	change := *m.pendingChange
+	m.pendingChange = nil
+	if change.Source || m.statusErr != nil {
+		return m.loadStatus()
+	}
+
+	ctx, op := m.startOperation()
+	integ := m.integration
+	return func() tea.Msg {
+		entries, err := integ.GetStatus(ctx, change.Targets...)
+		return statusUpdatedMsg{op: op, targets: change.Targets, entries: entries, err: err}
+	}
+}
+
+// updateFileStatus replaces the status of the targets, and of anything below
+// them, with entries. Targets that are no longer listed are up to date.
+func (m *Model) updateFileStatus(targets []string, entries []chezmoi.StatusEntry) {
+	var status []chezmoi.StatusEntry
+	for _, entry := range m.fileStatus {
+		if !isBelowAny(m.targetPath(entry.Path), targets) {
+			status = append(status, entry)
+		}
+	}
+	status = append(status, entries...)
+	sort.SliceStable(status, func(i, j int) bool {
+		return status[i].Path < status[j].Path
+	})
+	m.setFileStatus(status, nil)
+}
+
+// isBelowAny reports whether path is one of dirs or below one of them
+func isBelowAny(path string, dirs []string) bool {
+	for _, dir := range dirs {
+		if path == dir || strings.HasPrefix(path, dir+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,127 @@
+package ui
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"chezmoi-tui/internal/chezmoi/chezmoitest"
+	"chezmoi-tui/internal/watch"
+)
+
+func TestFileChangeRefreshesTargets(t *testing.T) {
+	fake := chezmoitest.NewFake()
+	m := newStatusModel(t, fake, browserStatus)
+
+	// .vimrc was brought up to date and .gitconfig was added
+	fake.On("status", "/home/user/.vimrc", "/home/user/.gitconfig").Stdout("A  .gitconfig\n")
+	runCmd(m, m.handleFileChange(watch.Change{Targets: []string{"/home/user/.vimrc", "/home/user/.gitconfig"}}))
+
+	var paths []string
+	for _, entry := range m.fileStatus {
+		paths = append(paths, entry.Path)
+	}
+	if strings.Join(paths, " ") != ".bashrc .gitconfig .zshrc" {
+		t.Errorf("Expected .vimrc to be replaced by .gitconfig, got %v", paths)
+	}
+	if fake.CallCount("status") != 2 {
+		t.Errorf("Expected a single incremental status call, got %d status calls", fake.CallCount("status"))
+	}
+}
+
+func TestSourceChangeReloadsStatus(t *testing.T) {
+	fake := chezmoitest.NewFake()
+	m := newStatusModel(t, fake, browserStatus)
+
+	fake.On("status").Stdout(" M .bashrc\n")
+	runCmd(m, m.handleFileChange(watch.Change{Source: true}))
+	if len(m.fileStatus) != 1 {
+		t.Errorf("Expected the status to be reloaded, got %+v", m.fileStatus)
+	}
+}
+
+func TestFileChangeDeferredWhileAway(t *testing.T) {
+	fake := chezmoitest.NewFake()
+	m := newStatusModel(t, fake, browserStatus)
+
+	// Changes while the diff of .bashrc is shown wait until it is left
+	fake.On("diff")
+	runCmd(m, press(m, "d"))
+	if m.screen != screenDiff {
+		t.Fatalf("Expected the diff screen, got %v", m.screen)
+	}
+	fake.On("status", "/home/user/.bashrc")
+	if cmd := m.handleFileChange(watch.Change{Targets: []string{"/home/user/.bashrc"}}); cmd != nil {
+		t.Error("Expected no refresh away from the status screen")
+	}
+
+	runCmd(m, press(m, "h"))
+	if len(m.fileStatus) != 2 || m.fileStatus[0].Path != ".vimrc" {
+		t.Errorf("Expected .bashrc to be up to date, got %+v", m.fileStatus)
+	}
+}
+
+func TestStartWatch(t *testing.T) {
+	fake := chezmoitest.NewFake()
+	m := newStatusModel(t, fake, browserStatus)
+	m.watchEnabled = true
+	m.refreshInterval = time.Minute
+
+	sourceDir := t.TempDir()
+	fake.On("source-path").Stdout(sourceDir + "\n")
+	fake.On("managed").Stdout(".bashrc\x00")
+	m.Update(m.startWatch()())
+	t.Cleanup(m.stopWatch)
+
+	if m.watcher == nil {
+		t.Fatalf("Expected the watcher to run, got: %v", m.watchErr)
+	}
+	if m.startWatch() != nil {
+		t.Error("Expected the watcher to be started once")
+	}
+	if m.scheduleRefresh() != nil {
+		t.Error("Expected no polling while files are watched")
+	}
+}
+
+func TestWatcherStopFallsBackToPolling(t *testing.T) {
+	fake := chezmoitest.NewFake()
+	m := newStatusModel(t, fake, browserStatus)
+	m.watchEnabled = true
+	m.refreshInterval = time.Minute
+
+	fake.On("source-path").Stdout(t.TempDir() + "\n")
+	fake.On("managed").Stdout(".bashrc\x00")
+	_, wait := m.Update(m.startWatch()())
+	t.Cleanup(m.stopWatch)
+	if m.watcher == nil {
+		t.Fatalf("Expected the watcher to run, got: %v", m.watchErr)
+	}
+
+	m.watcher.Close()
+	if _, cmd := m.Update(wait()); cmd == nil {
+		t.Error("Expected the status to be polled again")
+	}
+	if m.watcher != nil {
+		t.Error("Expected the stopped watcher to be dropped")
+	}
+	if view := m.View(); !strings.Contains(view, "Not watching files: the file watcher stopped") {
+		t.Errorf("Expected the stopped watcher to be shown, got:\n%s", view)
+	}
+}
+
+func TestStartWatchFallsBackToPolling(t *testing.T) {
+	fake := chezmoitest.NewFake()
+	m := newStatusModel(t, fake, browserStatus)
+	m.watchEnabled = true
+	m.refreshInterval = time.Minute
+
+	m.Update(watchStartedMsg{err: errors.New("too many open files")})
+	if m.scheduleRefresh() == nil {
+		t.Error("Expected the status to be polled")
+	}
+	if view := m.View(); !strings.Contains(view, "Not watching files: too many open files") {
+		t.Errorf("Expected the watch error to be shown, got:\n%s", view)
+	}
+}